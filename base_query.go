@@ -28,16 +28,48 @@ type BaseQuery struct {
 	SelectColLinkStr string
 	// true：使用原始字段名；false：使用别名
 	SelectRaw bool
+	// true：条件值使用绑定参数（占位符）传递；false：条件值直接拼接到SQL中
+	BindParams bool
 
 	TableName       string
 	Distinct        bool
 	SelectForUpdate bool
 	Select          Select
 	Order           Order
-	Limit           Limit
-	Where           Where
-	GroupBy         GroupBy
-	Having          Having
+	// SearchOrder 全文检索相关度排序：[字段, 检索内容]，排在 Order 之前
+	SearchOrder [][2]string
+	// ValuesOrder 按值列表排序（OrderByValues），排在 SearchOrder 之后、Order 之前
+	ValuesOrder []*RawSQL
+	// SelectExpr OrderExpr GroupByExpr 原始SQL片段或表达式，分别排在 Select Order GroupBy 之后
+	SelectExpr  []*RawSQL
+	OrderExpr   []*RawSQL
+	GroupByExpr []*RawSQL
+	Limit       Limit
+	Where       Where
+	GroupBy     GroupBy
+	// Grouping 分组小计（rollup cube grouping sets），排在 GroupBy GroupByExpr 之后
+	Grouping *GroupingSet
+	Having   Having
+
+	// FromQuery 子查询作为主表（派生表），TableName 为子查询的别名
+	FromQuery *BaseQuery
+	// With 公共表表达式，查询（包括 count）之前的 WITH 子句
+	With []*CTE
+	// SetQuery 集合运算（union intersect except），Order Limit 作用于合并后的结果
+	SetQuery []*SetQuery
+	// outer 关联子查询的外层查询，用于解析 orm.OuterCol
+	outer *BaseQuery
+
+	// 以下仅 ClickHouse 有效
+	// Final 查询使用 FINAL
+	Final bool
+	// Sample SAMPLE 子句，如：0.1 1/10 OFFSET 1/2
+	Sample string
+	// LimitBy limit n by：[size] 或 [offset, size]，LimitByCols 为分组字段，与 GroupBy 规则一致
+	LimitBy     Limit
+	LimitByCols GroupBy
+	// Settings 查询的 SETTINGS
+	Settings map[string]interface{}
 }
 
 func (q *BaseQuery) initJoinData() {
@@ -77,6 +109,16 @@ func (q *BaseQuery) addRef(level int, ref *referenceData) {
 }
 
 func (q *BaseQuery) formatColumn(sel string) *formatColumnData {
+	// 日期函数：created_at|month，created_at|trunc_month
+	if i := strings.Index(sel, "|"); i > 0 {
+		return q.formatDateColumn(sel[:i], sel[i+1:])
+	}
+
+	// json 字段路径：meta->a.b，tb2.meta->a.b
+	if i := strings.Index(sel, "->"); i > 0 {
+		return q.formatJSONColumn(sel[:i], sel[i+2:])
+	}
+
 	colData := &formatColumnData{}
 
 	dbCore := q.RefConf.getDBConf()
@@ -240,6 +282,71 @@ func (q *BaseQuery) formatColumn(sel string) *formatColumnData {
 	return colData
 }
 
+// formatJSONColumn json 字段路径，path 以 . 分隔，数字为数组下标，空格之后为别名
+func (q *BaseQuery) formatJSONColumn(col, path string) *formatColumnData {
+	alias := ""
+	if i := strings.Index(path, " "); i > 0 {
+		alias = path[i+1:]
+		path = path[:i]
+	}
+
+	keys := strings.Split(path, ".")
+	for _, k := range keys {
+		if k == "" || strings.ContainsAny(k, "'\"`()[] ") {
+			panic(fmt.Sprintf("field[%s] json path[%s] error", col, path))
+		}
+	}
+
+	colData := q.formatColumn(col)
+	if colData.FuncName != "" || colData.Alias != "" || colData.TableCol == "*" {
+		panic(fmt.Sprintf("field[%s] json path[%s] error", col, path))
+	}
+
+	colData.JSONPath = keys
+	colData.FormatCol = q.RefConf.getDBConf().Dialect.JSONPath(colData.FormatCol, keys)
+	if alias != "" {
+		colData.Alias = alias
+		colData.FormatCol += " " + alias
+	}
+	return colData
+}
+
+// formatDateColumn 日期函数，part 为日期部分（year month 等）或 trunc_ 加截断单位（trunc_month 等），空格之后为别名
+func (q *BaseQuery) formatDateColumn(col, part string) *formatColumnData {
+	alias := ""
+	if i := strings.Index(part, " "); i > 0 {
+		alias = part[i+1:]
+		part = part[:i]
+	}
+
+	colData := q.formatColumn(col)
+	if colData.FuncName != "" || colData.Alias != "" || colData.TableCol == "*" {
+		panic(fmt.Sprintf("field[%s] date function[%s] error", col, part))
+	}
+
+	var expr string
+	var err error
+	dialect := q.RefConf.getDBConf().Dialect
+	if unit := strings.TrimPrefix(part, "trunc_"); unit != part && isDatePart(unit) && unit != "weekday" {
+		expr, err = dialect.DateTrunc(colData.FormatCol, unit)
+	} else if isDatePart(part) {
+		expr, err = dialect.DatePart(colData.FormatCol, part)
+	} else {
+		panic(fmt.Sprintf("field[%s] date function[%s] error", col, part))
+	}
+	if err != nil {
+		panic(err)
+	}
+
+	colData.DatePart = part
+	colData.FormatCol = expr
+	if alias != "" {
+		colData.Alias = alias
+		colData.FormatCol += " " + alias
+	}
+	return colData
+}
+
 // 解包select字段
 func (q *BaseQuery) selectUnzip() {
 	if len(q.Select) <= 0 {
@@ -332,6 +439,29 @@ func (q *BaseQuery) aliasSelectData() []*selectModel {
 
 			if colData.Alias != "" {
 				selObj.Cols = append(selObj.Cols, colData.FormatCol)
+			} else if len(colData.JSONPath) > 0 || colData.DatePart != "" {
+				// 默认别名：字段_a_b，跨表为：tag_字段_a_b；日期函数为：字段_month
+				strBuf.Reset()
+				strBuf.Grow(100)
+				strBuf.WriteString(colData.FormatCol)
+				strBuf.WriteString(" as ")
+				strBuf.WriteString(dbCore.EscStart)
+				if len(colData.TagList) > 0 {
+					strBuf.WriteString(tagLabel)
+					strBuf.WriteString(linkStr)
+				}
+				strBuf.WriteString(colData.TableCol)
+				if len(colData.JSONPath) > 0 {
+					strBuf.WriteString(linkStr)
+					strBuf.WriteString(util.JoinArr(colData.JSONPath, linkStr))
+				}
+				if colData.DatePart != "" {
+					strBuf.WriteString(linkStr)
+					strBuf.WriteString(colData.DatePart)
+				}
+				strBuf.WriteString(dbCore.EscEnd)
+
+				selObj.Cols = append(selObj.Cols, strBuf.String())
 			} else if colData.FuncName != "" {
 				txt := colData.TableCol
 
@@ -473,8 +603,11 @@ func (q *BaseQuery) orderData() []*orderModel {
 	orderObj := &orderModel{}
 
 	for _, sel := range q.Order {
+		sel, nulls := splitNullsOrder(sel)
+		orderObj.Nulls = append(orderObj.Nulls, nulls)
+
 		prefix := ""
-		if sel[0] == '-' || sel[1] == '+' {
+		if sel[0] == '-' || sel[0] == '+' {
 			prefix = sel[:1]
 			sel = sel[1:]
 		}
@@ -490,13 +623,53 @@ func (q *BaseQuery) orderData() []*orderModel {
 	return []*orderModel{orderObj}
 }
 
+// splitNullsOrder 排序字段的空值位置：-score|nulls_last，返回字段与 first、last（未指定为空）
+func splitNullsOrder(sel string) (string, string) {
+	i := strings.LastIndex(sel, "|")
+	if i < 0 {
+		return sel, ""
+	}
+
+	switch sel[i+1:] {
+	case "nulls_first":
+		return sel[:i], "first"
+	case "nulls_last":
+		return sel[:i], "last"
+	}
+	return sel, ""
+}
+
+// searchOrderData 全文检索相关度排序：[格式化后的字段, 检索内容]
+func (q *BaseQuery) searchOrderData() [][2]string {
+	if len(q.SearchOrder) <= 0 {
+		return nil
+	}
+
+	list := make([][2]string, 0, len(q.SearchOrder))
+	for _, so := range q.SearchOrder {
+		col := so[0]
+		if col != "" && col[0] == '#' {
+			col = col[1:]
+		} else {
+			col = q.formatColumn(col).FormatCol
+		}
+		list = append(list, [2]string{col, so[1]})
+	}
+	return list
+}
+
 func (q *BaseQuery) groupData() []string {
-	if len(q.GroupBy) <= 0 {
+	return q.formatCols(q.GroupBy)
+}
+
+// formatCols 字段列表，# 为原始字段
+func (q *BaseQuery) formatCols(colList []string) []string {
+	if len(colList) <= 0 {
 		return nil
 	}
 
-	cols := make([]string, 0, len(q.GroupBy))
-	for _, sel := range q.GroupBy {
+	cols := make([]string, 0, len(colList))
+	for _, sel := range colList {
 		if sel[0] == '#' {
 			cols = append(cols, sel[1:])
 		} else {
@@ -511,7 +684,8 @@ func (q *BaseQuery) groupData() []string {
 func (q *BaseQuery) formatCond(where map[string]interface{}) map[string]interface{} {
 	newCond := map[string]interface{}{}
 	var strBuf strings.Builder
-	for k, v := range where {
+	for _, k := range sortedKeys(where) {
+		v := where[k]
 		not := ""
 		if k[:1] == "~" {
 			not = "~"
@@ -519,11 +693,20 @@ func (q *BaseQuery) formatCond(where map[string]interface{}) map[string]interfac
 		}
 
 		val := v
+		if c, ok := condTree(k, v); ok {
+			newCond[not+k] = q.formatCondTree(c)
+			continue
+		}
+
 		switch v := v.(type) {
+		case Col:
+			val = q.colValue(v)
+		case OuterCol:
+			val = q.outerColValue(v)
 		case BaseQuery:
-			val = v.cond()
+			val = q.subQuery(v)
 		case *BaseQuery:
-			val = v.cond()
+			val = q.subQuery(*v)
 		case map[string]interface{}:
 			if len(v) <= 0 {
 				continue
@@ -551,6 +734,8 @@ func (q *BaseQuery) formatCond(where map[string]interface{}) map[string]interfac
 			newCond[not+k[1:]] = val
 		} else if k == "$or" || k == "$and" {
 			newCond[not+k] = val
+		} else if k == "$raw" {
+			newCond[not+k] = q.formatRawList(rawList(val))
 		} else {
 			arr := strings.Split(k, "__")
 			if len(arr) <= 1 {
@@ -571,12 +756,38 @@ func (q *BaseQuery) formatCond(where map[string]interface{}) map[string]interfac
 	return newCond
 }
 
+// colValue 字段引用格式化后的字段，# 为原始字段
+func (q *BaseQuery) colValue(c Col) colValue {
+	if c == "" {
+		panic("column value cannot be empty")
+	}
+
+	if c[0] == '#' {
+		return colValue(c[1:])
+	}
+	return colValue(q.formatColumn(string(c)).FormatCol)
+}
+
+// outerColValue 外层查询的字段，仅在子查询中有效
+func (q *BaseQuery) outerColValue(c OuterCol) colValue {
+	if q.outer == nil {
+		panic(fmt.Sprintf("outer column[%s] can only be used in subquery", c))
+	}
+	return q.outer.colValue(Col(c))
+}
+
+// subQuery 格式化子查询，子查询中的 orm.OuterCol 按当前查询解析
+func (q *BaseQuery) subQuery(sub BaseQuery) *queryModel {
+	sub.outer = q
+	return sub.cond()
+}
+
 func (q *BaseQuery) formatWhere() map[string]interface{} {
 	return q.formatCond(q.Where)
 }
 
 func (q *BaseQuery) formatHaving() map[string]interface{} {
-	if len(q.GroupBy) > 0 {
+	if len(q.GroupBy) > 0 || q.Grouping != nil {
 		return q.formatCond(q.Having)
 	}
 	return nil
@@ -615,6 +826,10 @@ func (q *BaseQuery) formatJoin() []*joinModel {
 }
 
 func (q *BaseQuery) cond() *queryModel {
+	if len(q.SetQuery) > 0 {
+		return q.setCond()
+	}
+
 	if q.TableName == "" {
 		panic("table name error")
 	}
@@ -640,14 +855,32 @@ func (q *BaseQuery) cond() *queryModel {
 		Limit:           q.Limit,
 		Select:          q.selectData(),
 		Order:           q.orderData(),
+		SearchOrder:     q.searchOrderData(),
+		ValuesOrder:     q.formatRawList(q.ValuesOrder),
+		OrderExpr:       q.formatRawList(q.OrderExpr),
+		SelectExpr:      q.formatRawList(q.SelectExpr),
+		GroupByExpr:     q.formatRawList(q.GroupByExpr),
 		GroupBy:         q.groupData(),
+		Grouping:        q.groupingData(),
 		Where:           q.formatWhere(),
 		Having:          q.formatHaving(),
 		JoinList:        q.formatJoin(),
+		Final:           q.Final,
+		Sample:          q.Sample,
+		LimitBy:         q.LimitBy,
+		LimitByCols:     q.formatCols(q.LimitByCols),
+		With:            q.withData(),
+	}
+	if len(q.Settings) > 0 {
+		query.Settings = clickhouseSettingsSQL(q.Settings)
+	}
+	if q.FromQuery != nil {
+		query.FromQuery = q.FromQuery.cond()
+		query.MainAlias = mainTableName
 	}
 
 	if !q.SelectRaw && len(q.Select) <= 0 {
-		tagList := q.tagSet.ToList()
+		tagList := sortedSet(q.tagSet)
 		q.Select = []string{"*"}
 		for _, tag := range tagList {
 			q.Select = append(q.Select, fmt.Sprintf("%s.*", tag))
@@ -666,9 +899,101 @@ func (q *BaseQuery) GetWhere() string {
 	return q.cond().GetWhere()
 }
 
+// where 生成条件SQL，BindParams 为true时值以占位标记代替，执行前需由 resolveBind 转换
+func (q *BaseQuery) where() string {
+	p := q.cond()
+	p.BindParams = q.BindParams
+	return p.GetWhere()
+}
+
 func (q *BaseQuery) SQL() string {
 	if q.CustomSQL != "" {
 		return q.CustomSQL
 	}
 	return q.cond().SQL()
 }
+
+// build 生成查询SQL及绑定参数，未启用 BindParams 时参数为nil
+func (q *BaseQuery) build() (string, []interface{}) {
+	if q.CustomSQL != "" {
+		return q.CustomSQL, nil
+	}
+
+	p := q.cond()
+	if !q.BindParams {
+		return p.SQL(), nil
+	}
+	p.BindParams = true
+	return resolveBind(p.DBCore.Dialect, p.SQL())
+}
+
+// buildCount 生成count SQL及绑定参数
+func (q *BaseQuery) buildCount() (string, []interface{}) {
+	p := q.cond()
+	if !q.BindParams {
+		return p.Count(), nil
+	}
+	p.BindParams = true
+	return resolveBind(p.DBCore.Dialect, p.Count())
+}
+
+// SQLWithArgs 生成查询SQL及绑定参数，设置了 CustomSQL 时原样返回
+func (q *BaseQuery) SQLWithArgs() (sqlStr string, args []interface{}, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			switch p := p.(type) {
+			case error:
+				err = p
+			default:
+				err = fmt.Errorf("%v", p)
+			}
+		}
+	}()
+
+	if q.CustomSQL != "" {
+		return q.CustomSQL, nil, nil
+	}
+
+	p := q.cond()
+	p.BindParams = true
+	sqlStr, args = resolveBind(p.DBCore.Dialect, p.SQL())
+	return sqlStr, args, nil
+}
+
+// CountWithArgs 生成count SQL及绑定参数
+func (q *BaseQuery) CountWithArgs() (sqlStr string, args []interface{}, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			switch p := p.(type) {
+			case error:
+				err = p
+			default:
+				err = fmt.Errorf("%v", p)
+			}
+		}
+	}()
+
+	p := q.cond()
+	p.BindParams = true
+	sqlStr, args = resolveBind(p.DBCore.Dialect, p.Count())
+	return sqlStr, args, nil
+}
+
+// GetWhereWithArgs 生成条件SQL（不含 where 关键字）及绑定参数
+func (q *BaseQuery) GetWhereWithArgs() (sqlStr string, args []interface{}, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			switch p := p.(type) {
+			case error:
+				err = p
+			default:
+				err = fmt.Errorf("%v", p)
+			}
+		}
+	}()
+
+	p := q.cond()
+	p.BindParams = true
+	sqlStr, args = resolveBind(p.DBCore.Dialect, p.GetWhere())
+	return sqlStr, args, nil
+}
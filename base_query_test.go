@@ -2,6 +2,7 @@ package orm
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/assembly-hub/orm/dbtype"
@@ -283,3 +284,88 @@ func TestMySQLOrmConf31(t *testing.T) {
 	s := q.Count()
 	fmt.Println(s)
 }
+
+func TestBindParams(t *testing.T) {
+	for _, tp := range []int{dbtype.MySQL, dbtype.Postgres} {
+		ref := NewReference(tp)
+		ref.AddTableDef("table1", Def{})
+		ref.AddTableDef("table2", Def2{})
+		ref.BuildRefs()
+
+		q := &BaseQuery{
+			RefConf:    ref,
+			TableName:  "table1",
+			Select:     []string{"id"},
+			BindParams: true,
+			Where: Where{
+				"name__startswith": "a'b",
+			},
+		}
+
+		s, args := q.build()
+		fmt.Println(s, args)
+		if len(args) != 1 || args[0] != "a'b%" {
+			t.Fatalf("args error: %v", args)
+		}
+		if tp == dbtype.MySQL && !strings.HasSuffix(s, "like ?") {
+			t.Fatalf("sql error: %s", s)
+		}
+		if tp == dbtype.Postgres && !strings.HasSuffix(s, "like $1") {
+			t.Fatalf("sql error: %s", s)
+		}
+
+		q.Where = Where{
+			"id__in": []int{1, 2},
+			"name":   "x",
+		}
+		s, args = q.buildCount()
+		fmt.Println(s, args)
+		if len(args) != 3 || strings.ContainsRune(s, bindMarker) {
+			t.Fatalf("count error: %s %v", s, args)
+		}
+
+		// []interface{} 与自定义类型的值保持数值类型
+		type status int
+		q.Where = Where{
+			"id__in":      []interface{}{1, uint8(2), "3"},
+			"ref_id":      status(4),
+			"id__between": []interface{}{1.5, 2},
+		}
+		_, args = q.build()
+		fmt.Println(args)
+		if len(args) != 6 || args[0] != 1.5 || args[1] != int64(2) || args[2] != int64(1) || args[3] != uint64(2) ||
+			args[4] != "3" || args[5] != int64(4) {
+			t.Fatalf("typed args error: %v", args)
+		}
+	}
+}
+
+func TestDeterministicSQL(t *testing.T) {
+	ref := NewReference(dbtype.MySQL)
+	ref.AddTableDef("table1", Def{})
+	ref.AddTableDef("table2", Def2{})
+	ref.BuildRefs()
+
+	q := &BaseQuery{
+		RefConf:   ref,
+		TableName: "table1",
+		Select:    []string{"id"},
+		Where: Where{
+			"name__contains": "a",
+			"id__gt":         1,
+			"ref_id__in":     []int{1, 2},
+			"$or": map[string]interface{}{
+				"id":   3,
+				"name": "b",
+			},
+		},
+	}
+
+	s := q.SQL()
+	for i := 0; i < 50; i++ {
+		if q.SQL() != s {
+			t.Fatal("sql is not deterministic")
+		}
+	}
+	fmt.Println(s)
+}
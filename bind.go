@@ -0,0 +1,228 @@
+// Package orm
+package orm
+
+import (
+	"encoding/hex"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/assembly-hub/basics/util"
+)
+
+// bindMarker 参数占位标记的边界符，绑定参数模式下生成SQL时值先编码为标记：\x00 类型 数据 \x00
+// 标记自带参数值，SQL片段可以任意拼接（子查询、count包裹、并发生成的批量语句等），
+// 最终由 resolveBind 按出现顺序替换为数据库占位符并还原参数列表
+const bindMarker = '\x00'
+
+const (
+	bindString = 's'
+	bindInt    = 'i'
+	bindUint   = 'u'
+	bindFloat  = 'f'
+)
+
+// bindParam 将参数编码为占位标记
+func bindParam(v interface{}) string {
+	var strBuf strings.Builder
+	strBuf.Grow(20)
+	strBuf.WriteByte(bindMarker)
+	switch v := bindValue(v).(type) {
+	case int64:
+		strBuf.WriteByte(bindInt)
+		strBuf.WriteString(strconv.FormatInt(v, 10))
+	case uint64:
+		strBuf.WriteByte(bindUint)
+		strBuf.WriteString(strconv.FormatUint(v, 10))
+	case float64:
+		strBuf.WriteByte(bindFloat)
+		strBuf.WriteString(strconv.FormatFloat(v, 'g', -1, 64))
+	case string:
+		strBuf.WriteByte(bindString)
+		strBuf.WriteString(hex.EncodeToString([]byte(v)))
+	}
+	strBuf.WriteByte(bindMarker)
+	return strBuf.String()
+}
+
+// bindTime 时间参数，由数据库方言包裹，如：oracle TO_DATE
+func bindTime(d Dialect, s string) string {
+	return d.TimeLiteral(bindParam(s))
+}
+
+// bindValue 转换为驱动可接收的数据，与拼接模式 formatValue 的处理保持一致
+func bindValue(raw interface{}) interface{} {
+	switch raw := raw.(type) {
+	case string:
+		return raw
+	case int:
+		return int64(raw)
+	case int8:
+		return int64(raw)
+	case int16:
+		return int64(raw)
+	case int32:
+		return int64(raw)
+	case int64:
+		return raw
+	case uint:
+		return uint64(raw)
+	case uint8:
+		return uint64(raw)
+	case uint16:
+		return uint64(raw)
+	case uint32:
+		return uint64(raw)
+	case uint64:
+		return raw
+	case float32:
+		f, _ := strconv.ParseFloat(strconv.FormatFloat(float64(raw), 'g', -1, 32), 64)
+		return f
+	case float64:
+		return raw
+	case bool:
+		if raw {
+			return int64(1)
+		}
+		return int64(0)
+	case time.Time, *time.Time:
+		return time2Str(raw)
+	default:
+		// 自定义类型按底层类型转换，其他类型转为字符串
+		def := reflect.ValueOf(raw)
+		switch def.Kind() {
+		case reflect.String:
+			return def.String()
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return def.Int()
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return def.Uint()
+		case reflect.Float32, reflect.Float64:
+			return def.Float()
+		case reflect.Bool:
+			return bindValue(def.Bool())
+		}
+		return util.Any2String(raw)
+	}
+}
+
+// parseBindParam 解析 s 开头的占位标记，返回参数值及标记长度，不是标记时长度为0
+func parseBindParam(s string) (interface{}, int) {
+	if len(s) < 3 || s[0] != bindMarker {
+		return nil, 0
+	}
+
+	end := strings.IndexByte(s[1:], bindMarker)
+	if end < 1 {
+		return nil, 0
+	}
+
+	data := s[2 : end+1]
+	var v interface{}
+	var err error
+	switch s[1] {
+	case bindInt:
+		v, err = strconv.ParseInt(data, 10, 64)
+	case bindUint:
+		v, err = strconv.ParseUint(data, 10, 64)
+	case bindFloat:
+		v, err = strconv.ParseFloat(data, 64)
+	case bindString:
+		var b []byte
+		b, err = hex.DecodeString(data)
+		v = string(b)
+	default:
+		return nil, 0
+	}
+	if err != nil {
+		return nil, 0
+	}
+	return v, end + 2
+}
+
+// resolveBind 将占位标记按出现顺序替换为数据库占位符，并返回对应的参数列表
+func resolveBind(d Dialect, s string) (string, []interface{}) {
+	if strings.IndexByte(s, bindMarker) < 0 {
+		return s, nil
+	}
+
+	var strBuf strings.Builder
+	strBuf.Grow(len(s))
+	var args []interface{}
+	for {
+		i := strings.IndexByte(s, bindMarker)
+		if i < 0 {
+			strBuf.WriteString(s)
+			break
+		}
+
+		strBuf.WriteString(s[:i])
+		v, n := parseBindParam(s[i:])
+		if n <= 0 {
+			panic("bind param marker is invalid")
+		}
+
+		args = append(args, v)
+		strBuf.WriteString(d.Placeholder(len(args)))
+		s = s[i+n:]
+	}
+	return strBuf.String(), args
+}
+
+// foldBind 合并被引号包裹的占位标记，如 like 语句中的 '%标记%'，通配符并入参数值，替换为新的占位标记
+func foldBind(s string) string {
+	if strings.IndexByte(s, bindMarker) < 0 {
+		return s
+	}
+
+	var strBuf strings.Builder
+	strBuf.Grow(len(s))
+	for {
+		i := strings.IndexByte(s, '\'')
+		if i < 0 {
+			strBuf.WriteString(s)
+			break
+		}
+
+		strBuf.WriteString(s[:i])
+		s = s[i:]
+
+		marker, n := quotedBindParam(s)
+		if n <= 0 {
+			strBuf.WriteByte('\'')
+			s = s[1:]
+			continue
+		}
+
+		strBuf.WriteString(marker)
+		s = s[n:]
+	}
+	return strBuf.String()
+}
+
+// quotedBindParam 解析 s 开头的 '[%]标记[%]'，返回合并通配符后的新标记及原文长度，不匹配时长度为0
+func quotedBindParam(s string) (string, int) {
+	j := 1
+	prefix, suffix := "", ""
+	if j < len(s) && s[j] == '%' {
+		prefix = "%"
+		j++
+	}
+
+	v, n := parseBindParam(s[j:])
+	if n <= 0 {
+		return "", 0
+	}
+
+	j += n
+	if j < len(s) && s[j] == '%' {
+		suffix = "%"
+		j++
+	}
+	if j >= len(s) || s[j] != '\'' {
+		return "", 0
+	}
+
+	return bindParam(prefix + util.Any2String(v) + suffix), j + 1
+}
@@ -0,0 +1,299 @@
+package orm
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/assembly-hub/basics/util"
+	"github.com/assembly-hub/orm/dbtype"
+)
+
+// clickhouse
+// 字符串比较本身区分大小写，bin 使用普通条件，ignore 使用 LOWER 与 ilike
+// update delete 为 mutation（alter table），可选轻量删除（delete from，22.8+）
+// 没有 replace 与 upsert，按 ReplacingMergeTree 的语义直接插入，相同排序键的数据在合并后只保留最新一条，读取时可配合 Final 使用
+type clickhouseDialect struct {
+	BaseDialect
+	lightweightDelete bool
+}
+
+// NewClickHouseDialect ClickHouse 方言，lightweightDelete 为 true 时删除使用 delete from（需要 22.8 及以上版本），
+// 否则使用 alter table ... delete；替换内置实现：orm.RegisterDialect(orm.NewClickHouseDialect(true))
+func NewClickHouseDialect(lightweightDelete bool) Dialect {
+	return clickhouseDialect{
+		BaseDialect:       BaseDialect{Type: dbtype.ClickHouse, EscStart: "`", EscEnd: "`"},
+		lightweightDelete: lightweightDelete,
+	}
+}
+
+func (d clickhouseDialect) Select(q *SelectParts) string {
+	parts := *q
+	// FINAL SAMPLE 紧跟在主表之后
+	var from strings.Builder
+	from.Grow(len(q.Sample) + len(q.Join) + 20)
+	if q.Final {
+		from.WriteString(" FINAL")
+	}
+	if q.Sample != "" {
+		if !isSample(q.Sample) {
+			panic(fmt.Sprintf("sample[%s] is invalid", q.Sample))
+		}
+		from.WriteString(" SAMPLE ")
+		from.WriteString(q.Sample)
+	}
+	from.WriteString(q.Join)
+	parts.Join = from.String()
+
+	var sql strings.Builder
+	sql.Grow(100)
+	sql.WriteString("select ")
+	parts.writeFrom(&sql, d.TableAlias)
+	parts.writeWhere(&sql, "")
+	parts.writeGroupBy(&sql)
+	parts.writeOrderBy(&sql)
+
+	if q.LimitByCols != "" {
+		if len(q.LimitBy) == 1 {
+			sql.WriteString(" limit ")
+			sql.WriteString(util.UintToStr(q.LimitBy[0]))
+		} else if len(q.LimitBy) == 2 {
+			sql.WriteString(" limit ")
+			sql.WriteString(util.UintToStr(q.LimitBy[0]))
+			sql.WriteByte(',')
+			sql.WriteString(util.UintToStr(q.LimitBy[1]))
+		}
+		sql.WriteString(" by ")
+		sql.WriteString(q.LimitByCols)
+	}
+
+	if len(q.Limit) == 1 {
+		sql.WriteString(" limit ")
+		sql.WriteString(util.UintToStr(q.Limit[0]))
+	} else if len(q.Limit) == 2 {
+		sql.WriteString(" limit ")
+		sql.WriteString(util.UintToStr(q.Limit[1]))
+		sql.WriteString(" offset ")
+		sql.WriteString(util.UintToStr(q.Limit[0]))
+	}
+
+	// 不支持 for update
+	if q.Settings != "" {
+		sql.WriteString(" SETTINGS ")
+		sql.WriteString(q.Settings)
+	}
+	return sql.String()
+}
+
+// SetOperation union 需要指定 distinct（union_default_mode 默认为空）
+func (d clickhouseDialect) SetOperation(op string) (string, error) {
+	if op == "union" {
+		return "union distinct", nil
+	}
+	return op, nil
+}
+
+// With 不支持递归
+func (d clickhouseDialect) With(list []*WithParts) (string, error) {
+	for _, w := range list {
+		if w.Recursive {
+			return "", ErrDBFunc
+		}
+	}
+	return d.BaseDialect.With(list)
+}
+
+// RowCompare 元组比较
+func (d clickhouseDialect) RowCompare(left, right []string, op string) (string, error) {
+	return rowCompareSQL(left, right, op), nil
+}
+
+func (d clickhouseDialect) IgnoreCondition(c *Condition) (string, bool) {
+	return postgresIgnoreFormatSubSQL(c.Operator, c.Column, c.Value, c.Raw, c.RawList, c.Data), true
+}
+
+// Regex match() 区分大小写，忽略大小写使用 (?i) 前缀
+func (d clickhouseDialect) Regex(column, pattern string, flag RegexFlag) (string, error) {
+	if flag.Has(RegexIgnoreCase) {
+		pattern = "concat('(?i)'," + pattern + ")"
+	}
+
+	var subSQL strings.Builder
+	subSQL.Grow(len(column) + len(pattern) + 20)
+	if flag.Has(RegexNot) {
+		subSQL.WriteString("not ")
+	}
+	subSQL.WriteString("match(")
+	subSQL.WriteString(column)
+	subSQL.WriteByte(',')
+	subSQL.WriteString(pattern)
+	subSQL.WriteByte(')')
+	return subSQL.String(), nil
+}
+
+var clickhouseDatePart = map[string]string{
+	"year":    "toYear",
+	"quarter": "toQuarter",
+	"month":   "toMonth",
+	"week":    "toISOWeek",
+	"weekday": "toDayOfWeek",
+	"day":     "toDayOfMonth",
+	"hour":    "toHour",
+}
+
+var clickhouseDateTrunc = map[string]string{
+	"year":    "toStartOfYear",
+	"quarter": "toStartOfQuarter",
+	"month":   "toStartOfMonth",
+	"week":    "toMonday",
+	"day":     "toStartOfDay",
+	"hour":    "toStartOfHour",
+}
+
+func (d clickhouseDialect) DatePart(column, part string) (string, error) {
+	f, ok := clickhouseDatePart[part]
+	if !ok {
+		return "", ErrDBFunc
+	}
+	return f + "(" + column + ")", nil
+}
+
+func (d clickhouseDialect) DateTrunc(column, unit string) (string, error) {
+	f, ok := clickhouseDateTrunc[unit]
+	if !ok {
+		return "", ErrDBFunc
+	}
+	return f + "(" + column + ")", nil
+}
+
+func (d clickhouseDialect) JSONHasKey(column, key string) (string, error) {
+	return "JSONHas(" + column + ",'" + key + "')", nil
+}
+
+func (d clickhouseDialect) Replace(w *WriteParts) (string, error) {
+	return d.Insert(w)
+}
+
+func (d clickhouseDialect) Upsert(w *WriteParts) (string, error) {
+	return d.Insert(w)
+}
+
+func (d clickhouseDialect) Update(table, set, where string) string {
+	if where == "" {
+		where = "1=1"
+	}
+
+	var sql strings.Builder
+	sql.Grow(len(table) + len(set) + len(where) + 30)
+	sql.WriteString("alter table ")
+	sql.WriteString(table)
+	sql.WriteString(" update ")
+	sql.WriteString(set)
+	sql.WriteString(" where ")
+	sql.WriteString(where)
+	return sql.String()
+}
+
+func (d clickhouseDialect) Delete(table, where string) string {
+	if where == "" {
+		where = "1=1"
+	}
+
+	var sql strings.Builder
+	sql.Grow(len(table) + len(where) + 30)
+	if d.lightweightDelete {
+		sql.WriteString("delete from ")
+		sql.WriteString(table)
+	} else {
+		sql.WriteString("alter table ")
+		sql.WriteString(table)
+		sql.WriteString(" delete")
+	}
+	sql.WriteString(" where ")
+	sql.WriteString(where)
+	return sql.String()
+}
+
+func (d clickhouseDialect) LastInsertID() bool {
+	return false
+}
+
+// clickhouseSettingsSQL SETTINGS 的内容，按名称排序：k1=v1,k2=v2
+func clickhouseSettingsSQL(settings map[string]interface{}) string {
+	var sql strings.Builder
+	sql.Grow(len(settings) * 20)
+	for _, k := range sortedKeys(settings) {
+		if !isIdentName(k) {
+			panic(fmt.Errorf("setting name[%s] is invalid", k))
+		}
+
+		if sql.Len() > 0 {
+			sql.WriteByte(',')
+		}
+		sql.WriteString(k)
+		sql.WriteByte('=')
+		switch v := settings[k].(type) {
+		case string:
+			sql.WriteByte('\'')
+			sql.WriteString(strings.ReplaceAll(v, "'", "''"))
+			sql.WriteByte('\'')
+		case bool:
+			if v {
+				sql.WriteByte('1')
+			} else {
+				sql.WriteByte('0')
+			}
+		default:
+			// 其他类型只允许数值，避免未转义的内容进入 SQL
+			rv := reflect.ValueOf(v)
+			switch rv.Kind() {
+			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+				sql.WriteString(strconv.FormatInt(rv.Int(), 10))
+			case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+				sql.WriteString(strconv.FormatUint(rv.Uint(), 10))
+			case reflect.Float32, reflect.Float64:
+				sql.WriteString(strconv.FormatFloat(rv.Float(), 'f', -1, rv.Type().Bits()))
+			default:
+				panic(fmt.Errorf("setting[%s] value type[%T] is invalid", k, v))
+			}
+		}
+	}
+	return sql.String()
+}
+
+// isSample SAMPLE 子句：k [OFFSET m]
+func isSample(s string) bool {
+	fields := strings.Fields(s)
+	switch len(fields) {
+	case 1:
+		return isSampleRatio(fields[0])
+	case 3:
+		return isSampleRatio(fields[0]) && strings.EqualFold(fields[1], "offset") && isSampleRatio(fields[2])
+	}
+	return false
+}
+
+// isSampleRatio 小数、整数或分数（n/m）
+func isSampleRatio(s string) bool {
+	num, den, ok := strings.Cut(s, "/")
+	if !ok {
+		return isSampleNumber(num)
+	}
+	return isSampleNumber(num) && isSampleNumber(den)
+}
+
+func isSampleNumber(s string) bool {
+	digits, dot := 0, false
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c >= '0' && c <= '9':
+			digits++
+		case c == '.' && !dot:
+			dot = true
+		default:
+			return false
+		}
+	}
+	return digits > 0
+}
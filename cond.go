@@ -0,0 +1,154 @@
+package orm
+
+import (
+	"fmt"
+
+	"github.com/assembly-hub/basics/util"
+)
+
+// Cond 条件树，由 Q Not And Or 组合，条件按顺序生成，同一字段可以出现多次
+// 作为 $and $or 的值使用（Where Wheres Having HavingSome UpdateByWhere DeleteByWhere 均支持），
+// 值也可以是 []*Cond，此时以 key 对应的 and or 连接，如：
+// orm.Where{"$and": orm.Q("id__gt", 1).And(orm.Q("id__lt", 10)).Or(orm.Not(orm.Q("name", "a")))}
+type Cond struct {
+	// op 为空时是叶子节点（key value，格式化后 value 为条件），否则为 and or
+	op    string
+	not   bool
+	key   string
+	value interface{}
+	list  []*Cond
+}
+
+// Q 单个条件，key value 与 Where 的规则一致，支持 tag、# 原始字段与所有操作符
+func Q(key string, value interface{}) *Cond {
+	if key == "" || value == nil {
+		panic("Fields and conditions cannot be nil")
+	}
+
+	if v, ok := value.(*ORM); ok {
+		value = v.cond(false)
+	}
+	return &Cond{key: key, value: value}
+}
+
+// Not 条件取反
+func Not(c *Cond) *Cond {
+	if c == nil {
+		panic("cond is nil")
+	}
+
+	n := *c
+	n.not = !c.not
+	return &n
+}
+
+// And 与 list 以 and 连接
+func (c *Cond) And(list ...*Cond) *Cond {
+	return c.join("and", list)
+}
+
+// Or 与 list 以 or 连接
+func (c *Cond) Or(list ...*Cond) *Cond {
+	return c.join("or", list)
+}
+
+func (c *Cond) join(op string, list []*Cond) *Cond {
+	n := &Cond{op: op}
+	// 相同连接方式的条件合并为一层
+	if c.op == op && !c.not {
+		n.list = append(n.list, c.list...)
+	} else {
+		n.list = append(n.list, c)
+	}
+
+	for _, sub := range list {
+		if sub == nil {
+			panic("cond is nil")
+		}
+		n.list = append(n.list, sub)
+	}
+	return n
+}
+
+// condTree $and $or 的值为条件树时返回条件树，[]*Cond 以 key 对应的方式连接
+func condTree(key string, value interface{}) (*Cond, bool) {
+	var c *Cond
+	switch v := value.(type) {
+	case *Cond:
+		c = v
+	case []*Cond:
+		if len(v) <= 0 {
+			return nil, false
+		}
+		c = &Cond{op: key[1:], list: v}
+	default:
+		return nil, false
+	}
+
+	if key != "$and" && key != "$or" {
+		panic(fmt.Sprintf("cond can only be used as the value of $and or $or, key[%s]", key))
+	}
+	return c, true
+}
+
+// formatCondTree 格式化条件树，叶子节点的 value 为格式化后的条件
+func (q *BaseQuery) formatCondTree(c *Cond) *Cond {
+	n := &Cond{op: c.op, not: c.not}
+	if c.op == "" {
+		n.value = q.formatCond(map[string]interface{}{c.key: c.value})
+		return n
+	}
+
+	for _, sub := range c.list {
+		n.list = append(n.list, q.formatCondTree(sub))
+	}
+	return n
+}
+
+// condSQL 条件树的SQL，or 连接的叶子节点加括号（取反与 $raw 已有括号）
+func (p *queryModel) condSQL(c *Cond) string {
+	sql := ""
+	if c.op == "" {
+		where, _ := c.value.(map[string]interface{})
+		sql = p.andSQL(where)
+	} else {
+		list := make([]string, 0, len(c.list))
+		for _, sub := range c.list {
+			subSQL := p.condSQL(sub)
+			if subSQL == "" {
+				continue
+			}
+			if c.op == "or" && sub.op == "" && !sub.not && !sub.wrapped() {
+				subSQL = "(" + subSQL + ")"
+			}
+			list = append(list, subSQL)
+		}
+
+		if len(list) == 1 {
+			sql = list[0]
+		} else if len(list) > 1 {
+			sql = "(" + util.JoinArr(list, " "+c.op+" ") + ")"
+		}
+	}
+
+	if sql == "" || !c.not {
+		return sql
+	}
+	if sql[0] == '(' {
+		return "(not " + sql + ")"
+	}
+	return "(not (" + sql + "))"
+}
+
+// wrapped 格式化后的叶子节点是否为 $raw 或取反的条件，生成的SQL已有括号
+func (c *Cond) wrapped() bool {
+	where, _ := c.value.(map[string]interface{})
+	if len(where) != 1 {
+		return false
+	}
+
+	for k := range where {
+		return k == "$raw" || k[0] == '~'
+	}
+	return false
+}
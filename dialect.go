@@ -0,0 +1,815 @@
+// Package orm
+package orm
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/assembly-hub/basics/set"
+	"github.com/assembly-hub/basics/util"
+	"github.com/assembly-hub/db"
+)
+
+// Dialect 数据库方言，封装不同数据库SQL的差异，内置数据库均已实现
+// 可通过 RegisterDialect 新增数据库或替换内置实现，自定义方言必须嵌入 BaseDialect（由 baseDialect 方法保证），只重写有差异的方法；
+// 接口新增的方法由 BaseDialect 提供通用实现（不支持的功能返回 ErrDBFunc），自定义方言升级后无需修改
+type Dialect interface {
+	// DBType 数据库类型，新增数据库请使用 dbtype 之外的值
+	DBType() int
+	// Quote 标识符（表名、字段）转义的起止字符
+	Quote() (start, end string)
+	// Placeholder 绑定参数的占位符，n 从 1 开始
+	Placeholder(n int) string
+	// TimeLiteral 时间值，val 为带引号的时间字符串（yyyy-mm-dd hh:mm:ss）或绑定参数
+	TimeLiteral(val string) string
+	// TableAlias 表或子查询的别名
+	TableAlias(table, alias string) string
+	// Select 查询语句，包括分页与锁
+	Select(q *SelectParts) string
+	// With 公共表表达式（WITH 子句，以空格结尾），不支持返回 ErrDBFunc
+	With(list []*WithParts) (string, error)
+	// SetOperation 集合运算的关键字，op 为：union、union all、intersect、except，不支持返回 ErrDBFunc
+	SetOperation(op string) (string, error)
+	// BinCondition 强制区分大小写的条件（bin 操作符），ok=false 时使用普通条件
+	BinCondition(c *Condition) (sql string, ok bool)
+	// IgnoreCondition 忽略大小写的条件（ignore 操作符），ok=false 时使用普通条件
+	IgnoreCondition(c *Condition) (sql string, ok bool)
+	// Regex 正则匹配（regex iregex nregex 操作符），column 已转义，pattern 为格式化后的值，不支持返回 ErrDBFunc
+	Regex(column, pattern string, flag RegexFlag) (string, error)
+	// Search 全文检索（search 操作符），column 已转义，query 为格式化后的值，不支持返回 ErrDBFunc
+	Search(column, query string) (string, error)
+	// SearchRank 全文检索的相关度，值越大越相关，OrderBySearch 使用，不支持返回 ErrDBFunc
+	SearchRank(column, query string) (string, error)
+	// DatePart 日期的组成部分（整数）：year quarter month week（ISO 周）weekday（1-7，周一为 1）day hour，不支持返回 ErrDBFunc
+	DatePart(column, part string) (string, error)
+	// DateTrunc 日期截断到所在时间段的开始：year quarter month week（周一）day hour，不支持返回 ErrDBFunc
+	DateTrunc(column, unit string) (string, error)
+	// NullsOrder 指定空值位置的排序（不包括 order by），column 已转义，desc 为降序，nullsFirst 为空值在前
+	NullsOrder(column string, desc, nullsFirst bool) string
+	// ValuesOrder 按值列表排序（不包括 order by），column 已转义，values 为格式化后的值，列表中的值按顺序在前，其他在后
+	ValuesOrder(column string, values []string) string
+	// Grouping 普通分组与分组小计（不包括 group by），groupBy 为普通分组（可以为空），kind 为 rollup、cube、grouping sets，
+	// sets 为已转义的字段，rollup cube 只有一组，不支持返回 ErrDBFunc
+	Grouping(groupBy, kind string, sets [][]string) (string, error)
+	// GroupingFunc GROUPING(column)，字段在小计行中被汇总时为 1，column 已转义，不支持返回 ErrDBFunc
+	GroupingFunc(column string) (string, error)
+	// Window 窗口函数：fn OVER (over)，不支持返回 ErrDBFunc
+	Window(fn, over string) (string, error)
+	// RowCompare 行值比较：(a,b) op (x,y)，op 为 > 或 <，不支持返回 ErrDBFunc（SeekPage 使用 or 展开）
+	RowCompare(left, right []string, op string) (string, error)
+	// JSONPath json 字段中 path 对应的值（文本），如：meta->a.b 的 path 为 [a b]，数字为数组下标
+	JSONPath(column string, path []string) string
+	// JSONContains json 字段包含 value（json_contains 操作符），value 为格式化后的 json 字符串
+	JSONContains(column, value string) (string, error)
+	// JSONHasKey json 字段包含 key（has_key 操作符），key 中的单引号已转义
+	JSONHasKey(column, key string) (string, error)
+	// Insert 插入语句，多行数据为批量插入
+	Insert(w *WriteParts) (string, error)
+	// Replace 替换语句
+	Replace(w *WriteParts) (string, error)
+	// Upsert 数据存在更新，不存在插入
+	Upsert(w *WriteParts) (string, error)
+	// Update 更新语句，table 已转义，where 为空时更新全部数据
+	Update(table, set, where string) string
+	// Delete 删除语句，table 已转义，where 为空时删除全部数据
+	Delete(table, where string) string
+	// LastInsertID 执行结果是否支持 LastInsertId
+	LastInsertID() bool
+
+	// baseDialect 只有 BaseDialect 实现，自定义方言必须嵌入 BaseDialect
+	baseDialect()
+}
+
+// VersionDialect 与数据库版本相关的方言，NewReferenceWithVersion 使用
+type VersionDialect interface {
+	Dialect
+	// WithVersion 指定版本的方言，如：5.7 8.0.20 11g 12c 2008 2012
+	WithVersion(version string) (Dialect, error)
+}
+
+// SelectParts 查询语句的各个部分，表名、字段均已转义
+type SelectParts struct {
+	// Columns 查询字段，包括 distinct
+	Columns string
+	Table   string
+	Alias   string
+	// Join 关联语句，以空格开头
+	Join    string
+	Where   string
+	GroupBy string
+	Having  string
+	OrderBy string
+	// Limit [size] 或 [offset, size]
+	Limit     []uint
+	ForUpdate bool
+	// PrimaryKey 主键，未转义
+	PrimaryKey string
+
+	// 以下仅 ClickHouse 使用
+	// Final 主表使用 FINAL
+	Final bool
+	// Sample 主表的 SAMPLE 子句，如：0.1 1/10 OFFSET 1/2
+	Sample string
+	// LimitBy limit n by 的 [size] 或 [offset, size]
+	LimitBy []uint
+	// LimitByCols limit n by 的字段，为空时不生效
+	LimitByCols string
+	// Settings 查询的 SETTINGS，如：max_threads=8
+	Settings string
+}
+
+// WithParts 公共表表达式（WITH）的一项
+type WithParts struct {
+	// Name 已转义的名称
+	Name string
+	// Columns 已转义的字段，来自 ref 中同名的表定义，未定义时为空
+	Columns []string
+	// SQL 查询语句，递归时为 anchor union all recursive
+	SQL       string
+	Recursive bool
+}
+
+// Condition 查询条件的数据
+type Condition struct {
+	// Operator 操作符，如：eq in contains
+	Operator string
+	// Column 已转义的字段
+	Column string
+	// Value 格式化后的值，如：'a' (1,2)
+	Value string
+	// Raw 字符串的原始值，单引号已转义，like 类条件使用
+	Raw string
+	// RawList 数组的原始值，in like 类条件使用
+	RawList []string
+	// Data 条件的原始数据
+	Data interface{}
+	// Bin Ignore 操作符的 bin ignore 前缀，仅自定义操作符（RegisterOperator）使用
+	Bin    bool
+	Ignore bool
+}
+
+// RegexFlag 正则匹配方式
+type RegexFlag int
+
+const (
+	// RegexNot 不匹配：nregex
+	RegexNot RegexFlag = 1 << iota
+	// RegexIgnoreCase 忽略大小写：iregex i_regex
+	RegexIgnoreCase
+	// RegexCaseSensitive 区分大小写：b_regex
+	RegexCaseSensitive
+)
+
+// Has 是否包含 f
+func (flag RegexFlag) Has(f RegexFlag) bool {
+	return flag&f != 0
+}
+
+// WriteParts 写入（insert replace upsert）的数据，字段未转义，值已格式化
+type WriteParts struct {
+	Table      string
+	PrimaryKey string
+	// UniqueKeys 唯一键，已排序
+	UniqueKeys []string
+	Cols       []string
+	// Rows 每行的值，与 Cols 一一对应
+	Rows [][]string
+	// UnionAll 多行数据使用 union all 连接，如：oracle merge
+	UnionAll bool
+}
+
+// ConflictKeys 判断数据冲突的字段：字段包含主键时为主键，否则包含全部唯一键时为唯一键，都不满足返回 nil
+func (w *WriteParts) ConflictKeys() []string {
+	colSet := set.New[string]()
+	colSet.Add(w.Cols...)
+	if w.PrimaryKey != "" && colSet.Has(w.PrimaryKey) {
+		return []string{w.PrimaryKey}
+	}
+
+	if len(w.UniqueKeys) <= 0 {
+		return nil
+	}
+	for _, k := range w.UniqueKeys {
+		if !colSet.Has(k) {
+			return nil
+		}
+	}
+	return w.UniqueKeys
+}
+
+// updateCols 数据冲突时需要更新的字段，排除 keys
+func (w *WriteParts) updateCols(keys []string) []string {
+	keySet := set.New[string]()
+	keySet.Add(keys...)
+	cols := make([]string, 0, len(w.Cols))
+	for _, k := range w.Cols {
+		if !keySet.Has(k) {
+			cols = append(cols, k)
+		}
+	}
+	return cols
+}
+
+var (
+	dialectMap  = map[int]Dialect{}
+	dialectLock sync.RWMutex
+)
+
+// RegisterDialect 注册数据库方言，数据库类型已存在时替换（包括内置数据库），只影响之后创建的 Reference
+func RegisterDialect(d Dialect) {
+	if d == nil {
+		panic("dialect is nil")
+	}
+
+	dialectLock.Lock()
+	defer dialectLock.Unlock()
+	dialectMap[d.DBType()] = d
+}
+
+// GetDialect 获取已注册的数据库方言，不存在返回 nil
+func GetDialect(dbType int) Dialect {
+	dialectLock.RLock()
+	defer dialectLock.RUnlock()
+	return dialectMap[dbType]
+}
+
+// BaseDialect 通用方言：? 占位符，limit offset 分页，for update 锁，多行 values 插入，不支持 Replace 与 Upsert
+type BaseDialect struct {
+	Type     int
+	EscStart string
+	EscEnd   string
+}
+
+func (d BaseDialect) baseDialect() {}
+
+func (d BaseDialect) DBType() int {
+	return d.Type
+}
+
+func (d BaseDialect) Quote() (start, end string) {
+	return d.EscStart, d.EscEnd
+}
+
+func (d BaseDialect) Placeholder(n int) string {
+	return "?"
+}
+
+func (d BaseDialect) TimeLiteral(val string) string {
+	return val
+}
+
+func (d BaseDialect) TableAlias(table, alias string) string {
+	return table + " as " + alias
+}
+
+func (d BaseDialect) Select(q *SelectParts) string {
+	var sql strings.Builder
+	sql.Grow(100)
+	sql.WriteString("select ")
+	q.writeFrom(&sql, d.TableAlias)
+	q.writeWhere(&sql, "")
+	q.writeGroupBy(&sql)
+	q.writeOrderBy(&sql)
+
+	if len(q.Limit) == 1 {
+		sql.WriteString(" limit ")
+		sql.WriteString(util.UintToStr(q.Limit[0]))
+	} else if len(q.Limit) == 2 {
+		sql.WriteString(" limit ")
+		sql.WriteString(util.UintToStr(q.Limit[1]))
+		sql.WriteString(" offset ")
+		sql.WriteString(util.UintToStr(q.Limit[0]))
+	}
+
+	if q.ForUpdate {
+		sql.WriteString(" for update")
+	}
+	return sql.String()
+}
+
+func (d BaseDialect) BinCondition(c *Condition) (string, bool) {
+	return "", false
+}
+
+func (d BaseDialect) IgnoreCondition(c *Condition) (string, bool) {
+	return "", false
+}
+
+func (d BaseDialect) Regex(column, pattern string, flag RegexFlag) (string, error) {
+	return "", ErrDBFunc
+}
+
+func (d BaseDialect) Search(column, query string) (string, error) {
+	return "", ErrDBFunc
+}
+
+func (d BaseDialect) SearchRank(column, query string) (string, error) {
+	return "", ErrDBFunc
+}
+
+// DatePart EXTRACT(YEAR FROM 字段)，仅支持 year month day hour
+func (d BaseDialect) DatePart(column, part string) (string, error) {
+	switch part {
+	case "year", "month", "day", "hour":
+		return "EXTRACT(" + strings.ToUpper(part) + " FROM " + column + ")", nil
+	}
+	return "", ErrDBFunc
+}
+
+func (d BaseDialect) DateTrunc(column, unit string) (string, error) {
+	return "", ErrDBFunc
+}
+
+// NullsOrder 字段 desc nulls first
+func (d BaseDialect) NullsOrder(column string, desc, nullsFirst bool) string {
+	sql := column + " asc"
+	if desc {
+		sql = column + " desc"
+	}
+	if nullsFirst {
+		return sql + " nulls first"
+	}
+	return sql + " nulls last"
+}
+
+// ValuesOrder case 字段 when 值1 then 0 when 值2 then 1 else n end
+func (d BaseDialect) ValuesOrder(column string, values []string) string {
+	var sql strings.Builder
+	sql.Grow(len(column) + len(values)*20 + 20)
+	sql.WriteString("case ")
+	sql.WriteString(column)
+	for i, v := range values {
+		sql.WriteString(" when ")
+		sql.WriteString(v)
+		sql.WriteString(" then ")
+		sql.WriteString(strconv.Itoa(i))
+	}
+	sql.WriteString(" else ")
+	sql.WriteString(strconv.Itoa(len(values)))
+	sql.WriteString(" end")
+	return sql.String()
+}
+
+// Grouping 普通分组,rollup(...)、cube(...)、grouping sets(...)
+func (d BaseDialect) Grouping(groupBy, kind string, sets [][]string) (string, error) {
+	return groupingSetsSQL(groupBy, kind, sets)
+}
+
+// GroupingFunc GROUPING(...)
+func (d BaseDialect) GroupingFunc(column string) (string, error) {
+	return "GROUPING(" + column + ")", nil
+}
+
+// Window fn OVER (...)
+func (d BaseDialect) Window(fn, over string) (string, error) {
+	return fn + " OVER (" + over + ")", nil
+}
+
+func (d BaseDialect) RowCompare(left, right []string, op string) (string, error) {
+	return "", ErrDBFunc
+}
+
+// JSONPath JSON_VALUE(字段,'$.a.b')
+func (d BaseDialect) JSONPath(column string, path []string) string {
+	return "JSON_VALUE(" + column + ",'" + jsonPathLiteral(path) + "')"
+}
+
+func (d BaseDialect) JSONContains(column, value string) (string, error) {
+	return "", ErrDBFunc
+}
+
+// JSONHasKey JSON_EXISTS(字段,'$."key"')
+func (d BaseDialect) JSONHasKey(column, key string) (string, error) {
+	return "JSON_EXISTS(" + column + ",'" + jsonPathLiteral([]string{key}) + "')", nil
+}
+
+func (d BaseDialect) Insert(w *WriteParts) (string, error) {
+	return insertSQL("insert", d.EscStart, d.EscEnd, w), nil
+}
+
+func (d BaseDialect) Replace(w *WriteParts) (string, error) {
+	return "", fmt.Errorf("当前数据库不支持Replace方法，请使用Upsert方法")
+}
+
+func (d BaseDialect) Upsert(w *WriteParts) (string, error) {
+	return "", ErrDBFunc
+}
+
+func (d BaseDialect) Update(table, set, where string) string {
+	var sql strings.Builder
+	sql.Grow(len(table) + len(set) + len(where) + 20)
+	sql.WriteString("update ")
+	sql.WriteString(table)
+	sql.WriteString(" set ")
+	sql.WriteString(set)
+	if where != "" {
+		sql.WriteString(" where ")
+		sql.WriteString(where)
+	}
+	return sql.String()
+}
+
+func (d BaseDialect) Delete(table, where string) string {
+	var sql strings.Builder
+	sql.Grow(len(table) + len(where) + 20)
+	sql.WriteString("delete from ")
+	sql.WriteString(table)
+	if where != "" {
+		sql.WriteString(" where ")
+		sql.WriteString(where)
+	}
+	return sql.String()
+}
+
+func (d BaseDialect) LastInsertID() bool {
+	return true
+}
+
+// With with [recursive] name as (...)，不写字段列表
+func (d BaseDialect) With(list []*WithParts) (string, error) {
+	return withSQL(list, "recursive ", false), nil
+}
+
+func (d BaseDialect) SetOperation(op string) (string, error) {
+	return op, nil
+}
+
+// nullsCaseOrder 不支持 nulls first/last 时的空值排序：case when 字段 is null then 0 else 1 end,字段 desc
+func nullsCaseOrder(column string, desc, nullsFirst bool) string {
+	var sql strings.Builder
+	sql.Grow(len(column)*2 + 50)
+	sql.WriteString("case when ")
+	sql.WriteString(column)
+	if nullsFirst {
+		sql.WriteString(" is null then 0 else 1 end,")
+	} else {
+		sql.WriteString(" is null then 1 else 0 end,")
+	}
+	sql.WriteString(column)
+	if desc {
+		sql.WriteString(" desc")
+	} else {
+		sql.WriteString(" asc")
+	}
+	return sql.String()
+}
+
+// rowCompareSQL (a,b) op (x,y)
+func rowCompareSQL(left, right []string, op string) string {
+	return "(" + strings.Join(left, ",") + ")" + op + "(" + strings.Join(right, ",") + ")"
+}
+
+// withSQL WITH 子句，recursive 为递归时的关键字（可以为空），columns 为 true 时写入字段列表
+func withSQL(list []*WithParts, recursive string, columns bool) string {
+	var sql strings.Builder
+	sql.Grow(len(list) * 100)
+	sql.WriteString("with ")
+	for _, w := range list {
+		if w.Recursive {
+			sql.WriteString(recursive)
+			break
+		}
+	}
+	for i, w := range list {
+		if i > 0 {
+			sql.WriteByte(',')
+		}
+		sql.WriteString(w.Name)
+		if columns && len(w.Columns) > 0 {
+			sql.WriteByte('(')
+			sql.WriteString(util.JoinArr(w.Columns, ","))
+			sql.WriteByte(')')
+		}
+		sql.WriteString(" as (")
+		sql.WriteString(w.SQL)
+		sql.WriteByte(')')
+	}
+	sql.WriteByte(' ')
+	return sql.String()
+}
+
+// writeFrom 字段 from 表 join
+func (q *SelectParts) writeFrom(sql *strings.Builder, tableAlias func(table, alias string) string) {
+	if q.Table == "" {
+		panic("MainTable is nil")
+	}
+
+	sql.WriteString(q.Columns)
+	sql.WriteString(" from ")
+	if q.Alias != "" {
+		sql.WriteString(tableAlias(q.Table, q.Alias))
+	} else {
+		sql.WriteString(q.Table)
+	}
+	sql.WriteString(q.Join)
+}
+
+// writeWhere where 条件，cond 为附加的条件，如：oracle rownum
+func (q *SelectParts) writeWhere(sql *strings.Builder, cond string) {
+	if q.Where != "" {
+		sql.WriteString(" where ")
+		sql.WriteString(q.Where)
+		if cond != "" {
+			sql.WriteString(" and ")
+			sql.WriteString(cond)
+		}
+	} else if cond != "" {
+		sql.WriteString(" where ")
+		sql.WriteString(cond)
+	}
+}
+
+func (q *SelectParts) writeGroupBy(sql *strings.Builder) {
+	if q.GroupBy != "" {
+		sql.WriteString(" group by ")
+		sql.WriteString(q.GroupBy)
+		if q.Having != "" {
+			sql.WriteString(" having ")
+			sql.WriteString(q.Having)
+		}
+	}
+}
+
+func (q *SelectParts) writeOrderBy(sql *strings.Builder) {
+	if q.OrderBy != "" {
+		sql.WriteString(" order by ")
+		sql.WriteString(q.OrderBy)
+	}
+}
+
+func quoteName(escStart, escEnd, name string) string {
+	return escStart + name + escEnd
+}
+
+// insertSQL tp into 表(字段) values(...),(...)
+func insertSQL(tp, escStart, escEnd string, w *WriteParts) string {
+	var sql strings.Builder
+	sql.Grow(len(w.Rows)*len(w.Cols)*5 + 100)
+	sql.WriteString(tp)
+	sql.WriteString(" into ")
+	sql.WriteString(quoteName(escStart, escEnd, w.Table))
+	sql.WriteByte('(')
+	sql.WriteString(connectStrArr(w.Cols, ",", escStart, escEnd))
+	sql.WriteString(") values")
+	for i, row := range w.Rows {
+		if i > 0 {
+			sql.WriteByte(',')
+		}
+		sql.WriteByte('(')
+		sql.WriteString(util.JoinArr(row, ","))
+		sql.WriteByte(')')
+	}
+	return sql.String()
+}
+
+// mergeSQL merge into 语法的 upsert，目标表别名为 T，数据源 source 的别名为 S
+func mergeSQL(escStart, escEnd string, tableAlias func(table, alias string) string,
+	source string, w *WriteParts, keys []string) string {
+	t := quoteName(escStart, escEnd, "T") + "." + escStart
+	s := quoteName(escStart, escEnd, "S") + "." + escStart
+
+	var sql strings.Builder
+	sql.Grow(len(w.Cols)*30 + len(source) + 100)
+	sql.WriteString("MERGE INTO ")
+	sql.WriteString(tableAlias(quoteName(escStart, escEnd, w.Table), quoteName(escStart, escEnd, "T")))
+	sql.WriteString(" USING ")
+	sql.WriteString(tableAlias(source, quoteName(escStart, escEnd, "S")))
+	sql.WriteString(" ON (")
+	for i, k := range keys {
+		if i > 0 {
+			sql.WriteString(" and ")
+		}
+		sql.WriteString(t)
+		sql.WriteString(k)
+		sql.WriteString(escEnd)
+		sql.WriteByte('=')
+		sql.WriteString(s)
+		sql.WriteString(k)
+		sql.WriteString(escEnd)
+	}
+	sql.WriteString(") WHEN MATCHED THEN UPDATE SET ")
+	for i, k := range w.updateCols(keys) {
+		if i > 0 {
+			sql.WriteByte(',')
+		}
+		sql.WriteString(t)
+		sql.WriteString(k)
+		sql.WriteString(escEnd)
+		sql.WriteByte('=')
+		sql.WriteString(s)
+		sql.WriteString(k)
+		sql.WriteString(escEnd)
+	}
+	sql.WriteString(" WHEN NOT MATCHED THEN INSERT(")
+	sql.WriteString(connectStrArr(w.Cols, ",", escStart, escEnd))
+	sql.WriteString(") VALUES(")
+	sql.WriteString(connectStrArr(w.Cols, ",", s, escEnd))
+	sql.WriteByte(')')
+	return sql.String()
+}
+
+// rowNumberAlias ROW_NUMBER() 分页的序号字段，位于结果的最后一列，查询时由 rowNumberRows 去除
+const rowNumberAlias = defaultAliasPrefix + "rn"
+
+// rowNumberSQL ROW_NUMBER() 分页，用于不支持 offset fetch 的旧版本数据库，Limit 为 [offset, size]
+// 未排序时按主键排序，没有主键使用 defOrder；distinct 无法与 ROW_NUMBER() 共用，返回 ErrDBFunc
+func rowNumberSQL(escStart, escEnd string, tableAlias func(table, alias string) string,
+	q *SelectParts, defOrder string) string {
+	if strings.HasPrefix(q.Columns, "distinct ") {
+		panic(ErrDBFunc)
+	}
+
+	parts := *q
+	if parts.Columns == "*" {
+		if parts.Alias != "" {
+			parts.Columns = parts.Alias + ".*"
+		} else {
+			parts.Columns = parts.Table + ".*"
+		}
+	}
+
+	orderBy := q.OrderBy
+	if orderBy == "" {
+		if q.PrimaryKey != "" {
+			// 带上主表，避免与关联表的同名字段冲突
+			orderBy = quoteName(escStart, escEnd, q.PrimaryKey)
+			if q.Alias != "" {
+				orderBy = q.Alias + "." + orderBy
+			} else if !strings.HasPrefix(q.Table, "(") {
+				orderBy = q.Table + "." + orderBy
+			}
+		} else {
+			orderBy = defOrder
+		}
+	}
+	rn := quoteName(escStart, escEnd, rowNumberAlias)
+	parts.Columns += ",ROW_NUMBER() OVER(ORDER BY " + orderBy + ") as " + rn
+
+	var inner strings.Builder
+	inner.Grow(100)
+	inner.WriteString("(select ")
+	parts.writeFrom(&inner, tableAlias)
+	parts.writeWhere(&inner, "")
+	parts.writeGroupBy(&inner)
+	inner.WriteByte(')')
+
+	var sql strings.Builder
+	sql.Grow(inner.Len() + 100)
+	sql.WriteString("select * from ")
+	sql.WriteString(tableAlias(inner.String(), quoteName(escStart, escEnd, defaultAliasPrefix+"page")))
+	sql.WriteString(" where ")
+	sql.WriteString(rn)
+	sql.WriteByte('>')
+	sql.WriteString(util.UintToStr(q.Limit[0]))
+	sql.WriteString(" and ")
+	sql.WriteString(rn)
+	sql.WriteString("<=")
+	sql.WriteString(util.UintToStr(q.Limit[0] + q.Limit[1]))
+	sql.WriteString(" order by ")
+	sql.WriteString(rn)
+	return sql.String()
+}
+
+// rowNumberRows 去除 ROW_NUMBER() 分页的序号字段（最后一列），结果与普通分页一致
+type rowNumberRows struct {
+	db.Rows
+}
+
+// hideRowNumber 最后一列为序号字段时去除，否则原样返回
+func hideRowNumber(rows db.Rows) db.Rows {
+	cols, err := rows.Columns()
+	if err != nil || len(cols) <= 1 || !strings.EqualFold(cols[len(cols)-1], rowNumberAlias) {
+		return rows
+	}
+	return rowNumberRows{rows}
+}
+
+func (r rowNumberRows) Columns() ([]string, error) {
+	cols, err := r.Rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	return cols[:len(cols)-1], nil
+}
+
+func (r rowNumberRows) ColumnTypes() ([]db.ColumnType, error) {
+	colType, err := r.Rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+	return colType[:len(colType)-1], nil
+}
+
+func (r rowNumberRows) Scan(dest ...interface{}) error {
+	var rn interface{}
+	row := make([]interface{}, len(dest)+1)
+	copy(row, dest)
+	row[len(dest)] = &rn
+	return r.Rows.Scan(row...)
+}
+
+// parseVersion 版本号的数字部分，如：8.0.20 -> [8 0 20]，11g -> [11]，2008 R2 -> [2008]
+func parseVersion(version string) ([]int, error) {
+	version = strings.TrimSpace(version)
+	var ver []int
+	n, digits := 0, 0
+	for _, c := range version {
+		if c >= '0' && c <= '9' {
+			n = n*10 + int(c-'0')
+			digits++
+			continue
+		}
+		if digits <= 0 {
+			break
+		}
+		ver = append(ver, n)
+		n, digits = 0, 0
+		if c != '.' {
+			break
+		}
+	}
+	if digits > 0 {
+		ver = append(ver, n)
+	}
+
+	if len(ver) <= 0 {
+		return nil, fmt.Errorf("database version[%s] is invalid", version)
+	}
+	return ver, nil
+}
+
+// versionBelow 版本低于 target，未指定版本时为 false（按最新版本处理）
+func versionBelow(ver []int, target ...int) bool {
+	if len(ver) <= 0 {
+		return false
+	}
+	for i, t := range target {
+		v := 0
+		if i < len(ver) {
+			v = ver[i]
+		}
+		if v != t {
+			return v < t
+		}
+	}
+	return false
+}
+
+// jsonPathLiteral json path：$.a.b[0]，非字母数字的 key 使用双引号，如：$."a-b"
+func jsonPathLiteral(path []string) string {
+	var buf strings.Builder
+	buf.Grow(len(path) * 10)
+	buf.WriteByte('$')
+	for _, k := range path {
+		if isIndexKey(k) {
+			buf.WriteByte('[')
+			buf.WriteString(k)
+			buf.WriteByte(']')
+		} else if isIdentName(k) {
+			buf.WriteByte('.')
+			buf.WriteString(k)
+		} else {
+			buf.WriteString(".\"")
+			buf.WriteString(strings.ReplaceAll(k, `"`, `\"`))
+			buf.WriteByte('"')
+		}
+	}
+	return buf.String()
+}
+
+// isIdentName 字母、数字、下划线组成，不以数字开头
+func isIdentName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, c := range name {
+		if c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (i > 0 && c >= '0' && c <= '9') {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+// isIndexKey 数组下标
+func isIndexKey(k string) bool {
+	if k == "" {
+		return false
+	}
+	for _, c := range k {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// sortedStrs 排序后的副本
+func sortedStrs(arr []string) []string {
+	arr = append([]string{}, arr...)
+	sort.Strings(arr)
+	return arr
+}
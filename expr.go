@@ -0,0 +1,182 @@
+package orm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 表达式构建，结果为 *RawSQL，可用于 SelectExpr OrderExpr GroupByExpr、$raw 以及其他表达式的参数
+// 参数：orm.Col 为字段（按 tag 解析并转义），*RawSQL 为表达式，*Cond 为条件，其他为值（拼接或绑定参数）
+// 未使用 As 设置别名时，SelectExpr 使用默认别名：Func 为 字段_函数，Arith 为 字段_运算_字段（跨表字段为 tag_字段）
+
+// arithNames 运算符对应的默认别名
+var arithNames = map[string]string{
+	"+": "add",
+	"-": "sub",
+	"*": "mul",
+	"/": "div",
+	"%": "mod",
+}
+
+// Func 数据库函数，如：orm.Func("COALESCE", orm.Col("tb2.price"), orm.Col("price"), 0)，
+// orm.Func("DATE_FORMAT", orm.Col("created_at"), "%Y")
+func Func(name string, args ...interface{}) *RawSQL {
+	if !isIdentName(name) {
+		panic(fmt.Sprintf("function[%s] name is invalid", name))
+	}
+
+	var sql strings.Builder
+	sql.Grow(len(name) + len(args)*2 + 2)
+	sql.WriteString(name)
+	sql.WriteByte('(')
+	for i := range args {
+		if i > 0 {
+			sql.WriteByte(',')
+		}
+		sql.WriteByte('?')
+	}
+	sql.WriteByte(')')
+
+	r := newExpr(sql.String(), args)
+	r.name = joinExprName(firstExprName(args), strings.ToLower(name))
+	return r
+}
+
+// Sub 子查询作为表达式（标量子查询），子查询中可以使用 orm.OuterCol 引用外层查询的字段，如：
+// orm.Sub(tb2.Select("#count(*)").Where("ref_id", orm.OuterCol("id"))).As("cnt")
+func Sub(sub *ORM) *RawSQL {
+	if sub == nil {
+		panic("subquery is nil")
+	}
+	return newExpr("?", []interface{}{sub})
+}
+
+// Coalesce COALESCE(a,b,...)
+func Coalesce(args ...interface{}) *RawSQL {
+	return Func("COALESCE", args...)
+}
+
+// Arith 四则运算：+ - * / %，如：orm.Arith(orm.Col("price"), "*", orm.Col("qty")).As("total")
+func Arith(left interface{}, op string, right interface{}) *RawSQL {
+	opName, ok := arithNames[op]
+	if !ok {
+		panic(fmt.Sprintf("arithmetic operator[%s] is invalid", op))
+	}
+
+	r := newExpr("(?"+op+"?)", []interface{}{left, right})
+	r.name = joinExprName(joinExprName(exprName(left), opName), exprName(right))
+	return r
+}
+
+// Case CASE WHEN，参数为条件（*Cond）与结果的对，如：orm.Case(orm.Q("score__gte", 60), "pass").Else("fail")
+func Case(pairs ...interface{}) *RawSQL {
+	if len(pairs) <= 0 || len(pairs)%2 != 0 {
+		panic("case needs cond and result pairs")
+	}
+
+	var sql strings.Builder
+	sql.Grow(len(pairs)*10 + 10)
+	sql.WriteString("CASE")
+	for i := 0; i < len(pairs); i += 2 {
+		if _, ok := pairs[i].(*Cond); !ok {
+			panic("case cond must be *Cond")
+		}
+		sql.WriteString(" WHEN ? THEN ?")
+	}
+	sql.WriteString(" END")
+
+	r := newExpr(sql.String(), pairs)
+	r.isCase = true
+	return r
+}
+
+// Else Case 表达式的 ELSE
+func (r *RawSQL) Else(v interface{}) *RawSQL {
+	if !r.isCase || strings.HasSuffix(r.sql, " ELSE ? END") {
+		panic("else can only be used once after case")
+	}
+
+	n := *r
+	n.sql = strings.TrimSuffix(r.sql, " END") + " ELSE ? END"
+	n.args = make([]interface{}, 0, len(r.args)+1)
+	n.args = append(append(n.args, r.args...), v)
+	return &n
+}
+
+// Desc 降序，用于 OrderExpr
+func (r *RawSQL) Desc() *RawSQL {
+	return newExpr("? desc", []interface{}{r})
+}
+
+// valuesOrder 按值列表排序，参数为字段与值，SQL 由数据库方言生成
+func valuesOrder(col string, values []interface{}) *RawSQL {
+	if col == "" || len(values) <= 0 {
+		panic("order by values needs column and values")
+	}
+
+	args := make([]interface{}, 0, len(values)+1)
+	args = append(append(args, Col(col)), values...)
+	r := newExpr("?", args)
+	r.valuesOrder = true
+	return r
+}
+
+// valuesOrderSQL 按值列表排序的SQL
+func (p *queryModel) valuesOrderSQL(r *RawSQL) string {
+	values := make([]string, 0, len(r.args)-1)
+	for _, arg := range r.args[1:] {
+		values = append(values, p.rawValue(arg))
+	}
+	return p.DBCore.Dialect.ValuesOrder(p.rawValue(r.args[0]), values)
+}
+
+func newExpr(sql string, args []interface{}) *RawSQL {
+	for i, arg := range args {
+		if v, ok := arg.(*ORM); ok {
+			args[i] = v.cond(false)
+		}
+	}
+	return &RawSQL{sql: sql, args: args}
+}
+
+// exprName 参数的默认别名：字段名（非字母数字替换为 _），表达式的默认别名，值为空
+func exprName(arg interface{}) string {
+	switch v := arg.(type) {
+	case Col:
+		if v == "" || v[0] == '#' {
+			return ""
+		}
+		// 与 Select 的默认别名一致：meta->a.b 为 meta_a_b
+		return strings.Map(func(c rune) rune {
+			if c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') {
+				return c
+			}
+			return '_'
+		}, strings.ReplaceAll(string(v), "->", "_"))
+	case *RawSQL:
+		if v.alias != "" {
+			return v.alias
+		}
+		return v.name
+	}
+	return ""
+}
+
+func firstExprName(args []interface{}) string {
+	for _, arg := range args {
+		if name := exprName(arg); name != "" {
+			return name
+		}
+	}
+	return ""
+}
+
+func joinExprName(a, b string) string {
+	if a == "" {
+		return b
+	}
+	if b == "" {
+		return a
+	}
+	return a + "_" + b
+}
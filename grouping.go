@@ -0,0 +1,130 @@
+package orm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 分组小计：GroupByRollup GroupByCube GroupingSets，排在 GroupBy GroupByExpr 之后，字段规则与 GroupBy 一致；
+// MySQL MariaDB 只支持 rollup（with rollup，不能与 GroupBy 一起使用），SQLite 返回 ErrDBFunc；
+// 小计行中被汇总的字段为 NULL，使用 orm.Grouping 区分
+
+// GroupingSet 分组小计，Kind 为：rollup、cube、grouping sets，rollup cube 只有一组字段
+type GroupingSet struct {
+	Kind string
+	Sets []GroupBy
+}
+
+type groupingModel struct {
+	kind string
+	sets [][]string
+}
+
+// GroupByRollup 按字段从右到左逐级小计，如：GroupByRollup("year", "month") 为 (year,month)、(year)、()
+func (orm *ORM) GroupByRollup(cols ...string) *ORM {
+	return orm.grouping("rollup", cols)
+}
+
+// GroupByCube 字段所有组合的小计
+func (orm *ORM) GroupByCube(cols ...string) *ORM {
+	return orm.grouping("cube", cols)
+}
+
+// GroupingSets 指定的多组分组，空的分组为总计，如：GroupingSets([]string{"year", "tb2.name"}, []string{"year"}, nil)
+func (orm *ORM) GroupingSets(sets ...[]string) *ORM {
+	if len(sets) <= 0 {
+		panic("grouping sets cannot be empty")
+	}
+
+	g := &GroupingSet{Kind: "grouping sets", Sets: make([]GroupBy, 0, len(sets))}
+	for _, set := range sets {
+		g.Sets = append(g.Sets, set)
+	}
+	orm.Q.Grouping = g
+	return orm
+}
+
+func (orm *ORM) grouping(kind string, cols []string) *ORM {
+	if len(cols) <= 0 {
+		panic(kind + " columns cannot be empty")
+	}
+	orm.Q.Grouping = &GroupingSet{Kind: kind, Sets: []GroupBy{cols}}
+	return orm
+}
+
+// Grouping GROUPING(字段)，字段在当前行被汇总（小计行）时为 1，否则为 0，用于 SelectExpr，默认别名为 字段_grouping，
+// 如：SelectExpr(orm.Grouping("tb2.name"))，结构体使用 json:"tb2_name_grouping" 的整数字段接收；
+// MySQL 需要 8.0 及以上，MariaDB SQLite 不支持，返回 ErrDBFunc
+func Grouping(col string) *RawSQL {
+	r := Func("GROUPING", Col(col))
+	r.grouping = true
+	return r
+}
+
+func (q *BaseQuery) groupingData() *groupingModel {
+	if q.Grouping == nil {
+		return nil
+	}
+
+	g := &groupingModel{kind: q.Grouping.Kind, sets: make([][]string, 0, len(q.Grouping.Sets))}
+	for _, set := range q.Grouping.Sets {
+		g.sets = append(g.sets, q.formatCols(set))
+	}
+	return g
+}
+
+// groupingSQL 普通分组与分组小计
+func (p *queryModel) groupingSQL(groupBy string) string {
+	sql, err := p.DBCore.Dialect.Grouping(groupBy, p.Grouping.kind, p.Grouping.sets)
+	if err != nil {
+		panic(err)
+	}
+	return sql
+}
+
+// groupingFuncSQL GROUPING(字段)
+func (p *queryModel) groupingFuncSQL(r *RawSQL) string {
+	sql, err := p.DBCore.Dialect.GroupingFunc(p.rawValue(r.args[0]))
+	if err != nil {
+		panic(err)
+	}
+	return sql
+}
+
+// groupingSetsSQL 普通分组,rollup(a,b)、cube(a,b)、grouping sets((a,b),(a),())
+func groupingSetsSQL(groupBy, kind string, sets [][]string) (string, error) {
+	var sql strings.Builder
+	sql.Grow(len(groupBy) + len(sets)*30 + 20)
+	if groupBy != "" {
+		sql.WriteString(groupBy)
+		sql.WriteByte(',')
+	}
+
+	switch kind {
+	case "rollup", "cube":
+		if len(sets) != 1 || len(sets[0]) <= 0 {
+			return "", fmt.Errorf("%s columns cannot be empty", kind)
+		}
+		sql.WriteString(kind)
+		sql.WriteByte('(')
+		sql.WriteString(strings.Join(sets[0], ","))
+		sql.WriteByte(')')
+	case "grouping sets":
+		if len(sets) <= 0 {
+			return "", fmt.Errorf("grouping sets cannot be empty")
+		}
+		sql.WriteString("grouping sets(")
+		for i, set := range sets {
+			if i > 0 {
+				sql.WriteByte(',')
+			}
+			sql.WriteByte('(')
+			sql.WriteString(strings.Join(set, ","))
+			sql.WriteByte(')')
+		}
+		sql.WriteByte(')')
+	default:
+		return "", fmt.Errorf("grouping[%s] is invalid", kind)
+	}
+	return sql.String(), nil
+}
@@ -4,89 +4,55 @@ package orm
 import "github.com/assembly-hub/orm/dbtype"
 
 type dbCoreData struct {
-	DBType    int
-	StrEsc    string
-	EscStart  string
-	EscEnd    string
-	Esc       string
-	BinStr    string
-	IgnoreStr string
+	DBType   int
+	EscStart string
+	EscEnd   string
+	Dialect  Dialect
 }
 
-var dbConfMap map[int]*dbCoreData
+func newDBCoreData(d Dialect) *dbCoreData {
+	escStart, escEnd := d.Quote()
+	return &dbCoreData{
+		DBType:   d.DBType(),
+		EscStart: escStart,
+		EscEnd:   escEnd,
+		Dialect:  d,
+	}
+}
 
 func init() {
-	dbConfMap = make(map[int]*dbCoreData)
-	dbConfMap[dbtype.MySQL] = &dbCoreData{
-		DBType:   dbtype.MySQL,
-		StrEsc:   "'",
-		EscStart: "`",
-		EscEnd:   "`",
-		Esc:      "\\",
-		BinStr:   "binary", // 数据库不区分大小写；放在数据之前，强制区分大小写
-	}
-	dbConfMap[dbtype.MariaDB] = &dbCoreData{
-		DBType:   dbtype.MariaDB,
-		StrEsc:   "'",
-		EscStart: "`",
-		EscEnd:   "`",
-		Esc:      "\\",
-		BinStr:   "binary", // 数据库不区分大小写；放在数据之前，强制区分大小写
-	}
-	dbConfMap[dbtype.SQLServer] = &dbCoreData{
-		DBType:   dbtype.SQLServer,
-		StrEsc:   "'",
-		EscStart: "[",
-		EscEnd:   "]",
-		Esc:      "%",
-		BinStr:   "COLLATE Chinese_PRC_CS_AS", // 数据库不区分大小写；放在操作符之前，强制区分大小写
-	}
-	dbConfMap[dbtype.Postgres] = &dbCoreData{
-		DBType:    dbtype.Postgres,
-		StrEsc:    "'",
-		EscStart:  "\"",
-		EscEnd:    "\"",
-		Esc:       "\\",
-		IgnoreStr: "", // 数据库本身区分大小写
-	}
-	dbConfMap[dbtype.OpenGauss] = &dbCoreData{
-		DBType:    dbtype.OpenGauss,
-		StrEsc:    "'",
-		EscStart:  "\"",
-		EscEnd:    "\"",
-		Esc:       "\\",
-		IgnoreStr: "", // 数据库本身区分大小写
-	}
-	dbConfMap[dbtype.SQLite2] = &dbCoreData{
-		DBType:    dbtype.SQLite2,
-		StrEsc:    "'",
-		EscStart:  "\"",
-		EscEnd:    "\"",
-		Esc:       "",
-		IgnoreStr: "COLLATE NOCASE", // 数据库本身区分大小写；放在操作符之前，忽略大小写
-	}
-	dbConfMap[dbtype.SQLite3] = &dbCoreData{
-		DBType:    dbtype.SQLite3,
-		StrEsc:    "'",
-		EscStart:  "\"",
-		EscEnd:    "\"",
-		Esc:       "",
-		IgnoreStr: "COLLATE NOCASE", // 数据库本身区分大小写，放在操作符之前，忽略大小写
-	}
-	dbConfMap[dbtype.Oracle] = &dbCoreData{
-		DBType:    dbtype.Oracle,
-		StrEsc:    "'",
-		EscStart:  "\"",
-		EscEnd:    "\"",
-		Esc:       "",
-		IgnoreStr: "", // 数据库本身区分大小写
-	}
-	dbConfMap[dbtype.ClickHouse] = &dbCoreData{
-		DBType:   dbtype.ClickHouse,
-		StrEsc:   "'",
-		EscStart: "`",
-		EscEnd:   "`",
-		Esc:      "\\",
-		BinStr:   "binary", // 数据库不区分大小写；放在数据之前，强制区分大小写
-	}
+	RegisterDialect(mysqlDialect{
+		BaseDialect: BaseDialect{Type: dbtype.MySQL, EscStart: "`", EscEnd: "`"},
+		binStr:      "binary", // 数据库不区分大小写；放在数据之前，强制区分大小写
+	})
+	RegisterDialect(mysqlDialect{
+		BaseDialect: BaseDialect{Type: dbtype.MariaDB, EscStart: "`", EscEnd: "`"},
+		binStr:      "binary", // 数据库不区分大小写；放在数据之前，强制区分大小写
+	})
+	RegisterDialect(sqlserverDialect{
+		BaseDialect: BaseDialect{Type: dbtype.SQLServer, EscStart: "[", EscEnd: "]"},
+		binStr:      "COLLATE Chinese_PRC_CS_AS", // 数据库不区分大小写；放在操作符之前，强制区分大小写
+	})
+	// 数据库本身区分大小写
+	RegisterDialect(postgresDialect{
+		BaseDialect: BaseDialect{Type: dbtype.Postgres, EscStart: "\"", EscEnd: "\""},
+	})
+	// 数据库本身区分大小写
+	RegisterDialect(gaussDialect{postgresDialect{
+		BaseDialect: BaseDialect{Type: dbtype.OpenGauss, EscStart: "\"", EscEnd: "\""},
+	}})
+	RegisterDialect(sqliteDialect{
+		BaseDialect: BaseDialect{Type: dbtype.SQLite2, EscStart: "\"", EscEnd: "\""},
+		ignoreStr:   "COLLATE NOCASE", // 数据库本身区分大小写；放在操作符之前，忽略大小写
+	})
+	RegisterDialect(sqliteDialect{
+		BaseDialect: BaseDialect{Type: dbtype.SQLite3, EscStart: "\"", EscEnd: "\""},
+		ignoreStr:   "COLLATE NOCASE", // 数据库本身区分大小写，放在操作符之前，忽略大小写
+	})
+	// 数据库本身区分大小写
+	RegisterDialect(oracleDialect{
+		BaseDialect: BaseDialect{Type: dbtype.Oracle, EscStart: "\"", EscEnd: "\""},
+	})
+	// 数据库本身区分大小写
+	RegisterDialect(NewClickHouseDialect(false))
 }
@@ -1,76 +1,12 @@
 package orm
 
 import (
-	"fmt"
-	"reflect"
 	"strings"
 
-	"github.com/assembly-hub/basics/util"
+	"github.com/assembly-hub/orm/dbtype"
 )
 
-func (p *queryModel) queryMySQL() string {
-	var sql strings.Builder
-	sql.Grow(100)
-	sql.WriteString("select ")
-
-	sel := p.selectSQL()
-	if p.MainTable == "" {
-		panic("MainTable is nil")
-	}
-
-	sql.WriteString(sel)
-	sql.WriteString(" from ")
-	sql.WriteString(p.MainTable)
-	if p.MainAlias != "" {
-		sql.WriteString(" as ")
-		sql.WriteString(p.MainAlias)
-	}
-
-	join := p.joinSQL()
-	if join != "" {
-		sql.WriteString(join)
-	}
-
-	where := p.andSQL(p.Where)
-	if where != "" {
-		sql.WriteString(" where ")
-		sql.WriteString(where)
-	}
-
-	if len(p.GroupBy) > 0 {
-		sql.WriteString(" group by ")
-		sql.WriteString(util.JoinArr(p.GroupBy, ","))
-	}
-
-	if len(p.GroupBy) > 0 && len(p.Having) > 0 {
-		sql.WriteString(" having ")
-		sql.WriteString(p.andSQL(p.Having))
-	}
-
-	order := p.orderSQL()
-	if order != "" {
-		sql.WriteString(" order by ")
-		sql.WriteString(order)
-	}
-
-	if len(p.Limit) == 1 {
-		sql.WriteString(" limit ")
-		sql.WriteString(util.UintToStr(p.Limit[0]))
-	} else if len(p.Limit) == 2 {
-		sql.WriteString(" limit ")
-		sql.WriteString(util.UintToStr(p.Limit[1]))
-		sql.WriteString(" offset ")
-		sql.WriteString(util.UintToStr(p.Limit[0]))
-	}
-
-	if p.SelectForUpdate {
-		sql.WriteString(" for update")
-	}
-
-	return sql.String()
-}
-
-func (p *queryModel) mysqlBinFormatSubSQL(colOperator string, colName string, val, rawVal string, rawStrArr []string,
+func mysqlBinFormatSubSQL(binStr string, colOperator string, colName string, val, rawVal string, rawStrArr []string,
 	colData interface{}) string {
 	var subSQL strings.Builder
 	subSQL.Grow(20)
@@ -78,53 +14,53 @@ func (p *queryModel) mysqlBinFormatSubSQL(colOperator string, colName string, va
 	case "eq":
 		subSQL.WriteString(colName)
 		subSQL.WriteByte('=')
-		subSQL.WriteString(p.DBCore.BinStr)
+		subSQL.WriteString(binStr)
 		subSQL.WriteString(val)
 	case "lt":
 		subSQL.WriteString(colName)
 		subSQL.WriteByte('<')
-		subSQL.WriteString(p.DBCore.BinStr)
+		subSQL.WriteString(binStr)
 		subSQL.WriteString(val)
 	case "lte":
 		subSQL.WriteString(colName)
 		subSQL.WriteString("<=")
-		subSQL.WriteString(p.DBCore.BinStr)
+		subSQL.WriteString(binStr)
 		subSQL.WriteString(val)
 	case "gt":
 		subSQL.WriteString(colName)
 		subSQL.WriteByte('>')
-		subSQL.WriteString(p.DBCore.BinStr)
+		subSQL.WriteString(binStr)
 		subSQL.WriteString(val)
 	case "gte":
 		subSQL.WriteString(colName)
 		subSQL.WriteString(">=")
-		subSQL.WriteString(p.DBCore.BinStr)
+		subSQL.WriteString(binStr)
 		subSQL.WriteString(val)
 	case "ne":
 		subSQL.WriteString(colName)
 		subSQL.WriteString("<>")
-		subSQL.WriteString(p.DBCore.BinStr)
+		subSQL.WriteString(binStr)
 		subSQL.WriteString(val)
 	case "in":
-		subSQL.WriteString(p.DBCore.BinStr)
+		subSQL.WriteString(binStr)
 		subSQL.WriteByte(' ')
 		subSQL.WriteString(colName)
 		subSQL.WriteString(" in ")
 		subSQL.WriteString(val)
 	case "nin":
-		subSQL.WriteString(p.DBCore.BinStr)
+		subSQL.WriteString(binStr)
 		subSQL.WriteByte(' ')
 		subSQL.WriteString(colName)
 		subSQL.WriteString(" not in ")
 		subSQL.WriteString(val)
 	default:
-		return p.mysqlBinLikeSQL(colOperator, colName, val, rawVal, rawStrArr)
+		return mysqlBinLikeSQL(binStr, colOperator, colName, val, rawVal, rawStrArr)
 	}
 
 	return subSQL.String()
 }
 
-func (p *queryModel) mysqlBinLikeSQL(colOperator string, colName string, val, rawVal string, rawStrArr []string) string {
+func mysqlBinLikeSQL(binStr string, colOperator string, colName string, val, rawVal string, rawStrArr []string) string {
 	var subSQL strings.Builder
 	subSQL.Grow(20)
 	switch colOperator {
@@ -132,7 +68,7 @@ func (p *queryModel) mysqlBinLikeSQL(colOperator string, colName string, val, ra
 		if rawVal != "" {
 			subSQL.WriteString(colName)
 			subSQL.WriteString(" like ")
-			subSQL.WriteString(p.DBCore.BinStr)
+			subSQL.WriteString(binStr)
 			subSQL.WriteString(" '")
 			subSQL.WriteString(rawVal)
 			subSQL.WriteString("%'")
@@ -141,7 +77,7 @@ func (p *queryModel) mysqlBinLikeSQL(colOperator string, colName string, val, ra
 		if rawVal != "" {
 			subSQL.WriteString(colName)
 			subSQL.WriteString(" like ")
-			subSQL.WriteString(p.DBCore.BinStr)
+			subSQL.WriteString(binStr)
 			subSQL.WriteString(" '%")
 			subSQL.WriteString(rawVal)
 			subSQL.WriteString("'")
@@ -150,7 +86,7 @@ func (p *queryModel) mysqlBinLikeSQL(colOperator string, colName string, val, ra
 		if rawVal != "" {
 			subSQL.WriteString(colName)
 			subSQL.WriteString(" like ")
-			subSQL.WriteString(p.DBCore.BinStr)
+			subSQL.WriteString(binStr)
 			subSQL.WriteString(" '%")
 			subSQL.WriteString(rawVal)
 			subSQL.WriteString("%'")
@@ -163,7 +99,7 @@ func (p *queryModel) mysqlBinLikeSQL(colOperator string, colName string, val, ra
 				}
 				subSQL.WriteString(colName)
 				subSQL.WriteString(" like ")
-				subSQL.WriteString(p.DBCore.BinStr)
+				subSQL.WriteString(binStr)
 				subSQL.WriteString(" '%")
 				subSQL.WriteString(v)
 				subSQL.WriteString("%'")
@@ -174,7 +110,7 @@ func (p *queryModel) mysqlBinLikeSQL(colOperator string, colName string, val, ra
 		if rawVal != "" {
 			subSQL.WriteString(colName)
 			subSQL.WriteString(" like ")
-			subSQL.WriteString(p.DBCore.BinStr)
+			subSQL.WriteString(binStr)
 			subSQL.WriteString(" '")
 			subSQL.WriteString(rawVal)
 			subSQL.WriteString("'")
@@ -187,7 +123,7 @@ func (p *queryModel) mysqlBinLikeSQL(colOperator string, colName string, val, ra
 				}
 				subSQL.WriteString(colName)
 				subSQL.WriteString(" like ")
-				subSQL.WriteString(p.DBCore.BinStr)
+				subSQL.WriteString(binStr)
 				subSQL.WriteString(" '")
 				subSQL.WriteString(v)
 				subSQL.WriteString("'")
@@ -195,12 +131,12 @@ func (p *queryModel) mysqlBinLikeSQL(colOperator string, colName string, val, ra
 			subSQL.WriteByte(')')
 		}
 	default:
-		return p.mysqlBinOrLikeSQL(colOperator, colName, val, rawVal, rawStrArr)
+		return mysqlBinOrLikeSQL(binStr, colOperator, colName, val, rawVal, rawStrArr)
 	}
 	return subSQL.String()
 }
 
-func (p *queryModel) mysqlBinOrLikeSQL(colOperator string, colName string, val, rawVal string, rawStrArr []string) string {
+func mysqlBinOrLikeSQL(binStr string, colOperator string, colName string, val, rawVal string, rawStrArr []string) string {
 	var subSQL strings.Builder
 	subSQL.Grow(20)
 	switch colOperator {
@@ -208,7 +144,7 @@ func (p *queryModel) mysqlBinOrLikeSQL(colOperator string, colName string, val,
 		if rawVal != "" {
 			subSQL.WriteString(colName)
 			subSQL.WriteString(" like ")
-			subSQL.WriteString(p.DBCore.BinStr)
+			subSQL.WriteString(binStr)
 			subSQL.WriteString(" '")
 			subSQL.WriteString(rawVal)
 			subSQL.WriteString("%'")
@@ -223,7 +159,7 @@ func (p *queryModel) mysqlBinOrLikeSQL(colOperator string, colName string, val,
 
 				subSQL.WriteString(colName)
 				subSQL.WriteString(" like ")
-				subSQL.WriteString(p.DBCore.BinStr)
+				subSQL.WriteString(binStr)
 				subSQL.WriteString(" '")
 				subSQL.WriteString(v)
 				subSQL.WriteString("%'")
@@ -234,7 +170,7 @@ func (p *queryModel) mysqlBinOrLikeSQL(colOperator string, colName string, val,
 		if rawVal != "" {
 			subSQL.WriteString(colName)
 			subSQL.WriteString(" like ")
-			subSQL.WriteString(p.DBCore.BinStr)
+			subSQL.WriteString(binStr)
 			subSQL.WriteString(" '%")
 			subSQL.WriteString(rawVal)
 			subSQL.WriteString("'")
@@ -249,7 +185,7 @@ func (p *queryModel) mysqlBinOrLikeSQL(colOperator string, colName string, val,
 
 				subSQL.WriteString(colName)
 				subSQL.WriteString(" like ")
-				subSQL.WriteString(p.DBCore.BinStr)
+				subSQL.WriteString(binStr)
 				subSQL.WriteString(" '%")
 				subSQL.WriteString(v)
 				subSQL.WriteString("'")
@@ -260,7 +196,7 @@ func (p *queryModel) mysqlBinOrLikeSQL(colOperator string, colName string, val,
 		if rawVal != "" {
 			subSQL.WriteString(colName)
 			subSQL.WriteString(" like ")
-			subSQL.WriteString(p.DBCore.BinStr)
+			subSQL.WriteString(binStr)
 			subSQL.WriteString(" '%")
 			subSQL.WriteString(rawVal)
 			subSQL.WriteString("%'")
@@ -275,7 +211,7 @@ func (p *queryModel) mysqlBinOrLikeSQL(colOperator string, colName string, val,
 
 				subSQL.WriteString(colName)
 				subSQL.WriteString(" like ")
-				subSQL.WriteString(p.DBCore.BinStr)
+				subSQL.WriteString(binStr)
 				subSQL.WriteString(" '%")
 				subSQL.WriteString(v)
 				subSQL.WriteString("%'")
@@ -286,7 +222,7 @@ func (p *queryModel) mysqlBinOrLikeSQL(colOperator string, colName string, val,
 		if rawVal != "" {
 			subSQL.WriteString(colName)
 			subSQL.WriteString(" like ")
-			subSQL.WriteString(p.DBCore.BinStr)
+			subSQL.WriteString(binStr)
 			subSQL.WriteString(" '")
 			subSQL.WriteString(rawVal)
 			subSQL.WriteString("'")
@@ -301,7 +237,7 @@ func (p *queryModel) mysqlBinOrLikeSQL(colOperator string, colName string, val,
 
 				subSQL.WriteString(colName)
 				subSQL.WriteString(" like ")
-				subSQL.WriteString(p.DBCore.BinStr)
+				subSQL.WriteString(binStr)
 				subSQL.WriteString(" '")
 				subSQL.WriteString(v)
 				subSQL.WriteString("'")
@@ -309,257 +245,214 @@ func (p *queryModel) mysqlBinOrLikeSQL(colOperator string, colName string, val,
 			subSQL.WriteByte(')')
 		}
 	default:
-		panic(fmt.Sprintf("col[%s] operation[%s] no definition", colName, colOperator))
+		panic("is not defined")
 	}
 	return subSQL.String()
 }
 
-// orm
-func (orm *ORM) mysqlUpsertSQL(data interface{}) (string, error) {
-	dbCore := orm.ref.getDBConf()
-	escLen := len(dbCore.EscStart) + len(dbCore.EscEnd)
+// mysqlDialect mysql mariadb
+type mysqlDialect struct {
+	BaseDialect
+	binStr string
+	// version 数据库版本，nil 为未指定
+	version []int
+}
 
-	var upsertSQL strings.Builder
-	upsertSQL.Grow(100)
-	upsertSQL.WriteString("insert into ")
-	upsertSQL.WriteString(dbCore.EscStart)
-	upsertSQL.WriteString(orm.tableName)
-	upsertSQL.WriteString(dbCore.EscEnd)
-	upsertSQL.WriteByte('(')
-
-	if data == nil {
-		return "", fmt.Errorf("insert data is nil")
+func (d mysqlDialect) WithVersion(version string) (Dialect, error) {
+	ver, err := parseVersion(version)
+	if err != nil {
+		return nil, err
 	}
+	d.version = ver
+	return d, nil
+}
 
-	typeErrStr := "type of upsert data is map[string]interface{} or *struct or struct"
-	var formatCols []string
-	var values []string
-
-	switch data := data.(type) {
-	case map[string]interface{}:
-		formatCols = make([]string, 0, len(data))
-		values = make([]string, 0, len(data))
-		for k, v := range data {
-			if k[0] == '#' {
-				k = k[1:]
-				err := globalVerifyObj.VerifyFieldName(k)
-				if err != nil {
-					return "", err
-				}
-				var formatKey strings.Builder
-				formatKey.Grow(escLen + len(k))
-				formatKey.WriteString(dbCore.EscStart)
-				formatKey.WriteString(k)
-				formatKey.WriteString(dbCore.EscEnd)
-
-				formatCols = append(formatCols, formatKey.String())
-				values = append(values, util.Any2String(v))
-				continue
-			}
-
-			err := globalVerifyObj.VerifyFieldName(k)
-			if err != nil {
-				return "", err
-			}
-
-			val, timeEmpty := orm.formatValue(v)
-			if k == orm.primaryKey && (val == "" || val == "0") {
-				continue
-			}
-			if timeEmpty {
-				continue
-			} else {
-				var formatKey strings.Builder
-				formatKey.Grow(escLen + len(k))
-				formatKey.WriteString(dbCore.EscStart)
-				formatKey.WriteString(k)
-				formatKey.WriteString(dbCore.EscEnd)
-
-				formatCols = append(formatCols, formatKey.String())
-				values = append(values, val)
-			}
-		}
-	default:
-		dataValue := reflect.ValueOf(data)
-		if dataValue.Type().Kind() != reflect.Struct && dataValue.Type().Kind() != reflect.Ptr {
-			return "", fmt.Errorf(typeErrStr)
-		}
-
-		if dataValue.Type().Kind() == reflect.Ptr {
-			dataValue = dataValue.Elem()
-		}
+func (d mysqlDialect) BinCondition(c *Condition) (string, bool) {
+	return mysqlBinFormatSubSQL(d.binStr, c.Operator, c.Column, c.Value, c.Raw, c.RawList, c.Data), true
+}
 
-		if dataValue.Type().Kind() != reflect.Struct {
-			return "", fmt.Errorf(typeErrStr)
-		}
+// Regex 默认与 ignore 由字段排序规则决定（通常不区分大小写），bin 使用 binary 强制区分大小写
+func (d mysqlDialect) Regex(column, pattern string, flag RegexFlag) (string, error) {
+	var subSQL strings.Builder
+	subSQL.Grow(len(column) + len(pattern) + 20)
+	subSQL.WriteString(column)
+	if flag.Has(RegexNot) {
+		subSQL.WriteString(" not")
+	}
+	subSQL.WriteString(" regexp ")
+	if flag.Has(RegexCaseSensitive) {
+		subSQL.WriteString(d.binStr)
+		subSQL.WriteByte(' ')
+	}
+	subSQL.WriteString(pattern)
+	return subSQL.String(), nil
+}
 
-		for i := 0; i < dataValue.NumField(); i++ {
-			colName := dataValue.Type().Field(i).Tag.Get("json")
-			ref := dataValue.Type().Field(i).Tag.Get("ref")
-			if ref != "" || colName == "" || !dataValue.Type().Field(i).IsExported() {
-				continue
-			}
+// Search 需要 FULLTEXT 索引，中文使用 ngram 解析器：FULLTEXT KEY ft_name (name) WITH PARSER ngram
+func (d mysqlDialect) Search(column, query string) (string, error) {
+	return "MATCH(" + column + ") AGAINST(" + query + " IN NATURAL LANGUAGE MODE)", nil
+}
 
-			err := globalVerifyObj.VerifyFieldName(colName)
-			if err != nil {
-				return "", err
-			}
+func (d mysqlDialect) SearchRank(column, query string) (string, error) {
+	return d.Search(column, query)
+}
 
-			val, timeEmpty := orm.formatValue(dataValue.Field(i).Interface())
-			if colName == orm.primaryKey && (val == "" || val == "0") {
-				continue
-			}
-			if timeEmpty {
-				continue
-			} else {
-				var formatKey strings.Builder
-				formatKey.Grow(escLen + len(colName))
-				formatKey.WriteString(dbCore.EscStart)
-				formatKey.WriteString(colName)
-				formatKey.WriteString(dbCore.EscEnd)
-
-				formatCols = append(formatCols, formatKey.String())
-				values = append(values, val)
-			}
-		}
+func (d mysqlDialect) DatePart(column, part string) (string, error) {
+	switch part {
+	case "year":
+		return "YEAR(" + column + ")", nil
+	case "quarter":
+		return "QUARTER(" + column + ")", nil
+	case "month":
+		return "MONTH(" + column + ")", nil
+	case "week":
+		return "WEEK(" + column + ",3)", nil
+	case "weekday":
+		return "(WEEKDAY(" + column + ")+1)", nil
+	case "day":
+		return "DAY(" + column + ")", nil
+	case "hour":
+		return "HOUR(" + column + ")", nil
 	}
-	if len(formatCols) <= 0 || len(values) <= 0 {
-		return "", fmt.Errorf("sql data is empty, please check it")
+	return "", ErrDBFunc
+}
+
+func (d mysqlDialect) DateTrunc(column, unit string) (string, error) {
+	switch unit {
+	case "year":
+		return "DATE_FORMAT(" + column + ",'%Y-01-01')", nil
+	case "quarter":
+		return "(MAKEDATE(YEAR(" + column + "),1)+INTERVAL QUARTER(" + column + ")-1 QUARTER)", nil
+	case "month":
+		return "DATE_FORMAT(" + column + ",'%Y-%m-01')", nil
+	case "week":
+		return "DATE_SUB(DATE(" + column + "),INTERVAL WEEKDAY(" + column + ") DAY)", nil
+	case "day":
+		return "DATE(" + column + ")", nil
+	case "hour":
+		return "DATE_FORMAT(" + column + ",'%Y-%m-%d %H:00:00')", nil
 	}
+	return "", ErrDBFunc
+}
 
-	upsertSQL.WriteString(util.JoinArr(formatCols, ","))
-	upsertSQL.WriteString(") values(")
-	upsertSQL.WriteString(util.JoinArr(values, ","))
-	upsertSQL.WriteString(") on duplicate key update ")
+// With MySQL 8.0、MariaDB 10.2 及以上支持
+func (d mysqlDialect) With(list []*WithParts) (string, error) {
+	if (d.Type == dbtype.MariaDB && versionBelow(d.version, 10, 2)) || (d.Type == dbtype.MySQL && versionBelow(d.version, 8)) {
+		return "", ErrDBFunc
+	}
+	return d.BaseDialect.With(list)
+}
 
-	for i := 0; i < len(formatCols); i++ {
-		if i > 0 {
-			upsertSQL.WriteByte(',')
+// SetOperation intersect except 需要 MySQL 8.0.31、MariaDB 10.3 及以上
+func (d mysqlDialect) SetOperation(op string) (string, error) {
+	if op == "intersect" || op == "except" {
+		if (d.Type == dbtype.MariaDB && versionBelow(d.version, 10, 3)) || (d.Type == dbtype.MySQL && versionBelow(d.version, 8, 0, 31)) {
+			return "", ErrDBFunc
 		}
-		upsertSQL.WriteString(formatCols[i])
-		upsertSQL.WriteString("=values(")
-		upsertSQL.WriteString(formatCols[i])
-		upsertSQL.WriteByte(')')
 	}
+	return op, nil
+}
 
-	return upsertSQL.String(), nil
+// Grouping 只支持 with rollup，不能与普通分组一起使用（with rollup 作用于所有分组字段）
+func (d mysqlDialect) Grouping(groupBy, kind string, sets [][]string) (string, error) {
+	if kind != "rollup" || groupBy != "" || len(sets) != 1 || len(sets[0]) <= 0 {
+		return "", ErrDBFunc
+	}
+	return strings.Join(sets[0], ",") + " with rollup", nil
 }
 
-func (orm *ORM) mysqlUpsertManySQL(dataList []interface{}, cols []string) (string, error) {
-	if len(dataList) <= 0 {
-		return "", fmt.Errorf("insert data is nil")
+// GroupingFunc MySQL 8.0 及以上支持，MariaDB 不支持
+func (d mysqlDialect) GroupingFunc(column string) (string, error) {
+	if d.Type == dbtype.MariaDB || versionBelow(d.version, 8) {
+		return "", ErrDBFunc
 	}
+	return d.BaseDialect.GroupingFunc(column)
+}
 
-	dbCore := orm.ref.getDBConf()
+// Window MySQL 8.0、MariaDB 10.2 及以上支持
+func (d mysqlDialect) Window(fn, over string) (string, error) {
+	if (d.Type == dbtype.MariaDB && versionBelow(d.version, 10, 2)) || (d.Type == dbtype.MySQL && versionBelow(d.version, 8)) {
+		return "", ErrDBFunc
+	}
+	return d.BaseDialect.Window(fn, over)
+}
 
-	escLen := len(dbCore.EscStart) + len(dbCore.EscEnd)
+// NullsOrder 不支持 nulls first/last，使用 case when 排序
+func (d mysqlDialect) NullsOrder(column string, desc, nullsFirst bool) string {
+	return nullsCaseOrder(column, desc, nullsFirst)
+}
 
-	var upsertSQL strings.Builder
-	upsertSQL.Grow(len(dataList)*len(cols)*5 + 100)
-
-	upsertSQL.WriteString("insert into ")
-	upsertSQL.WriteString(dbCore.EscStart)
-	upsertSQL.WriteString(orm.tableName)
-	upsertSQL.WriteString(dbCore.EscEnd)
-
-	typeErrStr := "type of upsert data is []map[string]interface{} or []*struct or []struct"
-
-	valArr := make([]string, 0, len(dataList))
-	for _, data := range dataList {
-		var valMap map[string]interface{}
-		switch data := data.(type) {
-		case map[string]interface{}:
-			valMap = data
-		default:
-			dataValue := reflect.ValueOf(data)
-			if dataValue.Type().Kind() != reflect.Struct && dataValue.Type().Kind() != reflect.Ptr {
-				return "", fmt.Errorf(typeErrStr)
-			}
+// ValuesOrder field(字段,值n,...,值1) desc，不在列表中的值为 0，排在最后
+func (d mysqlDialect) ValuesOrder(column string, values []string) string {
+	var sql strings.Builder
+	sql.Grow(len(column) + len(values)*20 + 20)
+	sql.WriteString("field(")
+	sql.WriteString(column)
+	for i := len(values) - 1; i >= 0; i-- {
+		sql.WriteByte(',')
+		sql.WriteString(values[i])
+	}
+	sql.WriteString(") desc")
+	return sql.String()
+}
 
-			if dataValue.Type().Kind() == reflect.Ptr {
-				dataValue = dataValue.Elem()
-			}
+// RowCompare MySQL 与 MariaDB 均支持行值比较
+func (d mysqlDialect) RowCompare(left, right []string, op string) (string, error) {
+	return rowCompareSQL(left, right, op), nil
+}
 
-			if dataValue.Type().Kind() != reflect.Struct {
-				return "", fmt.Errorf(typeErrStr)
-			}
+// JSONPath JSON_UNQUOTE(JSON_EXTRACT(字段,'$.a.b'))，MySQL 5.7 与 MariaDB 均支持
+func (d mysqlDialect) JSONPath(column string, path []string) string {
+	return "JSON_UNQUOTE(JSON_EXTRACT(" + column + ",'" + jsonPathLiteral(path) + "'))"
+}
 
-			valMap = map[string]interface{}{}
+func (d mysqlDialect) JSONContains(column, value string) (string, error) {
+	return "JSON_CONTAINS(" + column + "," + value + ")", nil
+}
 
-			for i := 0; i < dataValue.NumField(); i++ {
-				colName := dataValue.Type().Field(i).Tag.Get("json")
-				ref := dataValue.Type().Field(i).Tag.Get("ref")
-				if ref != "" || colName == "" || !dataValue.Type().Field(i).IsExported() {
-					continue
-				}
+func (d mysqlDialect) JSONHasKey(column, key string) (string, error) {
+	return "JSON_CONTAINS_PATH(" + column + ",'one','" + jsonPathLiteral([]string{key}) + "')", nil
+}
 
-				valMap[colName] = dataValue.Field(i).Interface()
-			}
-		}
-		if len(valMap) <= 0 {
-			return "", fmt.Errorf("sql data is empty, please check it")
-		}
+func (d mysqlDialect) Replace(w *WriteParts) (string, error) {
+	return insertSQL("replace", d.EscStart, d.EscEnd, w), nil
+}
 
-		var subVal strings.Builder
-		subVal.Grow(len(cols) * 10)
-		subVal.WriteByte('(')
-		for i := range cols {
+func (d mysqlDialect) Upsert(w *WriteParts) (string, error) {
+	var upsertSQL strings.Builder
+	upsertSQL.Grow(len(w.Rows)*len(w.Cols)*5 + len(w.Cols)*20 + 100)
+	upsertSQL.WriteString(insertSQL("insert", d.EscStart, d.EscEnd, w))
+
+	// MySQL 8.0.19 起支持行别名，8.0.20 起 values() 已废弃
+	if d.Type == dbtype.MySQL && d.version != nil && !versionBelow(d.version, 8, 0, 19) {
+		alias := quoteName(d.EscStart, d.EscEnd, defaultAliasPrefix+"new")
+		upsertSQL.WriteString(" as ")
+		upsertSQL.WriteString(alias)
+		upsertSQL.WriteString(" on duplicate key update ")
+		for i, k := range w.Cols {
 			if i > 0 {
-				subVal.WriteByte(',')
+				upsertSQL.WriteByte(',')
 			}
-			if v, ok := valMap[cols[i]]; ok {
-				val, timeEmpty := orm.formatValue(v)
-				if (cols[i] == orm.primaryKey && (val == "" || val == "0")) || timeEmpty {
-					subVal.WriteString("null")
-					continue
-				}
-
-				subVal.WriteString(val)
-			} else if v, ok = valMap["#"+cols[i]]; ok {
-				subVal.WriteString(util.Any2String(v))
-			} else {
-				subVal.WriteString("null")
-			}
-		}
-		subVal.WriteByte(')')
-		valArr = append(valArr, subVal.String())
-	}
-
-	if len(valArr) <= 0 {
-		return "", fmt.Errorf("insert data is empty")
-	}
-
-	formatCols := make([]string, 0, len(cols))
-	for _, k := range cols {
-		err := globalVerifyObj.VerifyFieldName(k)
-		if err != nil {
-			return "", err
+			col := quoteName(d.EscStart, d.EscEnd, k)
+			upsertSQL.WriteString(col)
+			upsertSQL.WriteByte('=')
+			upsertSQL.WriteString(alias)
+			upsertSQL.WriteByte('.')
+			upsertSQL.WriteString(col)
 		}
-
-		var buf strings.Builder
-		buf.Grow(escLen + len(k))
-		buf.WriteString(dbCore.EscStart)
-		buf.WriteString(k)
-		buf.WriteString(dbCore.EscEnd)
-
-		formatCols = append(formatCols, buf.String())
+		return upsertSQL.String(), nil
 	}
-	upsertSQL.WriteByte('(')
-	upsertSQL.WriteString(util.JoinArr(formatCols, ","))
-	upsertSQL.WriteString(") values")
-	upsertSQL.WriteString(util.JoinArr(valArr, ","))
 
 	upsertSQL.WriteString(" on duplicate key update ")
-
-	for i := 0; i < len(formatCols); i++ {
+	for i, k := range w.Cols {
 		if i > 0 {
 			upsertSQL.WriteByte(',')
 		}
-		upsertSQL.WriteString(formatCols[i])
+		col := quoteName(d.EscStart, d.EscEnd, k)
+		upsertSQL.WriteString(col)
 		upsertSQL.WriteString("=values(")
-		upsertSQL.WriteString(formatCols[i])
+		upsertSQL.WriteString(col)
 		upsertSQL.WriteByte(')')
 	}
-
 	return upsertSQL.String(), nil
 }
@@ -0,0 +1,92 @@
+package orm
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// OperatorFunc 自定义操作符，d 为当前数据库方言，c 为条件数据（Column 为已转义的字段，Value 为格式化后的值），
+// not 为条件是否取反（~），返回的SQL片段需要自行处理取反
+type OperatorFunc func(d Dialect, c *Condition, not bool) (string, error)
+
+var (
+	operatorMap  = map[string]OperatorFunc{}
+	operatorLock sync.RWMutex
+)
+
+// builtinOperators 内置操作符，不能注册
+var builtinOperators = map[string]struct{}{
+	"eq": {}, "ne": {}, "lt": {}, "lte": {}, "gt": {}, "gte": {}, "in": {}, "nin": {}, "between": {},
+	"date": {}, "null": {}, "regex": {}, "iregex": {}, "nregex": {}, "search": {}, "json_contains": {}, "has_key": {},
+	"startswith": {}, "endswith": {}, "contains": {}, "customlike": {},
+	"orstartswith": {}, "orendswith": {}, "orcontains": {}, "orcustomlike": {},
+}
+
+// RegisterOperator 注册自定义操作符，可用于 Where Having $or $and，支持 bin（b_） ignore（i_）前缀，已存在时替换，
+// name 由字母、数字与下划线组成，不能与内置操作符或前缀重名，如：orm.Where{"tags__overlap": []string{"a"}}
+func RegisterOperator(name string, fn OperatorFunc) {
+	if fn == nil {
+		panic("operator func is nil")
+	}
+	if !isIdentName(name) || strings.Contains(name, "__") {
+		panic(fmt.Sprintf("operator[%s] name is invalid", name))
+	}
+	if isBuiltinOperator(name) {
+		panic(fmt.Sprintf("operator[%s] is builtin", name))
+	}
+
+	operatorLock.Lock()
+	defer operatorLock.Unlock()
+	operatorMap[name] = fn
+}
+
+// isBuiltinOperator 内置操作符、日期部分操作符与带 bin ignore 前缀的操作符
+func isBuiltinOperator(name string) bool {
+	if _, ok := builtinOperators[name]; ok {
+		return true
+	}
+	if _, _, ok := datePartOperator(name); ok {
+		return true
+	}
+
+	prefix := name
+	if i := strings.IndexByte(name, '_'); i > 0 {
+		prefix = name[:i]
+	}
+	switch prefix {
+	case "bin", "b", "ignore", "i":
+		return true
+	}
+	return false
+}
+
+// lookupOperator 查找自定义操作符，返回操作符名称与 bin ignore 前缀，不存在时 fn 为 nil
+func lookupOperator(colOperator string) (name string, fn OperatorFunc, bin, ignore bool) {
+	operatorLock.RLock()
+	defer operatorLock.RUnlock()
+	if len(operatorMap) <= 0 {
+		return
+	}
+
+	name = colOperator
+	if i := strings.IndexByte(colOperator, '_'); i > 0 {
+		switch colOperator[:i] {
+		case "bin", "b":
+			name, bin = colOperator[i+1:], true
+		case "ignore", "i":
+			name, ignore = colOperator[i+1:], true
+		}
+	}
+	fn = operatorMap[name]
+	return
+}
+
+// customSubSQL 自定义操作符的条件
+func (p *queryModel) customSubSQL(fn OperatorFunc, c *Condition, colOperator string, not bool) string {
+	subSQL, err := fn(p.DBCore.Dialect, c, not)
+	if err != nil {
+		panic(fmt.Errorf("field[%s]'s operator[%s] %w", c.Column, colOperator, err))
+	}
+	return subSQL
+}
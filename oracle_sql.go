@@ -2,104 +2,15 @@ package orm
 
 import (
 	"fmt"
-	"reflect"
+	"strconv"
 	"strings"
 
-	"github.com/assembly-hub/basics/set"
 	"github.com/assembly-hub/basics/util"
 )
 
 const oracleTimeFormat = "yyyy-mm-dd hh24:mi:ss"
-const oracleDateFormat = "yyyy-mm-dd"
 
-func oracleDateTime(s string, isDate bool) string {
-	var strBuf strings.Builder
-	strBuf.Grow(50)
-	strBuf.WriteString("TO_DATE('")
-	strBuf.WriteString(s)
-	strBuf.WriteString("','")
-	if isDate {
-		strBuf.WriteString(oracleDateFormat)
-	} else {
-		strBuf.WriteString(oracleTimeFormat)
-	}
-	strBuf.WriteString("')")
-	return strBuf.String()
-}
-
-// queryModel
-func (p *queryModel) queryOracle() string {
-	var sql strings.Builder
-	sql.Grow(100)
-	sql.WriteString("select ")
-
-	sel := p.selectSQL()
-	if p.MainTable == "" {
-		panic("MainTable is nil")
-	}
-	sql.WriteString(sel)
-	sql.WriteString(" from ")
-	sql.WriteString(p.MainTable)
-	if p.MainAlias != "" {
-		sql.WriteString(" ")
-		sql.WriteString(p.MainAlias)
-	}
-
-	join := p.joinSQL()
-	if join != "" {
-		sql.WriteString(join)
-	}
-
-	limitSQL := ""
-	if len(p.Limit) == 1 {
-		limitSQL += "rownum<=" + util.UintToStr(p.Limit[0])
-	}
-
-	where := p.andSQL(p.Where)
-	if where != "" {
-		if limitSQL != "" {
-			limitSQL = " and " + limitSQL
-		}
-		sql.WriteString(" where ")
-		sql.WriteString(where)
-		sql.WriteString(limitSQL)
-	} else if limitSQL != "" {
-		sql.WriteString(" where ")
-		sql.WriteString(limitSQL)
-	}
-
-	if len(p.GroupBy) > 0 {
-		sql.WriteString(" group by ")
-		sql.WriteString(util.JoinArr(p.GroupBy, ","))
-	}
-
-	if len(p.GroupBy) > 0 && len(p.Having) > 0 {
-		sql.WriteString(" having ")
-		sql.WriteString(p.andSQL(p.Having))
-	}
-
-	order := p.orderSQL()
-	if order != "" {
-		sql.WriteString(" order by ")
-		sql.WriteString(order)
-	}
-
-	if len(p.Limit) == 2 {
-		sql.WriteString(" OFFSET ")
-		sql.WriteString(util.UintToStr(p.Limit[0]))
-		sql.WriteString(" ROWS FETCH NEXT ")
-		sql.WriteString(util.UintToStr(p.Limit[1]))
-		sql.WriteString(" ROWS ONLY")
-	}
-
-	if p.SelectForUpdate {
-		sql.WriteString(" for update")
-	}
-
-	return sql.String()
-}
-
-func (p *queryModel) oracleIgnoreFormatSubSQL(colOperator string, colName string, val, rawVal string, rawStrArr []string,
+func oracleIgnoreFormatSubSQL(colOperator string, colName string, val, rawVal string, rawStrArr []string,
 	colData interface{}) string {
 	var subSQL strings.Builder
 	subSQL.Grow(20)
@@ -127,14 +38,14 @@ func (p *queryModel) oracleIgnoreFormatSubSQL(colOperator string, colName string
 		val = connectStrArr(rawStrArr, ",", "LOWER('", "')")
 		subSQL.WriteString(" not in (")
 	default:
-		return p.oracleIgnoreLikeSQL(colOperator, colName, val, rawVal, rawStrArr)
+		return oracleIgnoreLikeSQL(colOperator, colName, val, rawVal, rawStrArr)
 	}
 	subSQL.WriteString(val)
 	subSQL.WriteByte(')')
 	return subSQL.String()
 }
 
-func (p *queryModel) oracleIgnoreLikeSQL(colOperator string, colName string, val, rawVal string, rawStrArr []string) string {
+func oracleIgnoreLikeSQL(colOperator string, colName string, val, rawVal string, rawStrArr []string) string {
 	var subSQL strings.Builder
 	subSQL.Grow(20)
 	switch colOperator {
@@ -199,12 +110,12 @@ func (p *queryModel) oracleIgnoreLikeSQL(colOperator string, colName string, val
 			subSQL.WriteByte(')')
 		}
 	default:
-		return p.oracleIgnoreOrLikeSQL(colOperator, colName, val, rawVal, rawStrArr)
+		return oracleIgnoreOrLikeSQL(colOperator, colName, val, rawVal, rawStrArr)
 	}
 	return subSQL.String()
 }
 
-func (p *queryModel) oracleIgnoreOrLikeSQL(colOperator string, colName string, val, rawVal string, rawStrArr []string) string {
+func oracleIgnoreOrLikeSQL(colOperator string, colName string, val, rawVal string, rawStrArr []string) string {
 	var subSQL strings.Builder
 	subSQL.Grow(20)
 	switch colOperator {
@@ -297,566 +208,241 @@ func (p *queryModel) oracleIgnoreOrLikeSQL(colOperator string, colName string, v
 			subSQL.WriteByte(')')
 		}
 	default:
-		panic("no definition")
+		panic("is not defined")
 	}
 	return subSQL.String()
 }
 
-// orm
-func (orm *ORM) oracleInsertManySQL(dataList []interface{}, cols []string) (string, error) {
-	if len(dataList) <= 0 {
-		return "", fmt.Errorf("insert data is nil")
-	}
-
-	dbCore := orm.ref.getDBConf()
-	var insertSQL strings.Builder
-	insertSQL.Grow(len(dataList)*len(cols)*25 + 200)
-
-	insertSQL.WriteString("insert all")
+// oracleDialect oracle，:n 占位符，12c 以下使用 rownum 与 ROW_NUMBER() 分页，upsert 使用 merge into
+type oracleDialect struct {
+	BaseDialect
+	// version 数据库版本，如：11g 为 [11]，nil 为未指定
+	version []int
+}
 
-	var tableBuff strings.Builder
-	tableBuff.WriteString(dbCore.EscStart)
-	tableBuff.WriteString(orm.tableName)
-	tableBuff.WriteString(dbCore.EscEnd)
-	tableBuff.WriteByte('(')
-	for i := range cols {
-		err := globalVerifyObj.VerifyFieldName(cols[i])
-		if err != nil {
-			return "", err
-		}
-		if i > 0 {
-			tableBuff.WriteByte(',')
-		}
-		tableBuff.WriteString(dbCore.EscStart)
-		tableBuff.WriteString(cols[i])
-		tableBuff.WriteString(dbCore.EscEnd)
+func (d oracleDialect) WithVersion(version string) (Dialect, error) {
+	ver, err := parseVersion(version)
+	if err != nil {
+		return nil, err
 	}
-	tableBuff.WriteByte(')')
-	tableAndField := tableBuff.String()
-
-	typeErrStr := "type of insert data is []map[string]interface{} or []*struct or []struct"
-
-	valArr := make([]string, 0, len(dataList))
-	for _, data := range dataList {
-		var valMap map[string]interface{}
-		switch data := data.(type) {
-		case map[string]interface{}:
-			valMap = data
-		default:
-			dataValue := reflect.ValueOf(data)
-			if dataValue.Type().Kind() != reflect.Struct && dataValue.Type().Kind() != reflect.Ptr {
-				return "", fmt.Errorf(typeErrStr)
-			}
-
-			if dataValue.Type().Kind() == reflect.Ptr {
-				dataValue = dataValue.Elem()
-			}
-
-			if dataValue.Type().Kind() != reflect.Struct {
-				return "", fmt.Errorf(typeErrStr)
-			}
-
-			valMap = map[string]interface{}{}
+	d.version = ver
+	return d, nil
+}
 
-			for i := 0; i < dataValue.NumField(); i++ {
-				colName := dataValue.Type().Field(i).Tag.Get("json")
-				ref := dataValue.Type().Field(i).Tag.Get("ref")
-				if ref != "" || colName == "" || !dataValue.Type().Field(i).IsExported() {
-					continue
-				}
+func (d oracleDialect) Placeholder(n int) string {
+	return ":" + strconv.Itoa(n)
+}
 
-				valMap[colName] = dataValue.Field(i).Interface()
-			}
-		}
-		if len(valMap) <= 0 {
-			return "", fmt.Errorf("sql data is empty, please check it")
-		}
+func (d oracleDialect) TimeLiteral(val string) string {
+	var strBuf strings.Builder
+	strBuf.Grow(len(val) + 40)
+	strBuf.WriteString("TO_DATE(")
+	strBuf.WriteString(val)
+	strBuf.WriteString(",'")
+	strBuf.WriteString(oracleTimeFormat)
+	strBuf.WriteString("')")
+	return strBuf.String()
+}
 
-		var subVal strings.Builder
-		for i := range cols {
-			if i > 0 {
-				subVal.WriteByte(',')
-			}
-			if v, ok := valMap[cols[i]]; ok {
-				val, timeEmpty := orm.formatValue(v)
-				if (cols[i] == orm.primaryKey && (val == "" || val == "0")) || timeEmpty {
-					subVal.WriteString("null")
-					continue
-				}
+func (d oracleDialect) TableAlias(table, alias string) string {
+	return table + " " + alias
+}
 
-				subVal.WriteString(val)
-			} else if v, ok = valMap["#"+cols[i]]; ok {
-				subVal.WriteString(util.Any2String(v))
-			} else {
-				subVal.WriteString("null")
-			}
+// With 递归不需要 recursive 关键字，但必须指定字段列表（ref 中同名的表定义）
+func (d oracleDialect) With(list []*WithParts) (string, error) {
+	for _, w := range list {
+		if w.Recursive && len(w.Columns) <= 0 {
+			return "", fmt.Errorf("recursive with[%s] requires columns, please define the table by ref.AddTableDef", w.Name)
 		}
-		valArr = append(valArr, subVal.String())
-	}
-
-	if len(valArr) <= 0 {
-		return "", fmt.Errorf("insert data is empty")
-	}
-
-	for i := 0; i < len(valArr); i++ {
-		insertSQL.WriteString(" into ")
-		insertSQL.WriteString(tableAndField)
-		insertSQL.WriteString(" VALUES(")
-		insertSQL.WriteString(valArr[i])
-		insertSQL.WriteByte(')')
 	}
-
-	insertSQL.WriteString(" select * from \"DUAL\"")
-	return insertSQL.String(), nil
+	return withSQL(list, "", true), nil
 }
 
-func (orm *ORM) oracleUpsertSQL(data interface{}) (string, error) {
-	if data == nil {
-		return "", fmt.Errorf("upsert data is nil")
+// SetOperation except 为 minus
+func (d oracleDialect) SetOperation(op string) (string, error) {
+	if op == "except" {
+		return "minus", nil
 	}
+	return op, nil
+}
 
-	dbCore := orm.ref.getDBConf()
-	escLen := len(dbCore.EscStart) + len(dbCore.EscEnd)
-
-	typeErrStr := "type of upsert data is map[string]interface{} or *struct or struct"
-	var formatCols []string
-	rawColSet := set.New[string]()
-	var rawVal []string
-
-	switch data := data.(type) {
-	case map[string]interface{}:
-		for k, v := range data {
-			if k[0] == '#' {
-				k = k[1:]
-				err := globalVerifyObj.VerifyFieldName(k)
-				if err != nil {
-					return "", err
-				}
-				var formatKey strings.Builder
-				formatKey.Grow(escLen + len(k))
-				formatKey.WriteString(dbCore.EscStart)
-				formatKey.WriteString(k)
-				formatKey.WriteString(dbCore.EscEnd)
-
-				formatCols = append(formatCols, formatKey.String())
-				rawColSet.Add(k)
-
-				val := util.Any2String(v)
-				rawVal = append(rawVal, val)
-				continue
-			}
-
-			err := globalVerifyObj.VerifyFieldName(k)
-			if err != nil {
-				return "", err
-			}
-
-			val, timeEmpty := orm.formatValue(v)
-			if k == orm.primaryKey && (val == "" || val == "0") {
-				continue
-			}
-			if timeEmpty {
-				continue
-			}
-			var formatKey strings.Builder
-			formatKey.Grow(escLen + len(k))
-			formatKey.WriteString(dbCore.EscStart)
-			formatKey.WriteString(k)
-			formatKey.WriteString(dbCore.EscEnd)
-
-			formatCols = append(formatCols, formatKey.String())
-			rawColSet.Add(k)
-			rawVal = append(rawVal, val)
-		}
-	default:
-		dataValue := reflect.ValueOf(data)
-		if dataValue.Type().Kind() != reflect.Struct && dataValue.Type().Kind() != reflect.Ptr {
-			return "", fmt.Errorf(typeErrStr)
-		}
-
-		if dataValue.Type().Kind() == reflect.Ptr {
-			dataValue = dataValue.Elem()
-		}
-
-		if dataValue.Type().Kind() != reflect.Struct {
-			return "", fmt.Errorf(typeErrStr)
-		}
-
-		for i := 0; i < dataValue.NumField(); i++ {
-			colName := dataValue.Type().Field(i).Tag.Get("json")
-			ref := dataValue.Type().Field(i).Tag.Get("ref")
-			if ref != "" || colName == "" || !dataValue.Type().Field(i).IsExported() {
-				continue
-			}
-
-			val, timeEmpty := orm.formatValue(dataValue.Field(i).Interface())
-			if colName == orm.primaryKey && (val == "" || val == "0") {
-				continue
-			}
-			if timeEmpty {
-				continue
-			}
-
-			var formatKey strings.Builder
-			formatKey.Grow(escLen + len(colName))
-			formatKey.WriteString(dbCore.EscStart)
-			formatKey.WriteString(colName)
-			formatKey.WriteString(dbCore.EscEnd)
-
-			formatCols = append(formatCols, formatKey.String())
-			rawColSet.Add(colName)
-			rawVal = append(rawVal, val)
+func (d oracleDialect) Select(q *SelectParts) string {
+	// offset fetch 需要 12c 及以上版本；ROW_NUMBER() 分页无法 for update
+	if len(q.Limit) == 2 && versionBelow(d.version, 12) {
+		if q.ForUpdate {
+			panic(ErrDBFunc)
 		}
+		return rowNumberSQL(d.EscStart, d.EscEnd, d.TableAlias, q, "NULL")
 	}
-	if len(formatCols) <= 0 {
-		return "", fmt.Errorf("sql data is empty, please check it")
-	}
-
-	hasPK := rawColSet.Has(orm.primaryKey)
-	hasUK := false
-	if !hasPK {
-		hasUK = orm.checkUK(rawColSet)
-	}
-
-	if hasPK || hasUK {
-		var upsertSQL strings.Builder
-		upsertSQL.Grow(len(formatCols)*10 + 100)
-
-		upsertSQL.WriteString("MERGE INTO ")
-		upsertSQL.WriteString(dbCore.EscStart)
-		upsertSQL.WriteString(orm.tableName)
-		upsertSQL.WriteString(dbCore.EscEnd)
-		upsertSQL.WriteString(" \"T\" USING (SELECT ")
-		for i := range formatCols {
-			if i > 0 {
-				upsertSQL.WriteByte(',')
-			}
-			upsertSQL.WriteString(rawVal[i])
-			upsertSQL.WriteString(" as ")
-			upsertSQL.WriteString(formatCols[i])
-		}
-		upsertSQL.WriteString(" from \"DUAL\") \"S\" ON (")
-
-		if hasPK {
-			upsertSQL.WriteString("\"T\".")
-			upsertSQL.WriteString(dbCore.EscStart)
-			upsertSQL.WriteString(orm.primaryKey)
-			upsertSQL.WriteString(dbCore.EscEnd)
-			upsertSQL.WriteString("=\"S\".")
-			upsertSQL.WriteString(dbCore.EscStart)
-			upsertSQL.WriteString(orm.primaryKey)
-			upsertSQL.WriteString(dbCore.EscEnd)
-		} else {
-			var onStrBuff strings.Builder
-			onStrBuff.Grow(orm.uniqueKeys.Size() * (escLen + 6 + 10))
-			orm.uniqueKeys.Range(func(item string) bool {
-				if onStrBuff.Len() > 0 {
-					onStrBuff.WriteString(" and ")
-				}
-
-				onStrBuff.WriteString("\"T\".")
-				onStrBuff.WriteString(dbCore.EscStart)
-				onStrBuff.WriteString(item)
-				onStrBuff.WriteString(dbCore.EscEnd)
-				onStrBuff.WriteString("=\"S\".")
-				onStrBuff.WriteString(dbCore.EscStart)
-				onStrBuff.WriteString(item)
-				onStrBuff.WriteString(dbCore.EscEnd)
-				return true
-			})
-			upsertSQL.WriteString(onStrBuff.String())
-		}
-		upsertSQL.WriteString(") WHEN MATCHED THEN UPDATE SET ")
-		if hasPK {
-			has := false
-			formatPK := dbCore.EscStart + orm.primaryKey + dbCore.EscEnd
-			for i := range formatCols {
-				if formatPK == formatCols[i] {
-					continue
-				}
-
-				if has {
-					upsertSQL.WriteByte(',')
-				}
-				has = true
-
-				upsertSQL.WriteString("\"T\".")
-				upsertSQL.WriteString(formatCols[i])
-				upsertSQL.WriteString("=\"S\".")
-				upsertSQL.WriteString(formatCols[i])
-			}
-		} else {
-			has := false
-			for i := range formatCols {
-				k := formatCols[i]
-				if orm.uniqueKeys.Has(k[len(dbCore.EscStart) : len(k)-len(dbCore.EscEnd)]) {
-					continue
-				}
 
-				if has {
-					upsertSQL.WriteByte(',')
-				}
-				has = true
-
-				upsertSQL.WriteString("\"T\".")
-				upsertSQL.WriteString(formatCols[i])
-				upsertSQL.WriteString("=\"S\".")
-				upsertSQL.WriteString(formatCols[i])
-			}
+	// rownum 在排序、分组、去重之前生效，只有简单查询才能直接放在 where 中
+	limitSQL := ""
+	if len(q.Limit) == 1 {
+		if q.OrderBy == "" && q.GroupBy == "" && !strings.HasPrefix(q.Columns, "distinct ") {
+			limitSQL = "rownum<=" + util.UintToStr(q.Limit[0])
+		} else if q.ForUpdate {
+			panic(ErrDBFunc)
+		} else if versionBelow(d.version, 12) {
+			return d.rownumSQL(q)
 		}
-		upsertSQL.WriteString(" WHEN NOT MATCHED THEN INSERT(")
-		upsertSQL.WriteString(util.JoinArr(formatCols, ","))
-		upsertSQL.WriteString(" VALUES(")
-		upsertSQL.WriteString(connectStrArr(formatCols, ",", "\"S\".", ""))
-		upsertSQL.WriteByte(')')
-		return upsertSQL.String(), nil
 	}
 
-	var upsertSQL strings.Builder
-	upsertSQL.WriteString("insert into ")
-	upsertSQL.WriteString(dbCore.EscStart)
-	upsertSQL.WriteString(orm.tableName)
-	upsertSQL.WriteString(dbCore.EscEnd)
-	upsertSQL.WriteByte('(')
-	upsertSQL.WriteString(util.JoinArr(formatCols, ","))
-	upsertSQL.WriteString(") VALUES(")
-	upsertSQL.WriteString(util.JoinArr(rawVal, ","))
-	upsertSQL.WriteByte(')')
-	return upsertSQL.String(), nil
-}
-
-func (orm *ORM) oracleUpsertManySQL(dataList []interface{}, cols []string) (string, error) {
-	if len(dataList) <= 0 {
-		return "", fmt.Errorf("upsert data is nil")
+	var sql strings.Builder
+	sql.Grow(100)
+	sql.WriteString("select ")
+	q.writeFrom(&sql, d.TableAlias)
+	q.writeWhere(&sql, limitSQL)
+	q.writeGroupBy(&sql)
+	q.writeOrderBy(&sql)
+
+	if len(q.Limit) == 1 && limitSQL == "" {
+		sql.WriteString(" FETCH FIRST ")
+		sql.WriteString(util.UintToStr(q.Limit[0]))
+		sql.WriteString(" ROWS ONLY")
+	} else if len(q.Limit) == 2 {
+		sql.WriteString(" OFFSET ")
+		sql.WriteString(util.UintToStr(q.Limit[0]))
+		sql.WriteString(" ROWS FETCH NEXT ")
+		sql.WriteString(util.UintToStr(q.Limit[1]))
+		sql.WriteString(" ROWS ONLY")
 	}
 
-	dbCore := orm.ref.getDBConf()
-	escLen := len(dbCore.EscStart) + len(dbCore.EscEnd)
-
-	typeErrStr := "type of upsert data is []map[string]interface{} or []*struct or []struct"
-
-	formatCols := make([]string, 0, len(cols))
-	for _, k := range cols {
-		err := globalVerifyObj.VerifyFieldName(k)
-		if err != nil {
-			return "", err
-		}
-		var formatKey strings.Builder
-		formatKey.Grow(escLen + len(k))
-		formatKey.WriteString(dbCore.EscStart)
-		formatKey.WriteString(k)
-		formatKey.WriteString(dbCore.EscEnd)
-
-		formatCols = append(formatCols, formatKey.String())
+	if q.ForUpdate {
+		sql.WriteString(" for update")
 	}
+	return sql.String()
+}
 
-	rawColSet := set.New[string]()
-	rawColSet.Add(cols...)
-
-	rawVal := make([][]string, 0, len(dataList))
-	for _, data := range dataList {
-		var valMap map[string]interface{}
-		switch data := data.(type) {
-		case map[string]interface{}:
-			valMap = data
-		default:
-			dataValue := reflect.ValueOf(data)
-			if dataValue.Type().Kind() != reflect.Struct && dataValue.Type().Kind() != reflect.Ptr {
-				return "", fmt.Errorf(typeErrStr)
-			}
-
-			if dataValue.Type().Kind() == reflect.Ptr {
-				dataValue = dataValue.Elem()
-			}
-
-			if dataValue.Type().Kind() != reflect.Struct {
-				return "", fmt.Errorf(typeErrStr)
-			}
+// rownumSQL 12c 以下：排序（分组、去重）之后再使用 rownum 限制条数
+// select * from (select ... order by ...) where rownum<=n
+func (d oracleDialect) rownumSQL(q *SelectParts) string {
+	parts := *q
+	parts.Limit = nil
+	inner := d.Select(&parts)
 
-			valMap = map[string]interface{}{}
+	var sql strings.Builder
+	sql.Grow(len(inner) + 50)
+	sql.WriteString("select * from (")
+	sql.WriteString(inner)
+	sql.WriteString(") where rownum<=")
+	sql.WriteString(util.UintToStr(q.Limit[0]))
+	return sql.String()
+}
 
-			for i := 0; i < dataValue.NumField(); i++ {
-				colName := dataValue.Type().Field(i).Tag.Get("json")
-				ref := dataValue.Type().Field(i).Tag.Get("ref")
-				if ref != "" || colName == "" || !dataValue.Type().Field(i).IsExported() {
-					continue
-				}
+func (d oracleDialect) IgnoreCondition(c *Condition) (string, bool) {
+	return oracleIgnoreFormatSubSQL(c.Operator, c.Column, c.Value, c.Raw, c.RawList, c.Data), true
+}
 
-				valMap[colName] = dataValue.Field(i).Interface()
-			}
-		}
-		if len(valMap) <= 0 {
-			return "", fmt.Errorf("sql data is empty, please check it")
-		}
+// Search 需要 Oracle Text 索引（CONTEXT），query 为 CONTAINS 的检索条件
+func (d oracleDialect) Search(column, query string) (string, error) {
+	return "CONTAINS(" + column + "," + query + ")>0", nil
+}
 
-		subVal := make([]string, 0, len(cols))
-		for _, colName := range cols {
-			if v, ok := valMap[colName]; ok {
-				val, timeEmpty := orm.formatValue(v)
-				if (colName == orm.primaryKey && (val == "" || val == "0")) || timeEmpty {
-					subVal = append(subVal, "null")
-					continue
-				}
+// oracleDateFormat 日期各部分对应的格式
+var oracleDateFormat = map[string]string{
+	"year":    "YYYY",
+	"quarter": "Q",
+	"month":   "MM",
+	"week":    "IW",
+	"day":     "DD",
+	"hour":    "HH24",
+}
 
-				subVal = append(subVal, val)
-			} else if v, ok = valMap["#"+colName]; ok {
-				subVal = append(subVal, util.Any2String(v))
-			} else {
-				subVal = append(subVal, "null")
-			}
-		}
-		rawVal = append(rawVal, subVal)
+func (d oracleDialect) DatePart(column, part string) (string, error) {
+	if part == "weekday" {
+		return "(TRUNC(" + column + ")-TRUNC(" + column + ",'IW')+1)", nil
 	}
 
-	if len(rawVal) <= 0 {
-		return "", fmt.Errorf("upsert data is empty")
+	f, ok := oracleDateFormat[part]
+	if !ok {
+		return "", ErrDBFunc
 	}
+	return "TO_NUMBER(TO_CHAR(" + column + ",'" + f + "'))", nil
+}
 
-	hasPK := rawColSet.Has(orm.primaryKey)
-	hasUK := false
-	if !hasPK {
-		hasUK = orm.checkUK(rawColSet)
+func (d oracleDialect) DateTrunc(column, unit string) (string, error) {
+	f, ok := oracleDateFormat[unit]
+	if !ok {
+		return "", ErrDBFunc
 	}
+	return "TRUNC(" + column + ",'" + f + "')", nil
+}
 
-	unionStr := " UNION "
-	if orm.oracleMergeUnionAll {
-		unionStr = " UNION ALL "
+// Regex REGEXP_LIKE，i 忽略大小写，c 区分大小写
+func (d oracleDialect) Regex(column, pattern string, flag RegexFlag) (string, error) {
+	var subSQL strings.Builder
+	subSQL.Grow(len(column) + len(pattern) + 30)
+	if flag.Has(RegexNot) {
+		subSQL.WriteString("not ")
+	}
+	subSQL.WriteString("REGEXP_LIKE(")
+	subSQL.WriteString(column)
+	subSQL.WriteByte(',')
+	subSQL.WriteString(pattern)
+	if flag.Has(RegexIgnoreCase) {
+		subSQL.WriteString(",'i'")
+	} else if flag.Has(RegexCaseSensitive) {
+		subSQL.WriteString(",'c'")
 	}
-	if hasPK || hasUK {
-		var upsertSQL strings.Builder
-		upsertSQL.Grow(len(formatCols)*10 + 100)
-
-		upsertSQL.WriteString("MERGE INTO ")
-		upsertSQL.WriteString(dbCore.EscStart)
-		upsertSQL.WriteString(orm.tableName)
-		upsertSQL.WriteString(dbCore.EscEnd)
-		upsertSQL.WriteString(" \"T\" USING (")
-		for index := range rawVal {
-			if index > 0 {
-				upsertSQL.WriteString(unionStr)
-			}
-			upsertSQL.WriteString("SELECT ")
-			for i := range formatCols {
-				if i > 0 {
-					upsertSQL.WriteByte(',')
-				}
-				upsertSQL.WriteString(rawVal[index][i])
-				upsertSQL.WriteString(" as ")
-				upsertSQL.WriteString(formatCols[i])
-			}
-			upsertSQL.WriteString(" from \"DUAL\"")
-		}
-
-		upsertSQL.WriteString(") \"S\" ON (")
-
-		if hasPK {
-			upsertSQL.WriteString("\"T\".")
-			upsertSQL.WriteString(dbCore.EscStart)
-			upsertSQL.WriteString(orm.primaryKey)
-			upsertSQL.WriteString(dbCore.EscEnd)
-			upsertSQL.WriteString("=\"S\".")
-			upsertSQL.WriteString(dbCore.EscStart)
-			upsertSQL.WriteString(orm.primaryKey)
-			upsertSQL.WriteString(dbCore.EscEnd)
-		} else {
-			var onStrBuff strings.Builder
-			onStrBuff.Grow(orm.uniqueKeys.Size() * (escLen + 6 + 10))
-			orm.uniqueKeys.Range(func(item string) bool {
-				if onStrBuff.Len() > 0 {
-					onStrBuff.WriteString(" and ")
-				}
-
-				onStrBuff.WriteString("\"T\".")
-				onStrBuff.WriteString(dbCore.EscStart)
-				onStrBuff.WriteString(item)
-				onStrBuff.WriteString(dbCore.EscEnd)
-				onStrBuff.WriteString("=\"S\".")
-				onStrBuff.WriteString(dbCore.EscStart)
-				onStrBuff.WriteString(item)
-				onStrBuff.WriteString(dbCore.EscEnd)
-				return true
-			})
-			upsertSQL.WriteString(onStrBuff.String())
-		}
-		upsertSQL.WriteString(") WHEN MATCHED THEN UPDATE SET ")
-		if hasPK {
-			has := false
-			formatPK := dbCore.EscStart + orm.primaryKey + dbCore.EscEnd
-			for i := range formatCols {
-				if formatPK == formatCols[i] {
-					continue
-				}
-
-				if has {
-					upsertSQL.WriteByte(',')
-				}
-				has = true
-
-				upsertSQL.WriteString("\"T\".")
-				upsertSQL.WriteString(formatCols[i])
-				upsertSQL.WriteString("=\"S\".")
-				upsertSQL.WriteString(formatCols[i])
-			}
-		} else {
-			has := false
-			for i := range formatCols {
-				k := formatCols[i]
-				if orm.uniqueKeys.Has(k[len(dbCore.EscStart) : len(k)-len(dbCore.EscEnd)]) {
-					continue
-				}
-
-				if has {
-					upsertSQL.WriteByte(',')
-				}
-				has = true
+	subSQL.WriteByte(')')
+	return subSQL.String(), nil
+}
 
-				upsertSQL.WriteString("\"T\".")
-				upsertSQL.WriteString(formatCols[i])
-				upsertSQL.WriteString("=\"S\".")
-				upsertSQL.WriteString(formatCols[i])
-			}
-		}
-		upsertSQL.WriteString(" WHEN NOT MATCHED THEN INSERT(")
-		upsertSQL.WriteString(util.JoinArr(formatCols, ","))
-		upsertSQL.WriteString(" VALUES(")
-		upsertSQL.WriteString(connectStrArr(formatCols, ",", "\"S\".", ""))
-		upsertSQL.WriteByte(')')
-		return upsertSQL.String(), nil
+func (d oracleDialect) Insert(w *WriteParts) (string, error) {
+	if len(w.Rows) <= 1 {
+		return d.BaseDialect.Insert(w)
 	}
 
-	var insertSQL strings.Builder
-	insertSQL.Grow(len(rawVal)*len(cols)*25 + 200)
-
-	insertSQL.WriteString("insert all")
-
 	var tableBuff strings.Builder
-	tableBuff.Grow(len(rawVal)*len(cols)*5 + 100)
-	tableBuff.WriteString(dbCore.EscStart)
-	tableBuff.WriteString(orm.tableName)
-	tableBuff.WriteString(dbCore.EscEnd)
+	tableBuff.WriteString(quoteName(d.EscStart, d.EscEnd, w.Table))
 	tableBuff.WriteByte('(')
-	for i := range formatCols {
-		if i > 0 {
-			tableBuff.WriteByte(',')
-		}
-		tableBuff.WriteString(formatCols[i])
-	}
+	tableBuff.WriteString(connectStrArr(w.Cols, ",", d.EscStart, d.EscEnd))
 	tableBuff.WriteByte(')')
 	tableAndField := tableBuff.String()
 
-	for i := 0; i < len(rawVal); i++ {
+	var insertSQL strings.Builder
+	insertSQL.Grow(len(w.Rows)*len(w.Cols)*25 + 200)
+	insertSQL.WriteString("insert all")
+	for _, row := range w.Rows {
 		insertSQL.WriteString(" into ")
 		insertSQL.WriteString(tableAndField)
 		insertSQL.WriteString(" VALUES(")
-		for j := 0; j < len(rawVal[i]); j++ {
-			if j > 0 {
-				insertSQL.WriteByte(',')
-			}
-			insertSQL.WriteString(rawVal[i][j])
-		}
+		insertSQL.WriteString(util.JoinArr(row, ","))
 		insertSQL.WriteByte(')')
 	}
-
 	insertSQL.WriteString(" select * from \"DUAL\"")
 	return insertSQL.String(), nil
 }
+
+func (d oracleDialect) Upsert(w *WriteParts) (string, error) {
+	keys := w.ConflictKeys()
+	if keys == nil {
+		return d.Insert(w)
+	}
+
+	unionStr := " UNION "
+	if w.UnionAll {
+		unionStr = " UNION ALL "
+	}
+
+	var source strings.Builder
+	source.Grow(len(w.Rows)*len(w.Cols)*25 + 20)
+	source.WriteByte('(')
+	for index, row := range w.Rows {
+		if index > 0 {
+			source.WriteString(unionStr)
+		}
+		source.WriteString("SELECT ")
+		for i := range w.Cols {
+			if i > 0 {
+				source.WriteByte(',')
+			}
+			source.WriteString(row[i])
+			source.WriteString(" as ")
+			source.WriteString(quoteName(d.EscStart, d.EscEnd, w.Cols[i]))
+		}
+		source.WriteString(" from \"DUAL\"")
+	}
+	source.WriteByte(')')
+	return mergeSQL(d.EscStart, d.EscEnd, d.TableAlias, source.String(), w, keys), nil
+}
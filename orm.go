@@ -5,7 +5,6 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
-	"strings"
 
 	"github.com/assembly-hub/basics/set"
 	"github.com/assembly-hub/basics/util"
@@ -13,8 +12,6 @@ import (
 	"github.com/assembly-hub/log"
 	"github.com/assembly-hub/log/empty"
 	"github.com/assembly-hub/task/execute"
-
-	"github.com/assembly-hub/orm/dbtype"
 )
 
 const (
@@ -25,29 +22,38 @@ const (
 )
 
 var (
+	// 为 nil 时由数据库方言（Dialect.LastInsertID）决定
 	notReadyLastInsertIDSet set.Set[int]
 )
 
-func init() {
-	notReadyLastInsertIDSet = set.New[int]()
-	notReadyLastInsertIDSet.Add(dbtype.SQLServer, dbtype.Postgres, dbtype.OpenGauss)
-}
-
-// InitNotReadyLastInsertID 初始化方法 LastInsertID 没有实现的数据库类型，会覆盖默认设置
+// InitNotReadyLastInsertID 初始化方法 LastInsertID 没有实现的数据库类型，会覆盖数据库方言的设置
 func InitNotReadyLastInsertID(db ...int) {
-	notReadyLastInsertIDSet.Clear()
-	notReadyLastInsertIDSet.Add(db...)
+	dbSet := set.New[int]()
+	dbSet.Add(db...)
+	notReadyLastInsertIDSet = dbSet
 }
 
 type databaseQuery struct {
 	Distinct        bool
 	SelectForUpdate bool
 	Select          []string
+	SelectExpr      []*RawSQL
 	Order           []string
+	SearchOrder     [][2]string
+	ValuesOrder     []*RawSQL
+	OrderExpr       []*RawSQL
+	GroupByExpr     []*RawSQL
 	Limit           []uint
 	Where           map[string]interface{}
 	GroupBy         []string
+	Grouping        *GroupingSet
 	Having          map[string]interface{}
+	Final           bool
+	Sample          string
+	LimitBy         []uint
+	LimitByCols     []string
+	Settings        map[string]interface{}
+	SetQuery        []*setORM
 }
 
 func newDBQuery() *databaseQuery {
@@ -56,10 +62,19 @@ func newDBQuery() *databaseQuery {
 	q.SelectForUpdate = false
 	q.Select = []string{}
 	q.Order = []string{}
+	q.SearchOrder = [][2]string{}
+	q.ValuesOrder = []*RawSQL{}
+	q.SelectExpr = []*RawSQL{}
+	q.OrderExpr = []*RawSQL{}
+	q.GroupByExpr = []*RawSQL{}
 	q.Limit = []uint{}
 	q.Where = map[string]interface{}{}
 	q.GroupBy = []string{}
 	q.Having = map[string]interface{}{}
+	q.LimitBy = []uint{}
+	q.LimitByCols = []string{}
+	q.Settings = map[string]interface{}{}
+	q.SetQuery = []*setORM{}
 	return q
 }
 
@@ -97,6 +112,14 @@ type ORM struct {
 	// 默认limit
 	limit uint
 
+	// 使用绑定参数执行SQL
+	bindParams bool
+
+	// 派生表：子查询作为主表，tableName 为子查询的别名
+	fromQuery *BaseQuery
+	// 公共表表达式（WITH）
+	with []*CTE
+
 	// 查询配置数据
 	Q      *databaseQuery
 	logger log.Log
@@ -153,6 +176,12 @@ func NewORMWithTx(ctx context.Context, tableName string, tx db.Tx, ref *Referenc
 	return dao
 }
 
+// NewORMFromQuery 子查询作为主表（派生表），alias 为子查询的别名，字段为 alias.字段，
+// 使用 ref.AddTableDef(alias, 结构体) 定义子查询的字段后，可以通过 tag 关联其他表；派生表只能查询
+func NewORMFromQuery(ctx context.Context, alias string, sub *ORM, executor db.Executor, ref *Reference) *ORM {
+	return NewORM(ctx, alias, executor, ref).FromSubquery(sub)
+}
+
 func initORM() *ORM {
 	dao := new(ORM)
 	dao.uniqueKeys = set.New[string]()
@@ -221,6 +250,13 @@ func (orm *ORM) UniqueKeys(uniqueKey ...string) *ORM {
 	return orm
 }
 
+// BindParams 启用后数据以绑定参数（占位符）传递给数据库，不再拼接到SQL中
+// 占位符：mysql/sqlite/clickhouse ?；postgres/opengauss $1；sqlserver @p1；oracle :1
+func (orm *ORM) BindParams(b bool) *ORM {
+	orm.bindParams = b
+	return orm
+}
+
 func (orm *ORM) SelectColLinkStr(s string) *ORM {
 	orm.selectColLinkStr = s
 	return orm
@@ -232,6 +268,36 @@ func (orm *ORM) CustomSQL(sql string) *ORM {
 	return orm
 }
 
+// FromSubquery 子查询作为主表（派生表），表名为子查询的别名：select ... from (子查询) as 表名，
+// 子查询的字段别名为打平的格式（tag_字段）；派生表只能查询，写操作返回 ErrSubquery
+func (orm *ORM) FromSubquery(sub *ORM) *ORM {
+	if sub == nil {
+		panic("subquery is nil")
+	}
+	orm.fromQuery = sub.cond(true)
+	return orm
+}
+
+// With 公共表表达式：with name as (q) select ...，name 可以作为表名（新建 ORM）或关联的表（ref 中定义），
+// 与 FromSubquery 一样不会被 ClearCache 清除；MySQL 8.0、MariaDB 10.2、SQLite 3.8.3 以下返回 ErrDBFunc
+func (orm *ORM) With(name string, q *ORM) *ORM {
+	if q == nil {
+		panic("with query is nil")
+	}
+	orm.with = append(orm.with, &CTE{Name: name, Query: q.cond(true)})
+	return orm
+}
+
+// WithRecursive 递归的公共表表达式：with recursive name as (anchor union all recursive)，
+// recursive 通过 tag 关联 name（如：ref:"inner;parent_id=id"）；Oracle 需要使用 ref.AddTableDef(name, 结构体) 定义字段，顺序与查询字段一致
+func (orm *ORM) WithRecursive(name string, anchor, recursive *ORM) *ORM {
+	if anchor == nil || recursive == nil {
+		panic("with query is nil")
+	}
+	orm.with = append(orm.with, &CTE{Name: name, Query: anchor.cond(true), Recursive: recursive.cond(true)})
+	return orm
+}
+
 // Query 条件对
 // "id__gt", 1, "name": "test"
 func (orm *ORM) Query(pair ...interface{}) *ORM {
@@ -267,7 +333,10 @@ func (orm *ORM) Clone(ctx context.Context) *ORM {
 	dao.Q = newDBQuery()
 	dao.ctx = ctx
 	dao.primaryKey = orm.primaryKey
+	dao.bindParams = orm.bindParams
 	dao.logger = orm.logger
+	dao.fromQuery = orm.fromQuery
+	dao.with = orm.with
 	return dao
 }
 
@@ -313,6 +382,50 @@ func (orm *ORM) SelectForUpdate(b bool) *ORM {
 	return orm
 }
 
+// Final ClickHouse：主表使用 FINAL，读取 ReplacingMergeTree 等引擎合并（去重）后的数据
+func (orm *ORM) Final(b bool) *ORM {
+	orm.Q.Final = b
+	return orm
+}
+
+// Sample ClickHouse：主表的 SAMPLE 子句，格式为：k [OFFSET m]，k m 为小数或分数，k 也可以是行数，如：0.1 1/10 10000 1/10 OFFSET 1/2
+func (orm *ORM) Sample(sample string) *ORM {
+	if !isSample(sample) {
+		panic(fmt.Sprintf("sample[%s] is invalid", sample))
+	}
+	orm.Q.Sample = sample
+	return orm
+}
+
+// LimitBy ClickHouse：limit size by cols，每组数据最多 size 条，字段规则与 GroupBy 一致
+func (orm *ORM) LimitBy(size uint, cols ...string) *ORM {
+	if len(cols) <= 0 {
+		panic("limit by cols is empty")
+	}
+	orm.Q.LimitBy = []uint{size}
+	orm.Q.LimitByCols = cols
+	return orm
+}
+
+// OverLimitBy ClickHouse：limit over,size by cols
+func (orm *ORM) OverLimitBy(over, size uint, cols ...string) *ORM {
+	if len(cols) <= 0 {
+		panic("limit by cols is empty")
+	}
+	orm.Q.LimitBy = []uint{over, size}
+	orm.Q.LimitByCols = cols
+	return orm
+}
+
+// Settings ClickHouse：查询的 SETTINGS，如：Settings("max_threads", 8)，值为 string bool 或数值，其他类型在生成SQL时 panic
+func (orm *ORM) Settings(name string, value interface{}) *ORM {
+	if name == "" || value == nil {
+		panic("setting name and value cannot be nil")
+	}
+	orm.Q.Settings[name] = value
+	return orm
+}
+
 func (orm *ORM) Where(col string, value interface{}) *ORM {
 	if col == "" || value == nil {
 		panic("Fields and conditions cannot be nil")
@@ -359,16 +472,69 @@ func (orm *ORM) Select(cols ...string) *ORM {
 	return orm
 }
 
+// SelectExpr 查询字段为原始SQL片段（orm.Raw），排在 Select 的字段（未设置时为默认字段）之后，别名使用 As 设置，如：SelectExpr(orm.Raw("price*?", 0.8).As("discount"))
+func (orm *ORM) SelectExpr(list ...*RawSQL) *ORM {
+	orm.Q.SelectExpr = append(orm.Q.SelectExpr, list...)
+	return orm
+}
+
 func (orm *ORM) Order(cols ...string) *ORM {
 	orm.Q.Order = append(orm.Q.Order, cols...)
 	return orm
 }
 
+// OrderBySearch 按全文检索的相关度降序排序，排在 Order 之前，col 的规则与 Order 一致，
+// 相关度仅支持 mysql（MATCH AGAINST）与 postgres（ts_rank），其他数据库返回 ErrDBFunc
+func (orm *ORM) OrderBySearch(col, query string) *ORM {
+	orm.Q.SearchOrder = append(orm.Q.SearchOrder, [2]string{col, query})
+	return orm
+}
+
+// OrderByValues 按值列表排序（置顶），列表中的值按顺序排在前面，其他值排在后面，排在 OrderBySearch 之后、Order 之前，
+// col 的规则与 orm.Col 一致（按 tag 解析），MySQL 使用 field()，其他数据库使用 case when，如：OrderByValues("tb2.status", "urgent", "high")
+func (orm *ORM) OrderByValues(col string, values ...interface{}) *ORM {
+	orm.Q.ValuesOrder = append(orm.Q.ValuesOrder, valuesOrder(col, values))
+	return orm
+}
+
+// OrderExpr 排序为原始SQL片段（orm.Raw），排在 Order 之后，排序方式写在SQL中，如：OrderExpr(orm.Raw("abs(score-?) desc", 60))
+func (orm *ORM) OrderExpr(list ...*RawSQL) *ORM {
+	orm.Q.OrderExpr = append(orm.Q.OrderExpr, list...)
+	return orm
+}
+
+// Union 集合运算：当前查询 union others，Order Limit Page 作用于合并后的结果，排序字段为结果的字段名（跨表字段为 tag_字段）；
+// 各查询的字段数量必须一致，others 不能设置排序与分页
+func (orm *ORM) Union(others ...*ORM) *ORM {
+	return orm.setOperation("union", others)
+}
+
+// UnionAll 与 Union 一致，保留重复数据
+func (orm *ORM) UnionAll(others ...*ORM) *ORM {
+	return orm.setOperation("union all", others)
+}
+
+// Intersect 交集，规则与 Union 一致，MySQL 需要 8.0.31 及以上
+func (orm *ORM) Intersect(others ...*ORM) *ORM {
+	return orm.setOperation("intersect", others)
+}
+
+// Except 差集，规则与 Union 一致，Oracle 使用 minus，MySQL 需要 8.0.31 及以上
+func (orm *ORM) Except(others ...*ORM) *ORM {
+	return orm.setOperation("except", others)
+}
+
 func (orm *ORM) GroupBy(cols ...string) *ORM {
 	orm.Q.GroupBy = append(orm.Q.GroupBy, cols...)
 	return orm
 }
 
+// GroupByExpr 分组为原始SQL片段或表达式（orm.Raw orm.Func 等），排在 GroupBy 之后
+func (orm *ORM) GroupByExpr(list ...*RawSQL) *ORM {
+	orm.Q.GroupByExpr = append(orm.Q.GroupByExpr, list...)
+	return orm
+}
+
 func (orm *ORM) ClearCache() *ORM {
 	orm.Q = newDBQuery()
 	return orm
@@ -413,12 +579,26 @@ func (orm *ORM) cond(flat bool) *BaseQuery {
 		Where:            orm.Q.Where,
 		SelectColLinkStr: orm.selectColLinkStr,
 		Order:            orm.Q.Order,
+		SearchOrder:      orm.Q.SearchOrder,
+		ValuesOrder:      orm.Q.ValuesOrder,
+		OrderExpr:        orm.Q.OrderExpr,
+		GroupByExpr:      orm.Q.GroupByExpr,
+		SelectExpr:       orm.Q.SelectExpr,
 		Distinct:         orm.Q.Distinct,
 		SelectForUpdate:  orm.Q.SelectForUpdate,
 		Limit:            orm.Q.Limit,
 		Select:           orm.Q.Select,
 		GroupBy:          orm.Q.GroupBy,
+		Grouping:         orm.Q.Grouping,
 		Having:           orm.Q.Having,
+		Final:            orm.Q.Final,
+		Sample:           orm.Q.Sample,
+		LimitBy:          orm.Q.LimitBy,
+		LimitByCols:      orm.Q.LimitByCols,
+		Settings:         orm.Q.Settings,
+		FromQuery:        orm.fromQuery,
+		With:             orm.with,
+		SetQuery:         orm.setQuery(flat),
 	}
 	if !flat {
 		q.SelectColLinkStr = selectColLinkStr
@@ -431,26 +611,7 @@ func (orm *ORM) ToSQL(flat bool) string {
 		return orm.customSQL
 	}
 
-	q := BaseQuery{
-		CustomSQL:        orm.customSQL,
-		PrivateKey:       orm.primaryKey,
-		RefConf:          orm.ref,
-		TableName:        orm.tableName,
-		Where:            orm.Q.Where,
-		SelectColLinkStr: orm.selectColLinkStr,
-		Order:            orm.Q.Order,
-		Distinct:         orm.Q.Distinct,
-		SelectForUpdate:  orm.Q.SelectForUpdate,
-		Limit:            orm.Q.Limit,
-		Select:           orm.Q.Select,
-		GroupBy:          orm.Q.GroupBy,
-		Having:           orm.Q.Having,
-	}
-	if !flat {
-		q.SelectColLinkStr = selectColLinkStr
-	}
-
-	return q.SQL()
+	return orm.cond(flat).SQL()
 }
 
 func (orm *ORM) PageData(result interface{}, flat bool, pageNo, pageSize uint) (pg *Paging, err error) {
@@ -524,34 +685,18 @@ func (orm *ORM) ToData(result interface{}, flat bool) (err error) {
 		}()
 	}
 
-	q := BaseQuery{
-		CustomSQL:        orm.customSQL,
-		PrivateKey:       orm.primaryKey,
-		RefConf:          orm.ref,
-		TableName:        orm.tableName,
-		Where:            orm.Q.Where,
-		SelectColLinkStr: orm.selectColLinkStr,
-		Order:            orm.Q.Order,
-		Distinct:         orm.Q.Distinct,
-		SelectForUpdate:  orm.Q.SelectForUpdate,
-		Limit:            orm.Q.Limit,
-		Select:           orm.Q.Select,
-		GroupBy:          orm.Q.GroupBy,
-		Having:           orm.Q.Having,
-	}
+	q := orm.cond(flat)
+	q.BindParams = orm.bindParams
 	if len(q.Limit) <= 0 && orm.limit > 0 {
 		q.Limit = []uint{orm.limit}
 	}
-	if !flat {
-		q.SelectColLinkStr = selectColLinkStr
-	}
 
 	var sqlDB db.BaseExecutor = orm.tx
 	if sqlDB == nil {
 		sqlDB = orm.executor
 	}
 
-	return toData(orm.ctx, sqlDB, &q, result, flat)
+	return toData(orm.ctx, sqlDB, q, result, flat)
 }
 
 func (orm *ORM) FetchData(dataType interface{}, flat bool, fetch func(row interface{}) bool) (err error) {
@@ -572,36 +717,21 @@ func (orm *ORM) FetchData(dataType interface{}, flat bool, fetch func(row interf
 		}()
 	}
 
-	q := BaseQuery{
-		CustomSQL:        orm.customSQL,
-		PrivateKey:       orm.primaryKey,
-		RefConf:          orm.ref,
-		TableName:        orm.tableName,
-		Where:            orm.Q.Where,
-		SelectColLinkStr: orm.selectColLinkStr,
-		Order:            orm.Q.Order,
-		Distinct:         orm.Q.Distinct,
-		SelectForUpdate:  orm.Q.SelectForUpdate,
-		Limit:            orm.Q.Limit,
-		Select:           orm.Q.Select,
-		GroupBy:          orm.Q.GroupBy,
-		Having:           orm.Q.Having,
-	}
+	q := orm.cond(flat)
+	q.BindParams = orm.bindParams
 	if len(q.Limit) <= 0 && orm.limit > 0 {
 		q.Limit = []uint{orm.limit}
 	}
-	if !flat {
-		q.SelectColLinkStr = selectColLinkStr
-	}
 
 	var sqlDB db.BaseExecutor = orm.tx
 	if sqlDB == nil {
 		sqlDB = orm.executor
 	}
-	return fetchData(orm.ctx, sqlDB, &q, dataType, flat, fetch)
+	return fetchData(orm.ctx, sqlDB, q, dataType, flat, fetch)
 }
 
-func (orm *ORM) ExecuteSQL(customSQL string) (affectedRow int64, err error) {
+// ExecuteSQL 执行自定义SQL，args 为绑定参数，占位符需符合数据库驱动的要求
+func (orm *ORM) ExecuteSQL(customSQL string, args ...interface{}) (affectedRow int64, err error) {
 	defer func() {
 		if p := recover(); p != nil {
 			switch p := p.(type) {
@@ -615,9 +745,9 @@ func (orm *ORM) ExecuteSQL(customSQL string) (affectedRow int64, err error) {
 
 	var ret sql.Result
 	if orm.tx != nil {
-		ret, err = orm.tx.ExecContext(orm.ctx, customSQL)
+		ret, err = orm.tx.ExecContext(orm.ctx, customSQL, args...)
 	} else if orm.executor != nil {
-		ret, err = orm.executor.ExecContext(orm.ctx, customSQL)
+		ret, err = orm.executor.ExecContext(orm.ctx, customSQL, args...)
 	} else {
 		return 0, ErrClient
 	}
@@ -650,27 +780,26 @@ func (orm *ORM) Exist() (b bool, err error) {
 		}()
 	}
 
-	q := BaseQuery{
-		PrivateKey:       orm.primaryKey,
-		RefConf:          orm.ref,
-		TableName:        orm.tableName,
-		Where:            orm.Q.Where,
-		SelectColLinkStr: orm.selectColLinkStr,
-		Order:            orm.Q.Order,
-		Distinct:         orm.Q.Distinct,
-		SelectForUpdate:  orm.Q.SelectForUpdate,
-		Limit:            Limit{1},
-		Select:           Select{orm.primaryKey},
-		GroupBy:          orm.Q.GroupBy,
-		Having:           orm.Q.Having,
+	// 集合运算的字段由各个查询决定，使用 count 检查
+	if len(orm.Q.SetQuery) > 0 {
+		c, err := orm.Count(false)
+		return c > 0, err
 	}
 
+	q := orm.cond(true)
+	// exists 查询只使用条件，不执行自定义SQL
+	q.CustomSQL = ""
+	q.Limit = Limit{1}
+	q.Select = Select{orm.primaryKey}
+	q.SelectExpr = nil
+	q.BindParams = orm.bindParams
+
 	var c int64
 	var sqlDB db.BaseExecutor = orm.tx
 	if sqlDB == nil {
 		sqlDB = orm.executor
 	}
-	err = toData(orm.ctx, sqlDB, &q, &c, false)
+	err = toData(orm.ctx, sqlDB, q, &c, false)
 	if err != nil {
 		return false, err
 	}
@@ -702,27 +831,15 @@ func (orm *ORM) Count(clearCache bool) (c int64, err error) {
 			orm.ClearCache()
 		}()
 	}
-	q := BaseQuery{
-		CustomSQL:        orm.customSQL,
-		PrivateKey:       orm.primaryKey,
-		RefConf:          orm.ref,
-		TableName:        orm.tableName,
-		Where:            orm.Q.Where,
-		SelectColLinkStr: selectColLinkStr,
-		Order:            orm.Q.Order,
-		Distinct:         orm.Q.Distinct,
-		SelectForUpdate:  orm.Q.SelectForUpdate,
-		Limit:            []uint{1},
-		Select:           orm.Q.Select,
-		GroupBy:          orm.Q.GroupBy,
-		Having:           orm.Q.Having,
-	}
+	q := orm.cond(false)
+	q.Limit = []uint{1}
+	q.BindParams = orm.bindParams
 
 	var sqlDB db.BaseExecutor = orm.tx
 	if sqlDB == nil {
 		sqlDB = orm.executor
 	}
-	return count(orm.ctx, sqlDB, &q)
+	return count(orm.ctx, sqlDB, q)
 }
 
 func (orm *ORM) InsertOne(data interface{}) (insertID int64, err error) {
@@ -749,7 +866,7 @@ func (orm *ORM) InsertOne(data interface{}) (insertID int64, err error) {
 	}
 
 	if sqlDB != nil {
-		ret, err = sqlDB.ExecContext(orm.ctx, insertSQL)
+		ret, err = orm.execContext(sqlDB, insertSQL)
 	} else {
 		return 0, ErrClient
 	}
@@ -757,7 +874,7 @@ func (orm *ORM) InsertOne(data interface{}) (insertID int64, err error) {
 		return 0, err
 	}
 
-	if notReadyLastInsertIDSet.Has(orm.ref.dbConf.DBType) {
+	if !orm.lastInsertID() {
 		return -1, nil
 	}
 
@@ -806,7 +923,7 @@ func (orm *ORM) InsertMany(data []interface{}, trans bool) (affected int64, inse
 		}()
 
 		for _, sqlObj := range sqlArr {
-			execContext, err := tx.ExecContext(orm.ctx, sqlObj)
+			execContext, err := orm.execContext(tx, sqlObj)
 			if err != nil {
 				panic(err)
 			}
@@ -817,7 +934,7 @@ func (orm *ORM) InsertMany(data []interface{}, trans bool) (affected int64, inse
 			}
 			affected += rowsAffected
 
-			if !notReadyLastInsertIDSet.Has(orm.ref.dbConf.DBType) {
+			if orm.lastInsertID() {
 				insertID, err := execContext.LastInsertId()
 				if err != nil {
 					panic(err)
@@ -837,9 +954,9 @@ func (orm *ORM) InsertMany(data []interface{}, trans bool) (affected int64, inse
 		var execContext sql.Result
 		for _, sqlObj := range sqlArr {
 			if orm.tx != nil {
-				execContext, err = orm.tx.ExecContext(orm.ctx, sqlObj)
+				execContext, err = orm.execContext(orm.tx, sqlObj)
 			} else if orm.executor != nil {
-				execContext, err = orm.executor.ExecContext(orm.ctx, sqlObj)
+				execContext, err = orm.execContext(orm.executor, sqlObj)
 			} else {
 				return 0, nil, ErrClient
 			}
@@ -854,7 +971,7 @@ func (orm *ORM) InsertMany(data []interface{}, trans bool) (affected int64, inse
 			}
 			affected += rowsAffected
 
-			if !notReadyLastInsertIDSet.Has(orm.ref.dbConf.DBType) {
+			if orm.lastInsertID() {
 				insertID, err := execContext.LastInsertId()
 				if err != nil {
 					panic(err)
@@ -947,7 +1064,7 @@ func (orm *ORM) UpsertOne(data interface{}) (insertID int64, err error) {
 	}
 
 	if sqlDB != nil {
-		ret, err = sqlDB.ExecContext(orm.ctx, insertSQL)
+		ret, err = orm.execContext(sqlDB, insertSQL)
 	} else {
 		return 0, ErrClient
 	}
@@ -955,7 +1072,7 @@ func (orm *ORM) UpsertOne(data interface{}) (insertID int64, err error) {
 		return 0, err
 	}
 
-	if notReadyLastInsertIDSet.Has(orm.ref.dbConf.DBType) {
+	if !orm.lastInsertID() {
 		return -1, nil
 	}
 
@@ -1004,7 +1121,7 @@ func (orm *ORM) UpsertMany(data []interface{}, trans bool) (affected int64, inse
 		}()
 
 		for _, sqlObj := range sqlArr {
-			execContext, err := tx.ExecContext(orm.ctx, sqlObj)
+			execContext, err := orm.execContext(tx, sqlObj)
 			if err != nil {
 				panic(err)
 			}
@@ -1015,7 +1132,7 @@ func (orm *ORM) UpsertMany(data []interface{}, trans bool) (affected int64, inse
 			}
 			affected += rowsAffected
 
-			if !notReadyLastInsertIDSet.Has(orm.ref.dbConf.DBType) {
+			if orm.lastInsertID() {
 				insertID, err := execContext.LastInsertId()
 				if err != nil {
 					panic(err)
@@ -1035,9 +1152,9 @@ func (orm *ORM) UpsertMany(data []interface{}, trans bool) (affected int64, inse
 		var execContext sql.Result
 		for _, sqlObj := range sqlArr {
 			if orm.tx != nil {
-				execContext, err = orm.tx.ExecContext(orm.ctx, sqlObj)
+				execContext, err = orm.execContext(orm.tx, sqlObj)
 			} else if orm.executor != nil {
-				execContext, err = orm.executor.ExecContext(orm.ctx, sqlObj)
+				execContext, err = orm.execContext(orm.executor, sqlObj)
 			} else {
 				return 0, nil, ErrClient
 			}
@@ -1045,7 +1162,7 @@ func (orm *ORM) UpsertMany(data []interface{}, trans bool) (affected int64, inse
 				panic(err)
 			}
 
-			if !notReadyLastInsertIDSet.Has(orm.ref.dbConf.DBType) {
+			if orm.lastInsertID() {
 				insertID, err := execContext.LastInsertId()
 				if err != nil {
 					panic(err)
@@ -1164,7 +1281,7 @@ func (orm *ORM) SaveMany(data []interface{}, trans bool) (affected int64, err er
 		}()
 
 		for _, sqlObj := range sqlArr {
-			execContext, err := tx.ExecContext(orm.ctx, sqlObj)
+			execContext, err := orm.execContext(tx, sqlObj)
 			if err != nil {
 				panic(err)
 			}
@@ -1187,9 +1304,9 @@ func (orm *ORM) SaveMany(data []interface{}, trans bool) (affected int64, err er
 		var execContext sql.Result
 		for _, sqlObj := range sqlArr {
 			if orm.tx != nil {
-				execContext, err = orm.tx.ExecContext(orm.ctx, sqlObj)
+				execContext, err = orm.execContext(orm.tx, sqlObj)
 			} else if orm.executor != nil {
-				execContext, err = orm.executor.ExecContext(orm.ctx, sqlObj)
+				execContext, err = orm.execContext(orm.executor, sqlObj)
 			} else {
 				return 0, ErrClient
 			}
@@ -1221,45 +1338,11 @@ func (orm *ORM) UpdateByWhere(update map[string]interface{}, where Where) (affec
 
 	affected, err = 0, nil
 
-	dbCore := orm.ref.getDBConf()
-
-	updateSQL := "update " + dbCore.EscStart + "%s" + dbCore.EscEnd + " set %s"
-
-	if update == nil {
-		return 0, fmt.Errorf("update data is nil")
-	}
-
-	if len(where) > 0 {
-		q := BaseQuery{
-			PrivateKey: orm.primaryKey,
-			RefConf:    orm.ref,
-			TableName:  orm.tableName,
-			Where:      where,
-		}
-		updateSQL += " where " + q.GetWhere()
-	}
-
-	updateSet := []string{}
-	for k, v := range update {
-		var val string
-		if k[0] == '#' {
-			k = k[1:]
-			val = fmt.Sprintf("%v", v)
-		} else {
-			value, timeEmpty := orm.formatValue(v)
-			if timeEmpty {
-				val = "null"
-			} else {
-				val = value
-			}
-		}
-
-		updateSet = append(updateSet, fmt.Sprintf("%s%s%s=%s",
-			dbCore.EscStart, k, dbCore.EscEnd, val))
+	updateSQL, err := orm.formatUpdateByWhereSQL(update, where)
+	if err != nil {
+		return 0, err
 	}
 
-	updateSQL = fmt.Sprintf(updateSQL, orm.tableName, util.JoinArr(updateSet, ","))
-
 	var ret sql.Result
 	var sqlDB db.BaseExecutor = orm.tx
 	if sqlDB == nil {
@@ -1267,7 +1350,7 @@ func (orm *ORM) UpdateByWhere(update map[string]interface{}, where Where) (affec
 	}
 
 	if sqlDB != nil {
-		ret, err = sqlDB.ExecContext(orm.ctx, updateSQL)
+		ret, err = orm.execContext(sqlDB, updateSQL)
 	} else {
 		return 0, ErrClient
 	}
@@ -1321,7 +1404,7 @@ func (orm *ORM) UpdateMany(data []interface{}, trans bool) (affected int64, err
 		}()
 
 		for _, sqlObj := range sqlArr {
-			execContext, err := tx.ExecContext(orm.ctx, sqlObj)
+			execContext, err := orm.execContext(tx, sqlObj)
 			if err != nil {
 				panic(err)
 			}
@@ -1344,9 +1427,9 @@ func (orm *ORM) UpdateMany(data []interface{}, trans bool) (affected int64, err
 		var execContext sql.Result
 		for _, sqlObj := range sqlArr {
 			if orm.tx != nil {
-				execContext, err = orm.tx.ExecContext(orm.ctx, sqlObj)
+				execContext, err = orm.execContext(orm.tx, sqlObj)
 			} else if orm.executor != nil {
-				execContext, err = orm.executor.ExecContext(orm.ctx, sqlObj)
+				execContext, err = orm.execContext(orm.executor, sqlObj)
 			} else {
 				return 0, ErrClient
 			}
@@ -1389,7 +1472,7 @@ func (orm *ORM) UpdateOne(data interface{}) (affected int64, err error) {
 	}
 
 	if sqlDB != nil {
-		execContext, err = sqlDB.ExecContext(orm.ctx, updateSQL)
+		execContext, err = orm.execContext(sqlDB, updateSQL)
 	} else {
 		return 0, ErrClient
 	}
@@ -1425,7 +1508,7 @@ func (orm *ORM) ReplaceOne(data interface{}) (affected int64, err error) {
 	}
 
 	if sqlDB != nil {
-		execContext, err = sqlDB.ExecContext(orm.ctx, replaceSQL)
+		execContext, err = orm.execContext(sqlDB, replaceSQL)
 	} else {
 		return 0, ErrClient
 	}
@@ -1433,7 +1516,7 @@ func (orm *ORM) ReplaceOne(data interface{}) (affected int64, err error) {
 	if err != nil {
 		return 0, err
 	}
-	if notReadyLastInsertIDSet.Has(orm.ref.dbConf.DBType) {
+	if !orm.lastInsertID() {
 		return -1, nil
 	}
 	return execContext.LastInsertId()
@@ -1481,12 +1564,12 @@ func (orm *ORM) ReplaceMany(data []interface{}, trans bool) (affected int64, ins
 		}()
 
 		for _, sqlObj := range sqlArr {
-			execContext, err := tx.ExecContext(orm.ctx, sqlObj)
+			execContext, err := orm.execContext(tx, sqlObj)
 			if err != nil {
 				panic(err)
 			}
 
-			if !notReadyLastInsertIDSet.Has(orm.ref.dbConf.DBType) {
+			if orm.lastInsertID() {
 				insertID, err := execContext.LastInsertId()
 				if err != nil {
 					panic(err)
@@ -1512,9 +1595,9 @@ func (orm *ORM) ReplaceMany(data []interface{}, trans bool) (affected int64, ins
 		var execContext sql.Result
 		for _, sqlObj := range sqlArr {
 			if orm.tx != nil {
-				execContext, err = orm.tx.ExecContext(orm.ctx, sqlObj)
+				execContext, err = orm.execContext(orm.tx, sqlObj)
 			} else if orm.executor != nil {
-				execContext, err = orm.executor.ExecContext(orm.ctx, sqlObj)
+				execContext, err = orm.execContext(orm.executor, sqlObj)
 			} else {
 				return 0, nil, ErrClient
 			}
@@ -1522,7 +1605,7 @@ func (orm *ORM) ReplaceMany(data []interface{}, trans bool) (affected int64, ins
 				panic(err)
 			}
 
-			if !notReadyLastInsertIDSet.Has(orm.ref.dbConf.DBType) {
+			if orm.lastInsertID() {
 				insertID, err := execContext.LastInsertId()
 				if err != nil {
 					panic(err)
@@ -1611,23 +1694,9 @@ func (orm *ORM) DeleteByWhere(where map[string]interface{}) (affected int64, err
 
 	affected, err = 0, nil
 
-	dbCore := orm.ref.getDBConf()
-	var delSQL strings.Builder
-	delSQL.Grow(100)
-	delSQL.WriteString("delete from ")
-	delSQL.WriteString(dbCore.EscStart)
-	delSQL.WriteString(orm.tableName)
-	delSQL.WriteString(dbCore.EscEnd)
-
-	if len(where) > 0 {
-		q := BaseQuery{
-			PrivateKey: orm.primaryKey,
-			RefConf:    orm.ref,
-			TableName:  orm.tableName,
-			Where:      where,
-		}
-		delSQL.WriteString(" where ")
-		delSQL.WriteString(q.GetWhere())
+	delSQL, err := orm.formatDeleteByWhereSQL(where)
+	if err != nil {
+		return 0, err
 	}
 
 	var ret sql.Result
@@ -1637,7 +1706,7 @@ func (orm *ORM) DeleteByWhere(where map[string]interface{}) (affected int64, err
 	}
 
 	if sqlDB != nil {
-		ret, err = sqlDB.ExecContext(orm.ctx, delSQL.String())
+		ret, err = orm.execContext(sqlDB, delSQL)
 	} else {
 		return 0, ErrClient
 	}
@@ -0,0 +1,151 @@
+package orm
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/assembly-hub/db"
+)
+
+// 聚合查询：Sum Avg Min Max Aggregate，使用当前的条件与关联，忽略排序、分页与分组，执行后按 KeepQuery 清除查询条件（与 ToData 一致）；
+// 集合运算（Union 等）的查询返回 ErrSetQuery
+
+// Sum 字段求和，字段规则与 Select 一致（支持 tag、json 路径、日期函数），dest 为接收结果的指针，
+// 如：var total int64; tb.Sum("amount", &total)，类型转换与 Pluck 一致；没有数据（NULL）时为零值，使用 sql.NullInt64 等类型可以区分
+func (orm *ORM) Sum(col string, dest interface{}) error {
+	return orm.aggregateValue("sum", col, dest)
+}
+
+// Avg 字段平均值，规则与 Sum 一致
+func (orm *ORM) Avg(col string, dest interface{}) error {
+	return orm.aggregateValue("avg", col, dest)
+}
+
+// Min 字段最小值，规则与 Sum 一致，可以用于数值、日期（time.Time）与字符串字段
+func (orm *ORM) Min(col string, dest interface{}) error {
+	return orm.aggregateValue("min", col, dest)
+}
+
+// Max 字段最大值，规则与 Min 一致
+func (orm *ORM) Max(col string, dest interface{}) error {
+	return orm.aggregateValue("max", col, dest)
+}
+
+// Aggregate 多个聚合，aggs 为 别名:聚合，聚合为 函数(字段)、函数(distinct 字段) 或 函数(*)，字段规则与 Select 一致，
+// 如：{"total": "sum(amount)", "users": "count(distinct user_id)", "last": "max(tb2.created_at)"}；
+// dest 为 map 或结构体的指针，没有数据时聚合的值为 NULL（map 中为 nil，结构体使用指针字段接收）
+func (orm *ORM) Aggregate(aggs map[string]string, dest interface{}) error {
+	return orm.aggregate(aggs, func(sqlDB db.BaseExecutor, q *BaseQuery) error {
+		return toData(orm.ctx, sqlDB, q, dest, true)
+	})
+}
+
+// aggregateValue 单个聚合，结果按单值接收
+func (orm *ORM) aggregateValue(fn, col string, dest interface{}) error {
+	dataValue := reflect.ValueOf(dest)
+	if dest == nil || dataValue.Kind() != reflect.Ptr || dataValue.IsNil() {
+		return ErrTargetNotSettable
+	}
+	switch dataValue.Elem().Kind() {
+	case reflect.Map, reflect.Slice, reflect.Array:
+		return ErrTargetNotSettable
+	}
+
+	return orm.aggregate(map[string]string{"v": fn + "(" + col + ")"}, func(sqlDB db.BaseExecutor, q *BaseQuery) error {
+		dataValue = dataValue.Elem()
+		ret, err := toFirstSingleData(orm.ctx, sqlDB, q, dataValue.Type())
+		if err != nil {
+			return err
+		}
+
+		if ret != nil {
+			dataValue.Set(*ret)
+		}
+		return nil
+	})
+}
+
+// aggregate 聚合查询，scan 接收结果
+func (orm *ORM) aggregate(aggs map[string]string, scan func(sqlDB db.BaseExecutor, q *BaseQuery) error) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			switch p := p.(type) {
+			case error:
+				err = p
+			default:
+				err = fmt.Errorf("%v", p)
+			}
+		}
+	}()
+
+	if orm.customSQL != "" {
+		return ErrCustomSQL
+	}
+
+	if len(orm.Q.SetQuery) > 0 {
+		return ErrSetQuery
+	}
+
+	if len(aggs) <= 0 {
+		return fmt.Errorf("aggregate cannot be empty")
+	}
+
+	if !orm.keepQuery {
+		defer func() {
+			orm.ClearCache()
+		}()
+	}
+
+	q := orm.cond(true)
+	q.SelectRaw = true
+	q.Select = nil
+	q.SelectExpr = make([]*RawSQL, 0, len(aggs))
+	for _, alias := range sortedKeys(aggs) {
+		q.SelectExpr = append(q.SelectExpr, aggregateExpr(alias, aggs[alias]))
+	}
+	q.Distinct = false
+	q.Order = nil
+	q.SearchOrder = nil
+	q.ValuesOrder = nil
+	q.OrderExpr = nil
+	q.GroupBy = nil
+	q.GroupByExpr = nil
+	q.Grouping = nil
+	q.Having = nil
+	q.Limit = nil
+	q.BindParams = orm.bindParams
+
+	var sqlDB db.BaseExecutor = orm.tx
+	if sqlDB == nil {
+		sqlDB = orm.executor
+	}
+	return scan(sqlDB, q)
+}
+
+// aggregateExpr 聚合表达式：函数(字段) 函数(distinct 字段) 函数(*)
+func aggregateExpr(alias, agg string) *RawSQL {
+	if !isIdentName(alias) {
+		panic(fmt.Sprintf("aggregate alias[%s] is invalid", alias))
+	}
+
+	i := strings.Index(agg, "(")
+	if i <= 0 || agg[len(agg)-1] != ')' || !isIdentName(agg[:i]) {
+		panic(fmt.Sprintf("aggregate[%s] error", agg))
+	}
+
+	fn, col := agg[:i], strings.TrimSpace(agg[i+1:len(agg)-1])
+	if col == "*" {
+		return Raw(fn + "(*)").As(alias)
+	}
+
+	distinct := ""
+	if strings.HasPrefix(strings.ToLower(col), "distinct ") {
+		distinct = "distinct "
+		col = strings.TrimSpace(col[9:])
+	}
+	if col == "" {
+		panic(fmt.Sprintf("aggregate[%s] error", agg))
+	}
+	return Raw(fn+"("+distinct+"?)", Col(col)).As(alias)
+}
@@ -0,0 +1,111 @@
+package orm
+
+import (
+	"fmt"
+)
+
+// 生成SQL及其绑定参数，不执行，便于交给日志、sqlmock、外部执行器等使用
+// 生成的SQL始终使用绑定参数，占位符与数据库类型对应，与 BindParams 设置无关
+
+// ToSQLWithArgs 生成查询SQL及绑定参数，设置了 CustomSQL 时原样返回
+func (orm *ORM) ToSQLWithArgs(flat bool) (string, []interface{}, error) {
+	return orm.cond(flat).SQLWithArgs()
+}
+
+// BuildCount 生成 Count 对应的SQL及绑定参数
+func (orm *ORM) BuildCount() (string, []interface{}, error) {
+	if orm.customSQL != "" {
+		return "", nil, ErrCustomSQL
+	}
+
+	q := orm.cond(false)
+	q.Limit = []uint{1}
+	return q.CountWithArgs()
+}
+
+// BuildInsert 生成 InsertOne 对应的SQL及绑定参数
+func (orm *ORM) BuildInsert(data interface{}) (string, []interface{}, error) {
+	return orm.buildStatement(func(dao *ORM) (string, error) {
+		return dao.formatInsertSQL(data)
+	})
+}
+
+// BuildInsertMany 生成 InsertManySameClos 单个批次对应的SQL及绑定参数
+func (orm *ORM) BuildInsertMany(data []interface{}, cols []string) (string, []interface{}, error) {
+	return orm.buildStatement(func(dao *ORM) (string, error) {
+		return dao.formatInsertManySQL(data, cols)
+	})
+}
+
+// BuildUpdate 生成 UpdateOne 对应的SQL及绑定参数，需要主键
+func (orm *ORM) BuildUpdate(data interface{}) (string, []interface{}, error) {
+	return orm.buildStatement(func(dao *ORM) (string, error) {
+		return dao.formatUpdateSQL(data)
+	})
+}
+
+// BuildUpdateByWhere 生成 UpdateByWhere 对应的SQL及绑定参数
+func (orm *ORM) BuildUpdateByWhere(update map[string]interface{}, where Where) (string, []interface{}, error) {
+	return orm.buildStatement(func(dao *ORM) (string, error) {
+		return dao.formatUpdateByWhereSQL(update, where)
+	})
+}
+
+// BuildUpsert 生成 UpsertOne 对应的SQL及绑定参数
+func (orm *ORM) BuildUpsert(data interface{}) (string, []interface{}, error) {
+	return orm.buildStatement(func(dao *ORM) (string, error) {
+		return dao.formatUpsertSQL(data)
+	})
+}
+
+// BuildUpsertMany 生成 UpsertManySameClos 单个批次对应的SQL及绑定参数
+func (orm *ORM) BuildUpsertMany(data []interface{}, cols []string) (string, []interface{}, error) {
+	return orm.buildStatement(func(dao *ORM) (string, error) {
+		return dao.formatUpsertManySQL(data, cols)
+	})
+}
+
+// BuildReplace 生成 ReplaceOne 对应的SQL及绑定参数
+func (orm *ORM) BuildReplace(data interface{}) (string, []interface{}, error) {
+	return orm.buildStatement(func(dao *ORM) (string, error) {
+		return dao.formatReplaceSQL(data)
+	})
+}
+
+// BuildReplaceMany 生成 ReplaceManySameClos 单个批次对应的SQL及绑定参数
+func (orm *ORM) BuildReplaceMany(data []interface{}, cols []string) (string, []interface{}, error) {
+	return orm.buildStatement(func(dao *ORM) (string, error) {
+		return dao.formatReplaceManySQL(data, cols)
+	})
+}
+
+// BuildDelete 生成 DeleteByWhere 对应的SQL及绑定参数
+func (orm *ORM) BuildDelete(where Where) (string, []interface{}, error) {
+	return orm.buildStatement(func(dao *ORM) (string, error) {
+		return dao.formatDeleteByWhereSQL(where)
+	})
+}
+
+// buildStatement 以绑定参数模式生成SQL，不修改当前 orm 的配置
+func (orm *ORM) buildStatement(format func(dao *ORM) (string, error)) (sqlStr string, args []interface{}, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			switch p := p.(type) {
+			case error:
+				err = p
+			default:
+				err = fmt.Errorf("%v", p)
+			}
+		}
+	}()
+
+	dao := *orm
+	dao.bindParams = true
+	sqlStr, err = format(&dao)
+	if err != nil {
+		return "", nil, err
+	}
+
+	sqlStr, args = dao.bindSQL(sqlStr)
+	return sqlStr, args, nil
+}
@@ -52,7 +52,8 @@ func toFetchDataMap(ctx context.Context, sqlDB db.BaseExecutor, q *BaseQuery, fl
 	var rows db.Rows
 	var err error
 	if sqlDB != nil {
-		rows, err = sqlDB.QueryContext(ctx, q.SQL())
+		sqlStr, args := q.build()
+		rows, err = queryContext(ctx, sqlDB, sqlStr, args)
 	} else {
 		return ErrClient
 	}
@@ -148,7 +149,8 @@ func toFetchStruct(ctx context.Context, sqlDB db.BaseExecutor, q *BaseQuery, fla
 	var rows db.Rows
 	var err error
 	if sqlDB != nil {
-		rows, err = sqlDB.QueryContext(ctx, q.SQL())
+		sqlStr, args := q.build()
+		rows, err = queryContext(ctx, sqlDB, sqlStr, args)
 	} else {
 		return ErrClient
 	}
@@ -269,7 +271,8 @@ func toFetchSingleData(ctx context.Context, sqlDB db.BaseExecutor, q *BaseQuery,
 
 	var rows db.Rows
 	if sqlDB != nil {
-		rows, err = sqlDB.QueryContext(ctx, q.SQL())
+		sqlStr, args := q.build()
+		rows, err = queryContext(ctx, sqlDB, sqlStr, args)
 	} else {
 		return ErrClient
 	}
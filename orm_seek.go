@@ -0,0 +1,456 @@
+package orm
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/assembly-hub/db"
+)
+
+// 游标分页（keyset）：按 Order 的字段与上一页最后（或第一）条数据的值查询，不使用 offset 与 count，
+// 条件为 (k1,k2) > (?,?)，数据库不支持行值比较或排序方向不一致时展开为 (k1>?) or (k1=? and k2>?)
+
+// Cursor 游标，SeekPage 返回的不透明字符串，包含签名，被修改或与查询的排序不一致时返回 ErrCursor
+type Cursor string
+
+type SeekPaging struct {
+	PageSize int    `json:"page_size"` //每页条数
+	Next     Cursor `json:"next"`      //下一页的游标，没有下一页时为空
+	Prev     Cursor `json:"prev"`      //上一页的游标，没有上一页时为空
+}
+
+// cursorSecret 游标签名的密钥，默认为进程启动时生成的随机值
+var cursorSecret = randomSecret()
+
+// SetCursorSecret 设置游标签名的密钥，多个进程之间使用游标时需要设置相同的密钥，程序启动时设置
+func SetCursorSecret(secret []byte) {
+	if len(secret) <= 0 {
+		panic("cursor secret cannot be empty")
+	}
+	cursorSecret = append([]byte(nil), secret...)
+}
+
+func randomSecret() []byte {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// seekKey 游标分页的排序字段，name 为结果中的字段名（跨表字段为 tag_字段）
+type seekKey struct {
+	col  string
+	desc bool
+	name string
+}
+
+// cursorData 游标的内容，Values 为 [类型, 值]
+type cursorData struct {
+	Prev   bool        `json:"p,omitempty"`
+	Values [][2]string `json:"v"`
+}
+
+// SeekPage 游标分页，result 为 map 或结构体的切片的指针（与 ToData flat=true 一致），after 为空时查询第一页，
+// 否则为上次返回的 Next 或 Prev；排序为 Order 的字段（支持 tag 关联字段，不支持 # 原始字段、函数、json 路径与日期函数），
+// 主键不在排序中时追加主键（方向与最后一个排序字段一致）保证顺序唯一，PrimaryKey("") 不追加；
+// 排序字段会加入 Select，结果中需要有对应的字段（结构体的字段名为 tag_字段），值不能为 NULL
+func (orm *ORM) SeekPage(result interface{}, after Cursor, size uint) (sp *SeekPaging, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			switch p := p.(type) {
+			case error:
+				err = p
+			default:
+				err = fmt.Errorf("%v", p)
+			}
+		}
+	}()
+
+	dataValue := reflect.ValueOf(result)
+	if result == nil || dataValue.Kind() != reflect.Ptr || dataValue.IsNil() || dataValue.Elem().Kind() != reflect.Slice {
+		return nil, ErrTargetNotSettable
+	}
+
+	elemType := dataValue.Elem().Type().Elem()
+	if elemType.Kind() == reflect.Ptr {
+		elemType = elemType.Elem()
+	}
+	var structData *tableStructData
+	switch elemType.Kind() {
+	case reflect.Map:
+		if elemType.Key().Kind() != reflect.String {
+			return nil, ErrMapKeyType
+		}
+	case reflect.Struct:
+		structData = orm.ref.getTableCacheByTp(elemType)
+		if structData == nil {
+			structData, err = computeStructData(elemType)
+			if err != nil {
+				return nil, err
+			}
+		}
+	default:
+		return nil, ErrTargetNotSettable
+	}
+
+	if orm.customSQL != "" {
+		return nil, ErrCustomSQL
+	}
+
+	if len(orm.Q.SetQuery) > 0 || len(orm.Q.SearchOrder) > 0 || len(orm.Q.ValuesOrder) > 0 || len(orm.Q.OrderExpr) > 0 {
+		return nil, fmt.Errorf("seek page does not support set query, OrderBySearch, OrderByValues and OrderExpr")
+	}
+
+	if size == 0 {
+		return nil, fmt.Errorf("page size need gt 0")
+	}
+
+	if !orm.keepQuery {
+		defer func() {
+			orm.ClearCache()
+		}()
+	}
+
+	keys := orm.seekKeys()
+	sign := orm.seekSign(keys)
+
+	var data *cursorData
+	if after != "" {
+		data, err = decodeCursor(after, sign, len(keys))
+		if err != nil {
+			return nil, err
+		}
+	}
+	prev := data != nil && data.Prev
+
+	q := orm.cond(true)
+	q.BindParams = orm.bindParams
+	q.Order = make(Order, 0, len(keys))
+	for _, k := range keys {
+		if k.desc != prev {
+			q.Order = append(q.Order, "-"+k.col)
+		} else {
+			q.Order = append(q.Order, k.col)
+		}
+	}
+	if len(q.Select) > 0 {
+		sel := make(Select, 0, len(q.Select)+len(keys))
+		sel = append(sel, q.Select...)
+		for _, k := range keys {
+			sel = append(sel, k.col)
+		}
+		q.Select = sel
+	}
+	if data != nil {
+		values, err := cursorValues(data.Values)
+		if err != nil {
+			return nil, err
+		}
+
+		where := make(Where, len(q.Where)+1)
+		for k, v := range q.Where {
+			where[k] = v
+		}
+		seek := seekCond(orm.ref.getDBConf().Dialect, keys, values, prev)
+		if raw, ok := where["$raw"]; ok {
+			list := rawList(raw)
+			where["$raw"] = append(list[:len(list):len(list)], seek)
+		} else {
+			where["$raw"] = seek
+		}
+		q.Where = where
+	}
+	q.Limit = []uint{size + 1}
+
+	var sqlDB db.BaseExecutor = orm.tx
+	if sqlDB == nil {
+		sqlDB = orm.executor
+	}
+
+	err = toData(orm.ctx, sqlDB, q, result, true)
+	if err != nil {
+		return nil, err
+	}
+
+	list := dataValue.Elem()
+	more := list.Len() > int(size)
+	if more {
+		list.Set(list.Slice(0, int(size)))
+	}
+	if prev {
+		reverseSlice(list)
+	}
+
+	sp = &SeekPaging{PageSize: int(size)}
+	n := list.Len()
+	if n <= 0 {
+		return sp, nil
+	}
+
+	if more || prev {
+		sp.Next, err = encodeCursor(seekRowValues(list.Index(n-1), keys, structData), false, sign)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if (prev && more) || (!prev && data != nil) {
+		sp.Prev, err = encodeCursor(seekRowValues(list.Index(0), keys, structData), true, sign)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return sp, nil
+}
+
+// seekKeys Order 的字段，主键不在其中时追加主键
+func (orm *ORM) seekKeys() []seekKey {
+	keys := make([]seekKey, 0, len(orm.Q.Order)+1)
+	hasPK := false
+	for _, col := range orm.Q.Order {
+		k := seekKey{col: col}
+		if col != "" && (col[0] == '-' || col[0] == '+') {
+			k.desc = col[0] == '-'
+			k.col = col[1:]
+		}
+		if k.col == "" || strings.ContainsAny(k.col, "#|() ") || strings.Contains(k.col, "->") {
+			panic(fmt.Sprintf("seek order column[%s] is invalid", col))
+		}
+
+		k.name = strings.ReplaceAll(k.col, ".", orm.selectColLinkStr)
+		if k.col == orm.primaryKey {
+			hasPK = true
+		}
+		keys = append(keys, k)
+	}
+
+	if !hasPK && orm.primaryKey != "" {
+		k := seekKey{col: orm.primaryKey, name: orm.primaryKey}
+		if len(keys) > 0 {
+			k.desc = keys[len(keys)-1].desc
+		}
+		keys = append(keys, k)
+	}
+
+	if len(keys) <= 0 {
+		panic("seek page requires order columns or primary key")
+	}
+	return keys
+}
+
+// seekSign 游标对应的查询：表名与排序，与游标的内容一起签名
+func (orm *ORM) seekSign(keys []seekKey) string {
+	var sign strings.Builder
+	sign.Grow(len(orm.tableName) + len(keys)*20)
+	sign.WriteString(orm.tableName)
+	for _, k := range keys {
+		sign.WriteByte('|')
+		if k.desc {
+			sign.WriteByte('-')
+		}
+		sign.WriteString(k.col)
+	}
+	return sign.String()
+}
+
+// seekCond 游标条件，降序（prev 时升序）的字段使用 <
+func seekCond(dialect Dialect, keys []seekKey, values []interface{}, prev bool) *RawSQL {
+	ops := make([]string, len(keys))
+	same := true
+	for i, k := range keys {
+		ops[i] = ">"
+		if k.desc != prev {
+			ops[i] = "<"
+		}
+		if ops[i] != ops[0] {
+			same = false
+		}
+	}
+
+	if same {
+		marks := make([]string, len(keys))
+		for i := range marks {
+			marks[i] = "?"
+		}
+		if sql, err := dialect.RowCompare(marks, marks, ops[0]); err == nil {
+			args := make([]interface{}, 0, len(keys)*2)
+			for _, k := range keys {
+				args = append(args, Col(k.col))
+			}
+			return Raw(sql, append(args, values...)...)
+		}
+	}
+
+	// (k1>?) or (k1=? and k2>?) or ...
+	var sql strings.Builder
+	sql.Grow(len(keys) * len(keys) * 10)
+	args := make([]interface{}, 0, len(keys)*(len(keys)+1))
+	for i, k := range keys {
+		if i > 0 {
+			sql.WriteString(" or ")
+		}
+		sql.WriteByte('(')
+		for j := 0; j < i; j++ {
+			sql.WriteString("?=? and ")
+			args = append(args, Col(keys[j].col), values[j])
+		}
+		sql.WriteString("?" + ops[i] + "?")
+		sql.WriteByte(')')
+		args = append(args, Col(k.col), values[i])
+	}
+	return Raw(sql.String(), args...)
+}
+
+// seekRowValues 结果中排序字段的值
+func seekRowValues(row reflect.Value, keys []seekKey, structData *tableStructData) []interface{} {
+	for row.Kind() == reflect.Ptr || row.Kind() == reflect.Interface {
+		row = row.Elem()
+	}
+
+	values := make([]interface{}, len(keys))
+	for i, k := range keys {
+		var v reflect.Value
+		if row.Kind() == reflect.Map {
+			v = row.MapIndex(reflect.ValueOf(k.name).Convert(row.Type().Key()))
+		} else if f, ok := structData.FieldMap[k.name]; ok && !f.Ref {
+			v = row.Field(f.Index)
+		}
+		if !v.IsValid() {
+			panic(fmt.Sprintf("seek column[%s] is not in the result", k.name))
+		}
+		values[i] = v.Interface()
+	}
+	return values
+}
+
+func reverseSlice(list reflect.Value) {
+	swap := reflect.Swapper(list.Interface())
+	for i, j := 0, list.Len()-1; i < j; i, j = i+1, j-1 {
+		swap(i, j)
+	}
+}
+
+// encodeCursor base64(json).base64(hmac)
+func encodeCursor(values []interface{}, prev bool, sign string) (Cursor, error) {
+	data := cursorData{Prev: prev, Values: make([][2]string, len(values))}
+	for i, v := range values {
+		cv, err := cursorValue(v)
+		if err != nil {
+			return "", err
+		}
+		data.Values[i] = cv
+	}
+
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+	return Cursor(base64.RawURLEncoding.EncodeToString(payload) + "." +
+		base64.RawURLEncoding.EncodeToString(cursorMAC(payload, sign))), nil
+}
+
+func decodeCursor(c Cursor, sign string, n int) (*cursorData, error) {
+	i := strings.IndexByte(string(c), '.')
+	if i <= 0 {
+		return nil, ErrCursor
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(string(c[:i]))
+	if err != nil {
+		return nil, ErrCursor
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(string(c[i+1:]))
+	if err != nil || !hmac.Equal(mac, cursorMAC(payload, sign)) {
+		return nil, ErrCursor
+	}
+
+	data := &cursorData{}
+	if json.Unmarshal(payload, data) != nil || len(data.Values) != n {
+		return nil, ErrCursor
+	}
+	return data, nil
+}
+
+func cursorMAC(payload []byte, sign string) []byte {
+	h := hmac.New(sha256.New, cursorSecret)
+	h.Write(payload)
+	h.Write([]byte{0})
+	h.Write([]byte(sign))
+	return h.Sum(nil)
+}
+
+// cursorValue 字段的值：[类型, 值]，类型为 i（整数）u（无符号整数）f（浮点数）s（字符串）b（布尔）t（时间）
+func cursorValue(v interface{}) ([2]string, error) {
+	if valuer, ok := v.(driver.Valuer); ok {
+		val, err := valuer.Value()
+		if err != nil {
+			return [2]string{}, err
+		}
+		v = val
+	}
+
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if !rv.IsValid() || rv.Kind() == reflect.Ptr {
+		return [2]string{}, fmt.Errorf("seek column value cannot be null")
+	}
+
+	switch val := rv.Interface().(type) {
+	case time.Time:
+		return [2]string{"t", val.Format(time.RFC3339Nano)}, nil
+	case []byte:
+		return [2]string{"s", string(val)}, nil
+	}
+
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return [2]string{"i", strconv.FormatInt(rv.Int(), 10)}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return [2]string{"u", strconv.FormatUint(rv.Uint(), 10)}, nil
+	case reflect.Float32, reflect.Float64:
+		return [2]string{"f", strconv.FormatFloat(rv.Float(), 'g', -1, 64)}, nil
+	case reflect.String:
+		return [2]string{"s", rv.String()}, nil
+	case reflect.Bool:
+		return [2]string{"b", strconv.FormatBool(rv.Bool())}, nil
+	}
+	return [2]string{}, fmt.Errorf("seek column value type[%s] is not supported", rv.Type())
+}
+
+func cursorValues(list [][2]string) ([]interface{}, error) {
+	values := make([]interface{}, len(list))
+	for i, cv := range list {
+		var err error
+		switch cv[0] {
+		case "i":
+			values[i], err = strconv.ParseInt(cv[1], 10, 64)
+		case "u":
+			values[i], err = strconv.ParseUint(cv[1], 10, 64)
+		case "f":
+			values[i], err = strconv.ParseFloat(cv[1], 64)
+		case "s":
+			values[i] = cv[1]
+		case "b":
+			values[i], err = strconv.ParseBool(cv[1])
+		case "t":
+			values[i], err = time.Parse(time.RFC3339Nano, cv[1])
+		default:
+			return nil, ErrCursor
+		}
+		if err != nil {
+			return nil, ErrCursor
+		}
+	}
+	return values, nil
+}
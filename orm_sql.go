@@ -7,13 +7,16 @@ import (
 	"strings"
 	"time"
 
-	"github.com/assembly-hub/basics/set"
 	"github.com/assembly-hub/basics/util"
-	"github.com/assembly-hub/orm/dbtype"
+	"github.com/assembly-hub/db"
 )
 
 func (orm *ORM) formatInsertSQL(data interface{}) (string, error) {
-	return orm.innerInsertOrReplaceSQL("insert", data)
+	w, err := orm.writeParts(data)
+	if err != nil {
+		return "", err
+	}
+	return orm.ref.dbConf.Dialect.Insert(w)
 }
 
 // 需要主键
@@ -22,71 +25,219 @@ func (orm *ORM) formatUpdateSQL(data interface{}) (string, error) {
 }
 
 func (orm *ORM) formatInsertManySQL(dataList []interface{}, cols []string) (string, error) {
-	switch orm.ref.dbConf.DBType {
-	case dbtype.MySQL, dbtype.MariaDB, dbtype.SQLServer, dbtype.SQLite2,
-		dbtype.SQLite3, dbtype.Postgres, dbtype.OpenGauss, dbtype.ClickHouse:
-		return orm.innerInsertOrReplaceManySQL("insert", dataList, cols)
-	case dbtype.Oracle:
-		return orm.oracleInsertManySQL(dataList, cols)
-	default:
-		return "", ErrDBType
+	w, err := orm.writeManyParts(dataList, cols)
+	if err != nil {
+		return "", err
 	}
+	return orm.ref.dbConf.Dialect.Insert(w)
 }
 
 func (orm *ORM) formatReplaceSQL(data interface{}) (string, error) {
-	switch orm.ref.dbConf.DBType {
-	case dbtype.MySQL, dbtype.MariaDB, dbtype.SQLite2, dbtype.SQLite3:
-		return orm.innerInsertOrReplaceSQL("replace", data)
-	case dbtype.Oracle, dbtype.SQLServer, dbtype.Postgres, dbtype.OpenGauss:
-		return "", fmt.Errorf("当前数据库不支持Replace方法，请使用Upsert方法")
-	default:
-		return "", ErrDBType
+	w, err := orm.writeParts(data)
+	if err != nil {
+		return "", err
 	}
+	return orm.ref.dbConf.Dialect.Replace(w)
 }
 
 func (orm *ORM) formatReplaceManySQL(dataList []interface{}, cols []string) (string, error) {
-	switch orm.ref.dbConf.DBType {
-	case dbtype.MySQL, dbtype.MariaDB, dbtype.SQLite2, dbtype.SQLite3:
-		return orm.innerInsertOrReplaceManySQL("replace", dataList, cols)
-	case dbtype.Oracle, dbtype.SQLServer, dbtype.Postgres, dbtype.OpenGauss:
-		return "", fmt.Errorf("当前数据库不支持Replace方法，请使用Upsert方法")
-	default:
-		return "", ErrDBType
+	w, err := orm.writeManyParts(dataList, cols)
+	if err != nil {
+		return "", err
 	}
+	return orm.ref.dbConf.Dialect.Replace(w)
 }
 
 func (orm *ORM) formatUpsertSQL(data interface{}) (string, error) {
-	switch orm.ref.dbConf.DBType {
-	case dbtype.MySQL, dbtype.MariaDB:
-		return orm.mysqlUpsertSQL(data)
-	case dbtype.OpenGauss:
-		return orm.gaussUpsertSQL(data)
-	case dbtype.SQLite2, dbtype.SQLite3, dbtype.Postgres:
-		return orm.sqliteUpsertSQL(data)
-	case dbtype.SQLServer:
-		return orm.sqlserverUpsertSQL(data)
-	case dbtype.Oracle:
-		return orm.oracleUpsertSQL(data)
-	default:
-		return "", ErrDBType
+	w, err := orm.writeParts(data)
+	if err != nil {
+		return "", err
 	}
+	return orm.ref.dbConf.Dialect.Upsert(w)
 }
 
 func (orm *ORM) formatUpsertManySQL(dataList []interface{}, cols []string) (string, error) {
-	switch orm.ref.dbConf.DBType {
-	case dbtype.MySQL, dbtype.MariaDB:
-		return orm.mysqlUpsertManySQL(dataList, cols)
-	case dbtype.OpenGauss:
-		return orm.gaussUpsertManySQL(dataList, cols)
-	case dbtype.SQLite2, dbtype.SQLite3, dbtype.Postgres:
-		return orm.sqliteUpsertManySQL(dataList, cols)
-	case dbtype.SQLServer:
-		return orm.sqlserverUpsertManySQL(dataList, cols)
-	case dbtype.Oracle:
-		return orm.oracleUpsertManySQL(dataList, cols)
+	w, err := orm.writeManyParts(dataList, cols)
+	if err != nil {
+		return "", err
+	}
+	return orm.ref.dbConf.Dialect.Upsert(w)
+}
+
+// writeParts 单条数据的写入数据，主键为空或时间为空的字段不写入
+func (orm *ORM) writeParts(data interface{}) (*WriteParts, error) {
+	if data == nil {
+		return nil, fmt.Errorf("insert data is nil")
+	}
+
+	typeErrStr := "type of data is map[string]interface{} or *struct or struct"
+	var cols []string
+	var values []string
+
+	switch data := data.(type) {
+	case map[string]interface{}:
+		cols = make([]string, 0, len(data))
+		values = make([]string, 0, len(data))
+		for _, k := range sortedKeys(data) {
+			v := data[k]
+			if k[0] == '#' {
+				k = k[1:]
+				err := globalVerifyObj.VerifyFieldName(k)
+				if err != nil {
+					return nil, err
+				}
+
+				cols = append(cols, k)
+				values = append(values, util.Any2String(v))
+				continue
+			}
+
+			err := globalVerifyObj.VerifyFieldName(k)
+			if err != nil {
+				return nil, err
+			}
+
+			val, timeEmpty := orm.formatValue(v)
+			if k == orm.primaryKey && emptyPK(val) {
+				continue
+			}
+			if timeEmpty {
+				continue
+			}
+
+			cols = append(cols, k)
+			values = append(values, val)
+		}
 	default:
-		return "", ErrDBType
+		dataValue := reflect.ValueOf(data)
+		if dataValue.Type().Kind() != reflect.Struct && dataValue.Type().Kind() != reflect.Ptr {
+			return nil, fmt.Errorf(typeErrStr)
+		}
+
+		if dataValue.Type().Kind() == reflect.Ptr {
+			dataValue = dataValue.Elem()
+		}
+
+		if dataValue.Type().Kind() != reflect.Struct {
+			return nil, fmt.Errorf(typeErrStr)
+		}
+
+		for i := 0; i < dataValue.NumField(); i++ {
+			colName := dataValue.Type().Field(i).Tag.Get("json")
+			ref := dataValue.Type().Field(i).Tag.Get("ref")
+			if ref != "" || colName == "" || !dataValue.Type().Field(i).IsExported() {
+				continue
+			}
+
+			err := globalVerifyObj.VerifyFieldName(colName)
+			if err != nil {
+				return nil, err
+			}
+
+			val, timeEmpty := orm.formatValue(dataValue.Field(i).Interface())
+			if colName == orm.primaryKey && emptyPK(val) {
+				continue
+			}
+			if timeEmpty {
+				continue
+			}
+
+			cols = append(cols, colName)
+			values = append(values, val)
+		}
+	}
+	if len(cols) <= 0 || len(values) <= 0 {
+		return nil, fmt.Errorf("sql data is empty, please check it")
+	}
+
+	return &WriteParts{
+		Table:      orm.tableName,
+		PrimaryKey: orm.primaryKey,
+		UniqueKeys: sortedSet(orm.uniqueKeys),
+		Cols:       cols,
+		Rows:       [][]string{values},
+		UnionAll:   orm.oracleMergeUnionAll,
+	}, nil
+}
+
+// writeManyParts 多条数据的写入数据，字段为 cols，缺少的值、主键为空或时间为空时写入 null
+func (orm *ORM) writeManyParts(dataList []interface{}, cols []string) (*WriteParts, error) {
+	if len(dataList) <= 0 {
+		return nil, fmt.Errorf("insert data is nil")
+	}
+
+	for _, k := range cols {
+		err := globalVerifyObj.VerifyFieldName(k)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	typeErrStr := "type of data is []map[string]interface{} or []*struct or []struct"
+
+	rows := make([][]string, 0, len(dataList))
+	for _, data := range dataList {
+		var valMap map[string]interface{}
+		switch data := data.(type) {
+		case map[string]interface{}:
+			valMap = data
+		default:
+			dataValue := reflect.ValueOf(data)
+			if dataValue.Type().Kind() != reflect.Struct && dataValue.Type().Kind() != reflect.Ptr {
+				return nil, fmt.Errorf(typeErrStr)
+			}
+
+			if dataValue.Type().Kind() == reflect.Ptr {
+				dataValue = dataValue.Elem()
+			}
+
+			if dataValue.Type().Kind() != reflect.Struct {
+				return nil, fmt.Errorf(typeErrStr)
+			}
+
+			valMap = map[string]interface{}{}
+
+			for i := 0; i < dataValue.NumField(); i++ {
+				colName := dataValue.Type().Field(i).Tag.Get("json")
+				ref := dataValue.Type().Field(i).Tag.Get("ref")
+				if ref != "" || colName == "" || !dataValue.Type().Field(i).IsExported() {
+					continue
+				}
+
+				valMap[colName] = dataValue.Field(i).Interface()
+			}
+		}
+		if len(valMap) <= 0 {
+			return nil, fmt.Errorf("sql data is empty, please check it")
+		}
+
+		row := make([]string, 0, len(cols))
+		for _, colName := range cols {
+			if v, ok := valMap[colName]; ok {
+				val, timeEmpty := orm.formatValue(v)
+				if (colName == orm.primaryKey && emptyPK(val)) || timeEmpty {
+					row = append(row, "null")
+					continue
+				}
+
+				row = append(row, val)
+			} else if v, ok = valMap["#"+colName]; ok {
+				row = append(row, util.Any2String(v))
+			} else {
+				row = append(row, "null")
+			}
+		}
+		rows = append(rows, row)
 	}
+
+	return &WriteParts{
+		Table:      orm.tableName,
+		PrimaryKey: orm.primaryKey,
+		UniqueKeys: sortedSet(orm.uniqueKeys),
+		Cols:       cols,
+		Rows:       rows,
+		UnionAll:   orm.oracleMergeUnionAll,
+	}, nil
 }
 
 func (orm *ORM) formatSaveSQL(data interface{}) (string, error) {
@@ -132,6 +283,66 @@ func (orm *ORM) formatSaveSQL(data interface{}) (string, error) {
 	return orm.formatInsertSQL(data)
 }
 
+func (orm *ORM) formatUpdateByWhereSQL(update map[string]interface{}, where Where) (string, error) {
+	dbCore := orm.ref.getDBConf()
+
+	if update == nil {
+		return "", fmt.Errorf("update data is nil")
+	}
+
+	whereSQL := ""
+	if len(where) > 0 {
+		q := BaseQuery{
+			PrivateKey: orm.primaryKey,
+			RefConf:    orm.ref,
+			TableName:  orm.tableName,
+			Where:      where,
+			BindParams: orm.bindParams,
+		}
+		whereSQL = q.where()
+	}
+
+	updateSet := []string{}
+	for _, k := range sortedKeys(update) {
+		v := update[k]
+		var val string
+		if k[0] == '#' {
+			k = k[1:]
+			val = fmt.Sprintf("%v", v)
+		} else {
+			value, timeEmpty := orm.formatValue(v)
+			if timeEmpty {
+				val = "null"
+			} else {
+				val = value
+			}
+		}
+
+		updateSet = append(updateSet, fmt.Sprintf("%s%s%s=%s",
+			dbCore.EscStart, k, dbCore.EscEnd, val))
+	}
+
+	return dbCore.Dialect.Update(quoteName(dbCore.EscStart, dbCore.EscEnd, orm.tableName),
+		util.JoinArr(updateSet, ","), whereSQL), nil
+}
+
+func (orm *ORM) formatDeleteByWhereSQL(where Where) (string, error) {
+	dbCore := orm.ref.getDBConf()
+
+	whereSQL := ""
+	if len(where) > 0 {
+		q := BaseQuery{
+			PrivateKey: orm.primaryKey,
+			RefConf:    orm.ref,
+			TableName:  orm.tableName,
+			Where:      where,
+			BindParams: orm.bindParams,
+		}
+		whereSQL = q.where()
+	}
+	return dbCore.Dialect.Delete(quoteName(dbCore.EscStart, dbCore.EscEnd, orm.tableName), whereSQL), nil
+}
+
 func (orm *ORM) formatValue(raw interface{}) (ret string, timeEmpty bool) {
 	ret, timeEmpty = "", false
 	if raw == nil {
@@ -139,6 +350,10 @@ func (orm *ORM) formatValue(raw interface{}) (ret string, timeEmpty bool) {
 		return
 	}
 
+	if orm.bindParams {
+		return orm.formatBindValue(raw)
+	}
+
 	var strBuf strings.Builder
 
 	switch raw := raw.(type) {
@@ -156,32 +371,14 @@ func (orm *ORM) formatValue(raw interface{}) (ret string, timeEmpty bool) {
 			break
 		}
 
-		ret = time2Str(raw)
-		if orm.ref.dbConf.DBType == dbtype.Oracle {
-			ret = oracleDateTime(ret, false)
-		} else {
-			strBuf.Grow(len(ret) + 2)
-			strBuf.WriteByte('\'')
-			strBuf.WriteString(ret)
-			strBuf.WriteByte('\'')
-			ret = strBuf.String()
-		}
+		ret = orm.ref.dbConf.Dialect.TimeLiteral("'" + time2Str(raw) + "'")
 	case *time.Time:
 		if raw.IsZero() {
 			timeEmpty = true
 			break
 		}
 
-		ret = time2Str(*raw)
-		if orm.ref.dbConf.DBType == dbtype.Oracle {
-			ret = oracleDateTime(ret, false)
-		} else {
-			strBuf.Grow(len(ret) + 2)
-			strBuf.WriteByte('\'')
-			strBuf.WriteString(ret)
-			strBuf.WriteByte('\'')
-			ret = strBuf.String()
-		}
+		ret = orm.ref.dbConf.Dialect.TimeLiteral("'" + time2Str(*raw) + "'")
 	case bool:
 		if raw {
 			ret = "1"
@@ -208,18 +405,59 @@ func (orm *ORM) formatValue(raw interface{}) (ret string, timeEmpty bool) {
 	return
 }
 
-func (orm *ORM) checkUK(colSet set.Set[string]) bool {
-	if orm.uniqueKeys.Empty() {
-		return false
+// formatBindValue 绑定参数模式下格式化数据，值以占位标记代替
+func (orm *ORM) formatBindValue(raw interface{}) (ret string, timeEmpty bool) {
+	switch raw := raw.(type) {
+	case time.Time:
+		if raw.IsZero() {
+			return "", true
+		}
+		return bindTime(orm.ref.dbConf.Dialect, time2Str(raw)), false
+	case *time.Time:
+		if raw.IsZero() {
+			return "", true
+		}
+		return bindTime(orm.ref.dbConf.Dialect, time2Str(raw)), false
+	}
+	return bindParam(raw), false
+}
+
+// emptyPK 主键值为空或0，绑定参数模式下解析占位标记判断
+func emptyPK(val string) bool {
+	if val == "" || val == "0" {
+		return true
 	}
 
-	uk := orm.uniqueKeys.ToList()
-	for _, k := range uk {
-		if !colSet.Has(k) {
-			return false
-		}
+	if v, n := parseBindParam(val); n == len(val) {
+		return util.Any2String(v) == "0"
+	}
+	return false
+}
+
+// lastInsertID 数据库是否支持 LastInsertId
+func (orm *ORM) lastInsertID() bool {
+	if notReadyLastInsertIDSet != nil {
+		return !notReadyLastInsertIDSet.Has(orm.ref.dbConf.DBType)
+	}
+	return orm.ref.dbConf.Dialect.LastInsertID()
+}
+
+// bindSQL 绑定参数模式下将占位标记转换为数据库占位符，返回SQL及参数
+func (orm *ORM) bindSQL(s string) (string, []interface{}) {
+	if !orm.bindParams {
+		return s, nil
 	}
-	return true
+	return resolveBind(orm.ref.dbConf.Dialect, s)
+}
+
+// execContext 执行SQL，绑定参数模式下参数随SQL一起传递
+func (orm *ORM) execContext(sqlDB db.BaseExecutor, s string) (sql.Result, error) {
+	if orm.fromQuery != nil {
+		return nil, ErrSubquery
+	}
+
+	sqlStr, args := orm.bindSQL(s)
+	return execContext(orm.ctx, sqlDB, sqlStr, args)
 }
 
 func (orm *ORM) executeSQL(sqlArr []interface{}, trans bool) (affected int64, err error) {
@@ -236,7 +474,7 @@ func (orm *ORM) executeSQL(sqlArr []interface{}, trans bool) (affected int64, er
 		}()
 
 		for _, sqlStr := range sqlArr {
-			execContext, e := tx.ExecContext(orm.ctx, sqlStr.(string))
+			execContext, e := orm.execContext(tx, sqlStr.(string))
 			if e != nil {
 				panic(e)
 			}
@@ -258,9 +496,9 @@ func (orm *ORM) executeSQL(sqlArr []interface{}, trans bool) (affected int64, er
 		var execContext sql.Result
 		for _, sqlStr := range sqlArr {
 			if orm.tx != nil {
-				execContext, err = orm.tx.ExecContext(orm.ctx, sqlStr.(string))
+				execContext, err = orm.execContext(orm.tx, sqlStr.(string))
 			} else if orm.executor != nil {
-				execContext, err = orm.executor.ExecContext(orm.ctx, sqlStr.(string))
+				execContext, err = orm.execContext(orm.executor, sqlStr.(string))
 			} else {
 				panic(ErrClient)
 			}
@@ -2,7 +2,12 @@ package orm
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -113,3 +118,1240 @@ func TestORM3(t *testing.T) {
 	fmt.Println(float64(t2.UnixMicro()-t1.UnixMicro()) / float64(c))
 	fmt.Println(orm.ToSQL(false))
 }
+
+func TestORM_BuildWithArgs(t *testing.T) {
+	sqlserverRef := NewReference(dbtype.SQLServer)
+	sqlserverRef.AddTableDef("table1", Def{})
+	sqlserverRef.AddTableDef("table2", Def2{})
+	sqlserverRef.BuildRefs()
+
+	dao := initORM()
+	dao.tableName = "table1"
+	dao.ref = sqlserverRef
+	dao.ctx = context.Background()
+
+	s, args, err := dao.BuildInsert(map[string]interface{}{
+		"name": "it's",
+	})
+	fmt.Println(s, args)
+	if err != nil || s != "insert into [table1]([name]) values(@p1)" || len(args) != 1 || args[0] != "it's" {
+		t.Fatalf("insert error: %s %v %v", s, args, err)
+	}
+
+	s, args, err = dao.BuildDelete(Where{
+		"id__in": []int{1, 2},
+	})
+	fmt.Println(s, args)
+	if err != nil || s != "delete from [table1] where [table1].[id] in (@p1,@p2)" || len(args) != 2 {
+		t.Fatalf("delete error: %s %v %v", s, args, err)
+	}
+
+	s, args, err = dao.BuildUpdate(map[string]interface{}{
+		"id":   int64(3),
+		"name": "x",
+	})
+	fmt.Println(s, args)
+	if err != nil || s != "update [table1] set [name]=@p1 where [id]=@p2" || len(args) != 2 || args[1] != int64(3) {
+		t.Fatalf("update error: %s %v %v", s, args, err)
+	}
+
+	s, args, err = dao.Query("name", "a").Limit(1).ToSQLWithArgs(true)
+	fmt.Println(s, args)
+	if err != nil || len(args) != 1 || args[0] != "a" || dao.bindParams {
+		t.Fatalf("select error: %s %v %v", s, args, err)
+	}
+}
+
+type fakeResult struct{}
+
+func (fakeResult) LastInsertId() (int64, error) { return 0, nil }
+func (fakeResult) RowsAffected() (int64, error) { return 1, nil }
+
+type fakeStmt struct{}
+
+func (fakeStmt) Close() error { return nil }
+func (fakeStmt) ExecContext(ctx context.Context, args ...any) (db2.Result, error) {
+	return fakeResult{}, nil
+}
+func (fakeStmt) QueryContext(ctx context.Context, args ...any) (db2.Rows, error) { return nil, nil }
+func (fakeStmt) QueryRowContext(ctx context.Context, args ...any) db2.Row        { return nil }
+
+// fakeRows 一行数据，Scan 的参数为 *interface{}
+type fakeRows struct {
+	cols []string
+	row  []interface{}
+	read bool
+}
+
+func (r *fakeRows) ColumnTypes() ([]db2.ColumnType, error) {
+	return make([]db2.ColumnType, len(r.cols)), nil
+}
+func (r *fakeRows) Columns() ([]string, error) { return r.cols, nil }
+func (r *fakeRows) Err() error                 { return nil }
+func (r *fakeRows) NextResultSet() bool        { return false }
+func (r *fakeRows) Close() error               { return nil }
+func (r *fakeRows) Next() bool {
+	next := !r.read
+	r.read = true
+	return next
+}
+func (r *fakeRows) Scan(dest ...any) error {
+	if len(dest) != len(r.row) {
+		return fmt.Errorf("scan %d values into %d", len(r.row), len(dest))
+	}
+	for i := range dest {
+		*dest[i].(*interface{}) = r.row[i]
+	}
+	return nil
+}
+
+type fakeExecutor struct {
+	prepared int
+	executed int
+	query    string
+	rows     db2.Rows
+}
+
+func (e *fakeExecutor) PrepareContext(ctx context.Context, query string) (db2.Stmt, error) {
+	e.prepared++
+	return fakeStmt{}, nil
+}
+func (e *fakeExecutor) ExecContext(ctx context.Context, query string, args ...any) (db2.Result, error) {
+	e.executed++
+	return fakeResult{}, nil
+}
+func (e *fakeExecutor) QueryContext(ctx context.Context, query string, args ...any) (db2.Rows, error) {
+	e.query = query
+	return e.rows, nil
+}
+func (e *fakeExecutor) QueryRowContext(ctx context.Context, query string, args ...any) db2.Row {
+	return nil
+}
+func (e *fakeExecutor) BeginTx(ctx context.Context, opts *sql.TxOptions) (db2.Tx, error) {
+	return nil, nil
+}
+
+// testRef 测试用的数据库配置：table1 为 Def，table2 为 Def2，defs 为其他的 表名, 结构体 对，version 为空时不指定版本
+func testRef(tp int, version string, defs ...interface{}) *Reference {
+	ref := NewReference(tp)
+	if version != "" {
+		ref = NewReferenceWithVersion(tp, version)
+	}
+	ref.AddTableDef("table1", Def{})
+	ref.AddTableDef("table2", Def2{})
+	for i := 0; i+1 < len(defs); i += 2 {
+		ref.AddTableDef(defs[i].(string), defs[i+1])
+	}
+	ref.BuildRefs()
+	return ref
+}
+
+func TestORM_StmtCache(t *testing.T) {
+	mysqlRef := NewReference(dbtype.MySQL)
+	mysqlRef.AddTableDef("table1", Def{})
+	mysqlRef.AddTableDef("table2", Def2{})
+	mysqlRef.BuildRefs()
+
+	executor := &fakeExecutor{}
+	EnableStmtCache(executor, 1)
+	defer DisableStmtCache(executor)
+
+	dao := NewORM(context.Background(), "table1", executor, mysqlRef).BindParams(true)
+	for i := 0; i < 3; i++ {
+		_, err := dao.DeleteByWhere(Where{"id": i})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if executor.prepared != 1 || executor.executed != 0 {
+		t.Fatalf("prepared: %d executed: %d", executor.prepared, executor.executed)
+	}
+
+	_, err := dao.DeleteByWhere(Where{"name": "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = dao.BindParams(false).DeleteByWhere(Where{"id": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if executor.prepared != 2 || executor.executed != 1 {
+		t.Fatalf("prepared: %d executed: %d", executor.prepared, executor.executed)
+	}
+}
+
+type testDialect struct {
+	BaseDialect
+}
+
+func (d testDialect) Placeholder(n int) string {
+	return "$" + strconv.Itoa(n)
+}
+
+func TestORM_Dialect(t *testing.T) {
+	RegisterDialect(testDialect{BaseDialect{Type: 100, EscStart: `"`, EscEnd: `"`}})
+	customRef := NewReference(100)
+	customRef.AddTableDef("table1", Def{})
+	customRef.AddTableDef("table2", Def2{})
+	customRef.BuildRefs()
+
+	dao := NewORM(context.Background(), "table1", &fakeExecutor{}, customRef)
+	s, args, err := dao.Query("name", "a").Limit(1).ToSQLWithArgs(false)
+	fmt.Println(s, args)
+	if err != nil || s != `select "table1"."id","table1"."name","table1"."ref_id" from "table1" where "table1"."name"=$1 limit 1` {
+		t.Fatalf("select error: %s %v %v", s, args, err)
+	}
+
+	_, _, err = dao.BuildUpsert(map[string]interface{}{"id": 1})
+	if err != ErrDBFunc {
+		t.Fatalf("upsert error: %v", err)
+	}
+
+	sqlserverRef := NewReference(dbtype.SQLServer)
+	sqlserverRef.AddTableDef("table1", Def{})
+	sqlserverRef.AddTableDef("table2", Def2{})
+	sqlserverRef.BuildRefs()
+
+	dao = NewORM(context.Background(), "table1", &fakeExecutor{}, sqlserverRef)
+	s, _, err = dao.BuildUpsert(map[string]interface{}{"id": 1, "name": "a"})
+	fmt.Println(s)
+	if err != nil || !strings.Contains(s, "INSERT([id],[name]) VALUES([S].[id],[S].[name]);") {
+		t.Fatalf("merge error: %s %v", s, err)
+	}
+}
+
+func TestORM_ClickHouse(t *testing.T) {
+	chRef := NewReference(dbtype.ClickHouse)
+	chRef.AddTableDef("table1", Def{})
+	chRef.AddTableDef("table2", Def2{})
+	chRef.BuildRefs()
+
+	dao := NewORM(context.Background(), "table1", &fakeExecutor{}, chRef)
+	s := dao.Select("id", "name").Query("name__bin_eq", "A").Final(true).Sample("0.1").
+		Order("-id").LimitBy(2, "name").Limit(10).Settings("max_threads", 8).ToSQL(true)
+	fmt.Println(s)
+	if s != "select `table1`.`id`,`table1`.`name` from `table1` FINAL SAMPLE 0.1 where `table1`.`name`='A' "+
+		"order by `table1`.`id` desc limit 2 by `table1`.`name` limit 10 SETTINGS max_threads=8" {
+		t.Fatalf("select error: %s", s)
+	}
+	type mode string
+	s = dao.ClearCache().Select("id").Settings("max_memory_usage", uint64(1e10)).Settings("mode", "a'b").Settings("ratio", 0.5).ToSQL(true)
+	if s != "select `table1`.`id` from `table1` SETTINGS max_memory_usage=10000000000,mode='a''b',ratio=0.5" {
+		t.Fatalf("settings error: %s", s)
+	}
+	for _, v := range []interface{}{mode("x"), []byte("1"), struct{}{}} {
+		if _, _, err := dao.ClearCache().Select("id").Settings("mode", v).ToSQLWithArgs(true); err == nil {
+			t.Fatalf("setting %T should return error", v)
+		}
+	}
+	s = dao.ClearCache().Select("id").Sample("1/10 offset 1/2").ToSQL(true)
+	if s != "select `table1`.`id` from `table1` SAMPLE 1/10 offset 1/2" {
+		t.Fatalf("sample error: %s", s)
+	}
+	for _, sample := range []string{"", "0.1 where 1=1", "1/", "a", "0.1 OFFSET", "1.2.3", "1/10 OFFSET 1/2 x"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("sample[%s] should panic", sample)
+				}
+			}()
+			dao.ClearCache().Sample(sample)
+		}()
+	}
+	dao.ClearCache()
+
+	s, _, err := dao.BuildUpdateByWhere(map[string]interface{}{"name": "b"}, Where{"id": 1})
+	fmt.Println(s)
+	if err != nil || s != "alter table `table1` update `name`=? where `table1`.`id`=?" {
+		t.Fatalf("update error: %s %v", s, err)
+	}
+
+	s, _, err = dao.BuildDelete(nil)
+	if err != nil || s != "alter table `table1` delete where 1=1" {
+		t.Fatalf("delete error: %s %v", s, err)
+	}
+
+	s, _, err = dao.BuildUpsert(map[string]interface{}{"id": 1, "name": "a"})
+	if err != nil || s != "insert into `table1`(`id`,`name`) values(?,?)" {
+		t.Fatalf("upsert error: %s %v", s, err)
+	}
+
+	if NewClickHouseDialect(true).Delete("`table1`", "`id`=1") != "delete from `table1` where `id`=1" {
+		t.Fatal("lightweight delete error")
+	}
+}
+
+func TestORM_Exist(t *testing.T) {
+	executor := &fakeExecutor{}
+	dao := NewORM(context.Background(), "table1", executor, testRef(dbtype.MySQL, ""))
+	if _, err := dao.CustomSQL("select 1").Exist(); err != ErrCustomSQL || executor.query != "" {
+		t.Fatalf("custom sql exist error: %s %v", executor.query, err)
+	}
+
+	b, err := dao.CustomSQL("").Where("tb2.name", "a").Exist()
+	if err != nil || b || executor.query != "select `table1`.`id` from `table1` left join `table2` as `orm_tb2` "+
+		"on `table1`.`id`=`orm_tb2`.`id` where `orm_tb2`.`name`='a' limit 1" {
+		t.Fatalf("exist error: %s %v", executor.query, err)
+	}
+}
+
+func TestORM_DialectVersion(t *testing.T) {
+	dao := NewORM(context.Background(), "table1", &fakeExecutor{}, testRef(dbtype.SQLServer, "2008 R2"))
+	s := dao.Select("id").Page(2, 10).ToSQL(true)
+	fmt.Println(s)
+	if s != "select * from (select [table1].[id],ROW_NUMBER() OVER(ORDER BY [table1].[id]) as [orm_rn] from [table1]) as [orm_page] "+
+		"where [orm_rn]>10 and [orm_rn]<=20 order by [orm_rn]" {
+		t.Fatalf("sqlserver page error: %s", s)
+	}
+
+	executor := &fakeExecutor{rows: &fakeRows{cols: []string{"id", "orm_rn"}, row: []interface{}{int64(1), int64(11)}}}
+	rows, err := queryContext(context.Background(), executor, s, nil)
+	if err != nil {
+		t.Fatalf("query error: %v", err)
+	}
+	cols, _ := rows.Columns()
+	colType, _ := rows.ColumnTypes()
+	var id interface{}
+	if !rows.Next() || rows.Scan(&id) != nil || id != int64(1) || len(cols) != 1 || cols[0] != "id" || len(colType) != 1 {
+		t.Fatalf("row number column error: %v %v", cols, id)
+	}
+
+	dao = NewORM(context.Background(), "table1", &fakeExecutor{}, testRef(dbtype.SQLServer, "2005"))
+	_, _, err = dao.BuildUpsert(map[string]interface{}{"id": 1, "name": "a"})
+	if err != ErrDBFunc {
+		t.Fatalf("sqlserver upsert error: %v", err)
+	}
+
+	dao = NewORM(context.Background(), "table1", &fakeExecutor{}, testRef(dbtype.Oracle, "11g"))
+	s = dao.Select("id").Order("-id").Page(1, 5).ToSQL(true)
+	fmt.Println(s)
+	if s != `select * from (select "table1"."id",ROW_NUMBER() OVER(ORDER BY "table1"."id" desc) as "orm_rn" from "table1") "orm_page" `+
+		`where "orm_rn">0 and "orm_rn"<=5 order by "orm_rn"` {
+		t.Fatalf("oracle page error: %s", s)
+	}
+	// 未排序时按主表的主键排序，避免与关联表的同名字段冲突
+	s = NewORM(context.Background(), "table1", &fakeExecutor{}, testRef(dbtype.SQLServer, "10")).Select("id", "tb2.name").Page(2, 10).ToSQL(true)
+	if s != "select * from (select [table1].[id],[orm_tb2].[name] as [tb2_name],ROW_NUMBER() OVER(ORDER BY [table1].[id]) as [orm_rn] "+
+		"from [table1] left join [table2] as [orm_tb2] on [table1].[id]=[orm_tb2].[id]) as [orm_page] where [orm_rn]>10 and [orm_rn]<=20 order by [orm_rn]" {
+		t.Fatalf("sqlserver join page error: %s", s)
+	}
+	s = NewORM(context.Background(), "table1", &fakeExecutor{}, testRef(dbtype.Oracle, "11g")).Select("id", "tb2.name").Page(2, 10).ToSQL(true)
+	if s != `select * from (select "table1"."id","orm_tb2"."name" as "tb2_name",ROW_NUMBER() OVER(ORDER BY "table1"."id") as "orm_rn" `+
+		`from "table1" left join "table2" "orm_tb2" on "table1"."id"="orm_tb2"."id") "orm_page" where "orm_rn">10 and "orm_rn"<=20 order by "orm_rn"` {
+		t.Fatalf("oracle join page error: %s", s)
+	}
+	s = NewORM(context.Background(), "table1", &fakeExecutor{}, testRef(dbtype.Oracle, "19c")).Select("id").Page(1, 5).ToSQL(true)
+	if !strings.HasSuffix(s, "OFFSET 0 ROWS FETCH NEXT 5 ROWS ONLY") {
+		t.Fatalf("oracle page error: %s", s)
+	}
+
+	dao = NewORM(context.Background(), "table1", &fakeExecutor{}, testRef(dbtype.MySQL, "8.0.20"))
+	s, _, err = dao.BuildUpsert(map[string]interface{}{"id": 1, "name": "a"})
+	fmt.Println(s)
+	if err != nil || s != "insert into `table1`(`id`,`name`) values(?,?) as `orm_new` on duplicate key update `id`=`orm_new`.`id`,`name`=`orm_new`.`name`" {
+		t.Fatalf("mysql upsert error: %s %v", s, err)
+	}
+	dao = NewORM(context.Background(), "table1", &fakeExecutor{}, testRef(dbtype.MySQL, "5.7.44"))
+	s, _, err = dao.BuildUpsert(map[string]interface{}{"id": 1, "name": "a"})
+	if err != nil || !strings.HasSuffix(s, "`name`=values(`name`)") {
+		t.Fatalf("mysql upsert error: %s %v", s, err)
+	}
+}
+
+func TestORM_OracleLimit(t *testing.T) {
+	oracleRef, oracle11Ref := testRef(dbtype.Oracle, "19c"), testRef(dbtype.Oracle, "11g")
+
+	dao := NewORM(context.Background(), "table1", &fakeExecutor{}, oracleRef)
+	s := dao.Select("id").Order("-id").Limit(10).ToSQL(true)
+	fmt.Println(s)
+	if s != `select "table1"."id" from "table1" order by "table1"."id" desc FETCH FIRST 10 ROWS ONLY` {
+		t.Fatalf("limit error: %s", s)
+	}
+
+	dao = NewORM(context.Background(), "table1", &fakeExecutor{}, oracle11Ref)
+	s = dao.Select("id").Order("-id").Limit(10).ToSQL(true)
+	fmt.Println(s)
+	if s != `select * from (select "table1"."id" from "table1" order by "table1"."id" desc) where rownum<=10` {
+		t.Fatalf("limit error: %s", s)
+	}
+
+	// Exist：Select 主键，limit 1
+	s = dao.ClearCache().Select("id").Query("name", "a").Order("name").Limit(1).ToSQL(true)
+	fmt.Println(s)
+	if s != `select * from (select "table1"."id" from "table1" where "table1"."name"='a' order by "table1"."name" asc) where rownum<=1` {
+		t.Fatalf("exist error: %s", s)
+	}
+
+	s = dao.ClearCache().Select("id").Query("name", "a").Limit(1).ToSQL(true)
+	if s != `select "table1"."id" from "table1" where "table1"."name"='a' and rownum<=1` {
+		t.Fatalf("limit error: %s", s)
+	}
+
+	s, _, err := dao.ClearCache().Select("id").Query("name", "a").Order("-id").Limit(5).BuildCount()
+	fmt.Println(s)
+	if err != nil || s != `SELECT COUNT(*) as "c" from (select "table1"."id" from "table1" where "table1"."name"=:1) "count_tb"` {
+		t.Fatalf("count error: %s %v", s, err)
+	}
+
+	// PageData
+	s = NewORM(context.Background(), "table1", &fakeExecutor{}, oracleRef).Select("id").Order("-id").Page(2, 10).ToSQL(true)
+	if s != `select "table1"."id" from "table1" order by "table1"."id" desc OFFSET 10 ROWS FETCH NEXT 10 ROWS ONLY` {
+		t.Fatalf("page error: %s", s)
+	}
+
+	_, _, err = dao.ClearCache().Select("id").Order("-id").Limit(1).SelectForUpdate(true).ToSQLWithArgs(true)
+	if err != ErrDBFunc {
+		t.Fatalf("for update error: %v", err)
+	}
+}
+
+func TestORM_Regex(t *testing.T) {
+	cases := map[int][]string{
+		dbtype.MySQL:      {"`table1`.`name` regexp '^a'", "`table1`.`name` not regexp binary '^a'"},
+		dbtype.Postgres:   {`"table1"."name" ~ '^a'`, `"table1"."name" !~* '^a'`},
+		dbtype.Oracle:     {`REGEXP_LIKE("table1"."name",'^a')`, `not REGEXP_LIKE("table1"."name",'^a','i')`},
+		dbtype.ClickHouse: {"match(`table1`.`name`,'^a')", "not match(`table1`.`name`,concat('(?i)','^a'))"},
+		dbtype.SQLite3:    {`"table1"."name" regexp '^a'`, `"table1"."name" not regexp '(?i)'||'^a'`},
+	}
+	for tp, expected := range cases {
+		r := testRef(tp, "")
+
+		q := &BaseQuery{RefConf: r, TableName: "table1", Where: Where{"name__regex": "^a"}}
+		s := q.GetWhere()
+		if s != expected[0] {
+			t.Fatalf("%d regex error: %s", tp, s)
+		}
+
+		op := "name__i_nregex"
+		if tp == dbtype.MySQL {
+			op = "name__b_nregex"
+		}
+		q = &BaseQuery{RefConf: r, TableName: "table1", Where: Where{op: "^a"}}
+		s = q.GetWhere()
+		if s != expected[1] {
+			t.Fatalf("%d nregex error: %s", tp, s)
+		}
+	}
+
+	r := testRef(dbtype.Postgres, "")
+	s, args, err := NewORM(context.Background(), "table1", &fakeExecutor{}, r).Select("id").
+		Wheres(Where{"~tb2.name__iregex": "x"}).ToSQLWithArgs(true)
+	fmt.Println(s, args)
+	if err != nil || !strings.HasSuffix(s, `where (not ("orm_tb2"."name" ~* $1))`) || len(args) != 1 {
+		t.Fatalf("tag regex error: %s %v %v", s, args, err)
+	}
+
+	r = testRef(dbtype.SQLServer, "")
+	_, _, err = NewORM(context.Background(), "table1", &fakeExecutor{}, r).Query("name__regex", "x").ToSQLWithArgs(true)
+	if !errors.Is(err, ErrDBFunc) {
+		t.Fatalf("sqlserver regex error: %v", err)
+	}
+
+	if ok, err := SQLiteRegexp("^a.c$", "abc"); !ok || err != nil {
+		t.Fatalf("sqlite regexp error: %v", err)
+	}
+}
+
+type JSONDef struct {
+	ID   int                    `json:"id"`
+	Meta map[string]interface{} `json:"meta" type:"json"`
+	Tags []string               `json:"tags" type:"json"`
+	Tb2  *Def2                  `json:"tb2" ref:"left;id=id"`
+}
+
+func TestORM_JSONPath(t *testing.T) {
+	dao := NewORM(context.Background(), "jtable", &fakeExecutor{}, testRef(dbtype.MySQL, "", "jtable", JSONDef{}))
+	s, args, err := dao.Select("id", "meta->a.b").Wheres(Where{
+		"meta->a.b":           "x",
+		"meta->list.0__gt":    1,
+		"tags__json_contains": "go",
+		"meta__has_key":       "it's",
+	}).Order("-meta->a.b").GroupBy("meta->c").ToSQLWithArgs(true)
+	fmt.Println(s, args)
+	if err != nil || s != "select `jtable`.`id`,JSON_UNQUOTE(JSON_EXTRACT(`jtable`.`meta`,'$.a.b')) as `meta_a_b` from `jtable` "+
+		"where JSON_UNQUOTE(JSON_EXTRACT(`jtable`.`meta`,'$.a.b'))=? and JSON_UNQUOTE(JSON_EXTRACT(`jtable`.`meta`,'$.list[0]'))>? "+
+		"and JSON_CONTAINS_PATH(`jtable`.`meta`,'one','$.\"it''s\"') and JSON_CONTAINS(`jtable`.`tags`,?) "+
+		"group by JSON_UNQUOTE(JSON_EXTRACT(`jtable`.`meta`,'$.c')) order by JSON_UNQUOTE(JSON_EXTRACT(`jtable`.`meta`,'$.a.b')) desc" ||
+		len(args) != 3 || args[2] != `"go"` {
+		t.Fatalf("mysql json error: %s %v %v", s, args, err)
+	}
+
+	q := &BaseQuery{RefConf: testRef(dbtype.Postgres, "", "jtable", JSONDef{}), TableName: "jtable", Where: Where{
+		"tb2.name->a.0":       "x",
+		"tags__json_contains": []string{"a"},
+		"meta__has_key":       "k",
+	}}
+	s = q.GetWhere()
+	fmt.Println(s)
+	if s != `"jtable"."meta"::jsonb?'k' and "jtable"."tags"::jsonb@>'["a"]'::jsonb and ("orm_tb2"."name"::jsonb#>>'{"a","0"}')='x'` {
+		t.Fatalf("postgres json error: %s", s)
+	}
+
+	q = &BaseQuery{RefConf: testRef(dbtype.Oracle, "", "jtable", JSONDef{}), TableName: "jtable", Where: Where{"meta->a-b": "x"}}
+	if s = q.GetWhere(); s != `JSON_VALUE("jtable"."meta",'$."a-b"')='x'` {
+		t.Fatalf("oracle json error: %s", s)
+	}
+
+	dao = NewORM(context.Background(), "jtable", &fakeExecutor{}, testRef(dbtype.SQLite3, "", "jtable", JSONDef{}))
+	_, _, err = dao.Query("tags__json_contains", "a").ToSQLWithArgs(true)
+	if !errors.Is(err, ErrDBFunc) {
+		t.Fatalf("sqlite json error: %v", err)
+	}
+}
+
+func TestORM_Search(t *testing.T) {
+	dao := NewORM(context.Background(), "table1", &fakeExecutor{}, testRef(dbtype.MySQL, ""))
+	s, args, err := dao.Select("id").Wheres(Where{
+		"name__search": "数据库",
+		"$or":          Where{"tb2.name__search": "go", "id": 1},
+	}).OrderBySearch("name", "数据库").Order("-id").ToSQLWithArgs(true)
+	fmt.Println(s, args)
+	if err != nil || s != "select `table1`.`id` from `table1` left join `table2` as `orm_tb2` on `table1`.`id`=`orm_tb2`.`id` "+
+		"where (MATCH(`orm_tb2`.`name`) AGAINST(? IN NATURAL LANGUAGE MODE) or `table1`.`id`=?) "+
+		"and MATCH(`table1`.`name`) AGAINST(? IN NATURAL LANGUAGE MODE) "+
+		"order by MATCH(`table1`.`name`) AGAINST(? IN NATURAL LANGUAGE MODE) desc,`table1`.`id` desc" ||
+		len(args) != 4 || args[3] != "数据库" {
+		t.Fatalf("mysql search error: %s %v %v", s, args, err)
+	}
+
+	dao = NewORM(context.Background(), "table1", &fakeExecutor{}, testRef(dbtype.Postgres, ""))
+	s, args, err = dao.Select("id").Query("name__search", "it's").OrderBySearch("name", "it's").ToSQLWithArgs(false)
+	if err != nil || s != `select "table1"."id" from "table1" where to_tsvector("table1"."name") @@ plainto_tsquery($1) `+
+		`order by ts_rank(to_tsvector("table1"."name"),plainto_tsquery($2)) desc` || len(args) != 2 || args[1] != "it's" {
+		t.Fatalf("postgres search error: %s %v %v", s, args, err)
+	}
+
+	q := &BaseQuery{RefConf: testRef(dbtype.SQLServer, ""), TableName: "table1", Where: Where{"name__search": `"go*"`}}
+	if s = q.GetWhere(); s != `CONTAINS([table1].[name],'"go*"')` {
+		t.Fatalf("sqlserver search error: %s", s)
+	}
+
+	q = &BaseQuery{RefConf: testRef(dbtype.Oracle, ""), TableName: "table1", Where: Where{"name__search": "go"}}
+	if s = q.GetWhere(); s != `CONTAINS("table1"."name",'go')>0` {
+		t.Fatalf("oracle search error: %s", s)
+	}
+
+	q = &BaseQuery{RefConf: testRef(dbtype.SQLite3, ""), TableName: "table1", Where: Where{"name__search": "go"}}
+	if s = q.GetWhere(); s != `"table1"."name" MATCH 'go'` {
+		t.Fatalf("sqlite search error: %s", s)
+	}
+
+	dao = NewORM(context.Background(), "table1", &fakeExecutor{}, testRef(dbtype.SQLite3, ""))
+	_, _, err = dao.OrderBySearch("name", "go").ToSQLWithArgs(false)
+	if !errors.Is(err, ErrDBFunc) {
+		t.Fatalf("sqlite search rank error: %v", err)
+	}
+
+	dao = NewORM(context.Background(), "table1", &fakeExecutor{}, testRef(dbtype.ClickHouse, ""))
+	_, _, err = dao.Query("name__search", "go").ToSQLWithArgs(false)
+	if !errors.Is(err, ErrDBFunc) {
+		t.Fatalf("clickhouse search error: %v", err)
+	}
+}
+
+func TestORM_Col(t *testing.T) {
+	ref := testRef(dbtype.MySQL, "")
+
+	dao := NewORM(context.Background(), "table1", &fakeExecutor{}, ref)
+	s, args, err := dao.Select("id").Wheres(Where{
+		"id__gt":     Col("tb2.id"),
+		"name__i_ne": Col("#`table1`.`id`"),
+		"name__lt":   "a",
+		"$or":        Where{"id": Col("id"), "name__b_eq": Col("tb2.name")},
+	}).GroupBy("id").Having("name__gte", Col("tb2.name")).ToSQLWithArgs(true)
+	fmt.Println(s, args)
+	if err != nil || s != "select `table1`.`id` from `table1` left join `table2` as `orm_tb2` on `table1`.`id`=`orm_tb2`.`id` "+
+		"where (`table1`.`id`=`table1`.`id` or `table1`.`name`=binary`orm_tb2`.`name`) and `table1`.`id`>`orm_tb2`.`id` "+
+		"and `table1`.`name`<>`table1`.`id` and `table1`.`name`<? "+
+		"group by `table1`.`id` having `table1`.`name`>=`orm_tb2`.`name`" || len(args) != 1 || args[0] != "a" {
+		t.Fatalf("col error: %s %v %v", s, args, err)
+	}
+
+	dao = NewORM(context.Background(), "table1", &fakeExecutor{}, ref)
+	_, _, err = dao.Query("name__startswith", Col("tb2.name")).ToSQLWithArgs(false)
+	if err == nil || !strings.Contains(err.Error(), "does not support column value") {
+		t.Fatalf("col operator error: %v", err)
+	}
+}
+
+func TestORM_DatePart(t *testing.T) {
+	dao := NewORM(context.Background(), "table1", &fakeExecutor{}, testRef(dbtype.MySQL, ""))
+	s, args, err := dao.Select("created_at|month", "tb2.created_at|trunc_day d", "#count(*) c").Wheres(Where{
+		"created_at__year":         2024,
+		"created_at__month_in":     []int{1, 2},
+		"tb2.created_at__hour_gte": 8,
+		"created_at|weekday":       1,
+	}).GroupBy("created_at|month", "tb2.created_at|trunc_day").Order("-created_at|month").ToSQLWithArgs(true)
+	fmt.Println(s, args)
+	if err != nil || s != "select MONTH(`table1`.`created_at`) as `created_at_month`,DATE(`orm_tb2`.`created_at`) d,count(*) c "+
+		"from `table1` left join `table2` as `orm_tb2` on `table1`.`id`=`orm_tb2`.`id` "+
+		"where (WEEKDAY(`table1`.`created_at`)+1)=? and HOUR(`orm_tb2`.`created_at`)>=? and MONTH(`table1`.`created_at`) in (?,?) "+
+		"and `table1`.`created_at`>='2024-01-01 00:00:00' and `table1`.`created_at`<'2025-01-01 00:00:00' "+
+		"group by MONTH(`table1`.`created_at`),DATE(`orm_tb2`.`created_at`) order by MONTH(`table1`.`created_at`) desc" ||
+		len(args) != 4 {
+		t.Fatalf("mysql date part error: %s %v %v", s, args, err)
+	}
+
+	q := &BaseQuery{RefConf: testRef(dbtype.Postgres, ""), TableName: "table1", Where: Where{
+		"created_at__year_gt": "2023", "created_at__weekday_ne": 7, "created_at|trunc_week__lt": Col("tb2.created_at")}}
+	if s = q.GetWhere(); s != `EXTRACT(ISODOW FROM "table1"."created_at")::int<>7 and "table1"."created_at">='2024-01-01 00:00:00' `+
+		`and date_trunc('week',"table1"."created_at")<"orm_tb2"."created_at"` {
+		t.Fatalf("postgres date part error: %s", s)
+	}
+
+	q = &BaseQuery{RefConf: testRef(dbtype.SQLServer, ""), TableName: "table1", Where: Where{"created_at__week": 1, "created_at__year_ne": 2024}}
+	if s = q.GetWhere(); s != `DATEPART(iso_week,[table1].[created_at])=1 and ([table1].[created_at]<'2024-01-01 00:00:00' or [table1].[created_at]>='2025-01-01 00:00:00')` {
+		t.Fatalf("sqlserver date part error: %s", s)
+	}
+
+	q = &BaseQuery{RefConf: testRef(dbtype.Oracle, ""), TableName: "table1", Where: Where{"created_at__quarter": 2}}
+	if s = q.GetWhere(); s != `TO_NUMBER(TO_CHAR("table1"."created_at",'Q'))=2` {
+		t.Fatalf("oracle date part error: %s", s)
+	}
+
+	q = &BaseQuery{RefConf: testRef(dbtype.SQLite3, ""), TableName: "table1", Where: Where{"created_at__day": 1}}
+	if s = q.GetWhere(); s != `CAST(strftime('%d',"table1"."created_at") AS INTEGER)=1` {
+		t.Fatalf("sqlite date part error: %s", s)
+	}
+
+	q = &BaseQuery{RefConf: testRef(dbtype.ClickHouse, ""), TableName: "table1", GroupBy: GroupBy{"created_at|trunc_month"}, Select: Select{"created_at|trunc_month m"}}
+	if s = q.SQL(); s != "select toStartOfMonth(`table1`.`created_at`) m from `table1` group by toStartOfMonth(`table1`.`created_at`)" {
+		t.Fatalf("clickhouse date part error: %s", s)
+	}
+
+	dao = NewORM(context.Background(), "table1", &fakeExecutor{}, testRef(dbtype.MySQL, ""))
+	_, _, err = dao.Query("created_at__year", "24a").ToSQLWithArgs(false)
+	if err == nil || !strings.Contains(err.Error(), "year[24a] is invalid") {
+		t.Fatalf("year error: %v", err)
+	}
+}
+
+func TestORM_RegisterOperator(t *testing.T) {
+	RegisterOperator("test_len", func(d Dialect, c *Condition, not bool) (string, error) {
+		if _, ok := c.Data.(int); !ok {
+			return "", errors.New("value must be int")
+		}
+
+		fn := "LENGTH"
+		if d.DBType() == dbtype.SQLServer {
+			fn = "LEN"
+		}
+		col := c.Column
+		if c.Ignore {
+			col = "TRIM(" + col + ")"
+		}
+		op := "="
+		if not {
+			op = "<>"
+		}
+		return fn + "(" + col + ")" + op + c.Value, nil
+	})
+
+	ref := testRef(dbtype.MySQL, "")
+
+	dao := NewORM(context.Background(), "table1", &fakeExecutor{}, ref)
+	s, args, err := dao.Select("id").Wheres(Where{
+		"~name__test_len": 3,
+		"$or":             Where{"tb2.name__i_test_len": 2, "id": 1},
+	}).GroupBy("name").Having("name__test_len", 4).ToSQLWithArgs(true)
+	fmt.Println(s, args)
+	if err != nil || s != "select `table1`.`id` from `table1` left join `table2` as `orm_tb2` on `table1`.`id`=`orm_tb2`.`id` "+
+		"where (LENGTH(TRIM(`orm_tb2`.`name`))=? or `table1`.`id`=?) and LENGTH(`table1`.`name`)<>? "+
+		"group by `table1`.`name` having LENGTH(`table1`.`name`)=?" || len(args) != 4 {
+		t.Fatalf("custom operator error: %s %v %v", s, args, err)
+	}
+
+	dao = NewORM(context.Background(), "table1", &fakeExecutor{}, ref)
+	_, _, err = dao.Query("name__test_len", "a").ToSQLWithArgs(false)
+	if err == nil || !strings.Contains(err.Error(), "value must be int") {
+		t.Fatalf("custom operator value error: %v", err)
+	}
+
+	dao = NewORM(context.Background(), "table1", &fakeExecutor{}, ref)
+	_, _, err = dao.Query("name__unknown", "a").ToSQLWithArgs(false)
+	if err == nil || !strings.Contains(err.Error(), "operator[unknown] is not defined") {
+		t.Fatalf("unknown operator error: %v", err)
+	}
+
+	for _, name := range []string{"eq", "month_gt", "i_len", "a-b"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("register operator[%s] should panic", name)
+				}
+			}()
+			RegisterOperator(name, func(d Dialect, c *Condition, not bool) (string, error) { return "", nil })
+		}()
+	}
+}
+
+func TestORM_Cond(t *testing.T) {
+	ref := testRef(dbtype.MySQL, "")
+
+	c := Q("id__gt", 1).And(Q("id__lt", 10), Q("#`table1`.`ref_id`", 2)).
+		Or(Not(Q("tb2.name__contains", "a")), Q("name__in", []string{"x"}).And(Q("id__gt", 5)))
+	dao := NewORM(context.Background(), "table1", &fakeExecutor{}, ref)
+	s, args, err := dao.Select("id").Where("name", "n").Where("$and", c).
+		GroupBy("name").Having("$or", []*Cond{Q("#count(*)__gt", 1), Q("#count(*)__lt", 0)}).ToSQLWithArgs(true)
+	fmt.Println(s, args)
+	if err != nil || s != "select `table1`.`id` from `table1` left join `table2` as `orm_tb2` on `table1`.`id`=`orm_tb2`.`id` "+
+		"where ((`table1`.`id`>? and `table1`.`id`<? and `table1`.`ref_id`=?) or (not (`orm_tb2`.`name` like ?)) "+
+		"or (`table1`.`name` in (?) and `table1`.`id`>?)) and `table1`.`name`=? "+
+		"group by `table1`.`name` having ((count(*)>?) or (count(*)<?))" ||
+		len(args) != 9 || args[0] != int64(1) || args[3] != "%a%" || args[6] != "n" {
+		t.Fatalf("cond error: %s %v %v", s, args, err)
+	}
+
+	dao = NewORM(context.Background(), "table1", &fakeExecutor{}, ref)
+	s, err = dao.formatDeleteByWhereSQL(Where{"~$and": Not(Q("id", 1))})
+	if err != nil || s != "delete from `table1` where (not (not (`table1`.`id`=1)))" {
+		t.Fatalf("cond delete error: %s %v", s, err)
+	}
+
+	s, err = dao.formatUpdateByWhereSQL(map[string]interface{}{"name": "a"}, Where{"$or": Q("id", 1).Or(Q("id", 2))})
+	if err != nil || s != "update `table1` set `name`='a' where ((`table1`.`id`=1) or (`table1`.`id`=2))" {
+		t.Fatalf("cond update error: %s %v", s, err)
+	}
+
+	_, _, err = NewORM(context.Background(), "table1", &fakeExecutor{}, ref).Where("id", Q("id", 1)).ToSQLWithArgs(false)
+	if err == nil {
+		t.Fatalf("cond key error")
+	}
+}
+
+func TestORM_Raw(t *testing.T) {
+	ref := testRef(dbtype.Postgres, "")
+
+	dao := NewORM(context.Background(), "table1", &fakeExecutor{}, ref)
+	s, args, err := dao.Select("id").SelectExpr(Raw("id*?", 2).As("id2")).Wheres(Where{
+		"$raw": Raw("id+ref_id>? and name<>'?' and meta ?? 'k'", 10),
+		"$or":  Where{"$raw": []*RawSQL{Raw("id in ?", []int{1, 2}), Raw("name=?", "it's")}, "id": 3},
+	}).OrderExpr(Raw("abs(id-?) desc", 5)).ToSQLWithArgs(true)
+	fmt.Println(s, args)
+	if err != nil || s != `select "table1"."id",id*$1 as "id2" from "table1" `+
+		`where ("table1"."id"=$2 or ((id in ($3,$4)) and (name=$5))) and (id+ref_id>$6 and name<>'?' and meta ? 'k') `+
+		`order by abs(id-$7) desc` || len(args) != 7 || args[4] != "it's" {
+		t.Fatalf("raw error: %s %v %v", s, args, err)
+	}
+
+	dao = NewORM(context.Background(), "table1", &fakeExecutor{}, ref)
+	s = dao.Select("id").Where("$and", Q("$raw", Raw("name=?", "it's")).Or(Q("id", 1))).
+		GroupBy("name").Having("$raw", Raw("count(*)>?", 1)).ToSQL(false)
+	if s != `select "table1"."id" from "table1" where ((name='it''s') or ("table1"."id"=1)) `+
+		`group by "table1"."name" having (count(*)>1)` {
+		t.Fatalf("raw literal error: %s", s)
+	}
+
+	dao = NewORM(context.Background(), "table1", &fakeExecutor{}, ref)
+	_, _, err = dao.Where("$raw", Raw("id=? and name=?", 1)).ToSQLWithArgs(false)
+	if err == nil || !strings.Contains(err.Error(), "args count error") {
+		t.Fatalf("raw args error: %v", err)
+	}
+}
+
+func TestORM_Expr(t *testing.T) {
+	ref := testRef(dbtype.MySQL, "")
+
+	total := Arith(Col("id"), "*", Coalesce(Col("tb2.id"), 0))
+	level := Case(Q("id__gte", 60), "pass", Q("tb2.name__null", true).Or(Q("id", 0)), "none").Else("fail").As("level")
+	year := Func("DATE_FORMAT", Col("created_at"), "%Y")
+	dao := NewORM(context.Background(), "table1", &fakeExecutor{}, ref)
+	s, args, err := dao.Select("name").SelectExpr(total, level, year).
+		Where("$raw", Raw("? > ?", total, 100)).
+		GroupBy("name").GroupByExpr(year).OrderExpr(total.Desc()).ToSQLWithArgs(true)
+	fmt.Println(s, args)
+	if err != nil || s != "select `table1`.`name`,(`table1`.`id`*COALESCE(`orm_tb2`.`id`,?)) as `id_mul_tb2_id_coalesce`,"+
+		"CASE WHEN `table1`.`id`>=? THEN ? WHEN ((`orm_tb2`.`name` is null) or (`table1`.`id`=?)) THEN ? ELSE ? END as `level`,"+
+		"DATE_FORMAT(`table1`.`created_at`,?) as `created_at_date_format` "+
+		"from `table1` left join `table2` as `orm_tb2` on `table1`.`id`=`orm_tb2`.`id` "+
+		"where ((`table1`.`id`*COALESCE(`orm_tb2`.`id`,?)) > ?) "+
+		"group by `table1`.`name`,DATE_FORMAT(`table1`.`created_at`,?) "+
+		"order by (`table1`.`id`*COALESCE(`orm_tb2`.`id`,?)) desc" || len(args) != 11 || args[5] != "fail" {
+		t.Fatalf("expr error: %s %v %v", s, args, err)
+	}
+
+	dao = NewORM(context.Background(), "table1", &fakeExecutor{}, ref)
+	s = dao.SelectExpr(Coalesce(Col("meta->a.b"), "x")).Select("id").ToSQL(false)
+	if s != "select `table1`.`id`,COALESCE(JSON_UNQUOTE(JSON_EXTRACT(`table1`.`meta`,'$.a.b')),'x') as `meta_a_b_coalesce` from `table1`" {
+		t.Fatalf("expr literal error: %s", s)
+	}
+
+	for _, fn := range []func(){
+		func() { Func("a b") },
+		func() { Arith(Col("a"), "^", 1) },
+		func() { Case(Q("a", 1)) },
+		func() { Case(Q("a", 1), 1).Else(2).Else(3) },
+		func() { Func("f").Else(1) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("expr should panic")
+				}
+			}()
+			fn()
+		}()
+	}
+}
+
+func TestORM_Window(t *testing.T) {
+	ref := testRef(dbtype.MySQL, "")
+
+	rn := RowNumber().PartitionBy("name").OrderBy("-id")
+	total := Over(Func("SUM", Col("tb2.id"))).PartitionBy("name").OrderBy("id").
+		Frame("ROWS BETWEEN UNBOUNDED PRECEDING AND CURRENT ROW").As("total")
+	dao := NewORM(context.Background(), "table1", &fakeExecutor{}, ref)
+	s, args, err := dao.Select("id").SelectExpr(rn, total, Lag("name", 1)).OrderExpr(rn).ToSQLWithArgs(true)
+	fmt.Println(s, args)
+	if err != nil || s != "select `table1`.`id`,"+
+		"ROW_NUMBER() OVER (PARTITION BY `table1`.`name` ORDER BY `table1`.`id` desc) as `row_number`,"+
+		"SUM(`orm_tb2`.`id`) OVER (PARTITION BY `table1`.`name` ORDER BY `table1`.`id` asc "+
+		"ROWS BETWEEN UNBOUNDED PRECEDING AND CURRENT ROW) as `total`,"+
+		"LAG(`table1`.`name`,?) OVER () as `name_lag` "+
+		"from `table1` left join `table2` as `orm_tb2` on `table1`.`id`=`orm_tb2`.`id` "+
+		"order by ROW_NUMBER() OVER (PARTITION BY `table1`.`name` ORDER BY `table1`.`id` desc)" || len(args) != 1 {
+		t.Fatalf("window error: %s %v %v", s, args, err)
+	}
+
+	for _, c := range [][2]interface{}{
+		{dbtype.MySQL, "5.7"},
+		{dbtype.MariaDB, "10.1"},
+		{dbtype.SQLite3, "3.24"},
+	} {
+		r := testRef(c[0].(int), c[1].(string))
+		_, _, err = NewORM(context.Background(), "table1", &fakeExecutor{}, r).SelectExpr(rn).ToSQLWithArgs(true)
+		if !errors.Is(err, ErrDBFunc) {
+			t.Fatalf("window %v %v should not be supported: %v", c[0], c[1], err)
+		}
+	}
+
+	r := testRef(dbtype.SQLite3, "3.25")
+	s = NewORM(context.Background(), "table1", &fakeExecutor{}, r).SelectExpr(DenseRank().OrderBy("id")).ToSQL(false)
+	if !strings.HasSuffix(s, "DENSE_RANK() OVER (ORDER BY \"table1\".\"id\" asc) as \"dense_rank\" from \"table1\"") {
+		t.Fatalf("sqlite window error: %s", s)
+	}
+
+	for _, fn := range []func(){
+		func() { Func("f").PartitionBy("a") },
+		func() { RowNumber().Frame("ROWS 1;drop") },
+		func() { RowNumber().OrderBy("") },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("window should panic")
+				}
+			}()
+			fn()
+		}()
+	}
+}
+
+type StatDef struct {
+	RefID int  `json:"ref_id"`
+	Cnt   int  `json:"cnt"`
+	Tb1   *Def `json:"tb1" ref:"left;ref_id=id"`
+}
+
+func TestORM_Subquery(t *testing.T) {
+	ref := testRef(dbtype.MySQL, "", "stat", StatDef{})
+
+	sub := NewORM(context.Background(), "table1", &fakeExecutor{}, ref).
+		Select("ref_id", "#count(*) cnt").Where("tb2.name", "a").GroupBy("ref_id")
+	dao := NewORMFromQuery(context.Background(), "stat", sub, &fakeExecutor{}, ref)
+	s, args, err := dao.Select("ref_id", "cnt", "tb1.name").Where("cnt__gt", 1).Order("-cnt").ToSQLWithArgs(true)
+	fmt.Println(s, args)
+	if err != nil || s != "select `stat`.`ref_id`,`stat`.`cnt`,`orm_tb1`.`name` as `tb1_name` from "+
+		"(select `table1`.`ref_id`,count(*) cnt from `table1` left join `table2` as `orm_tb2` on `table1`.`id`=`orm_tb2`.`id` "+
+		"where `orm_tb2`.`name`=? group by `table1`.`ref_id`) as `stat` "+
+		"left join `table1` as `orm_tb1` on `stat`.`ref_id`=`orm_tb1`.`id` "+
+		"where `stat`.`cnt`>? order by `stat`.`cnt` desc" || len(args) != 2 || args[0] != "a" {
+		t.Fatalf("from subquery error: %s %v %v", s, args, err)
+	}
+	if _, err = dao.DeleteByWhere(Where{"cnt": 0}); err != ErrSubquery {
+		t.Fatalf("subquery table should not be written: %v", err)
+	}
+
+	cnt := NewORM(context.Background(), "table2", &fakeExecutor{}, ref).Select("#count(*)").
+		Where("name", OuterCol("tb2.name")).Where("id__gt", 10)
+	dao = NewORM(context.Background(), "table1", &fakeExecutor{}, ref)
+	s = dao.Select("id").SelectExpr(Sub(cnt).As("cnt")).
+		Where("$raw", Raw("? > 0", Sub(cnt))).ToSQL(false)
+	fmt.Println(s)
+	if s != "select `table1`.`id`,(select count(*) from `table2` where `table2`.`id`>10 and `table2`.`name`=`orm_tb2`.`name`) as `cnt` "+
+		"from `table1` left join `table2` as `orm_tb2` on `table1`.`id`=`orm_tb2`.`id` "+
+		"where ((select count(*) from `table2` where `table2`.`id`>10 and `table2`.`name`=`orm_tb2`.`name`) > 0)" {
+		t.Fatalf("scalar subquery error: %s", s)
+	}
+
+	dao = NewORM(context.Background(), "table1", &fakeExecutor{}, ref)
+	s = dao.Select("id").Where("id__in", NewORM(context.Background(), "table2", &fakeExecutor{}, ref).
+		Select("id").Where("name", OuterCol("name"))).ToSQL(false)
+	if s != "select `table1`.`id` from `table1` where `table1`.`id` in (select `table2`.`id` from `table2` where `table2`.`name`=`table1`.`name`)" {
+		t.Fatalf("correlated subquery error: %s", s)
+	}
+
+	dao = NewORM(context.Background(), "table1", &fakeExecutor{}, ref)
+	if _, _, err = dao.Where("name", OuterCol("name")).ToSQLWithArgs(false); err == nil {
+		t.Fatalf("outer column should only be used in subquery")
+	}
+}
+
+type TreeDef struct {
+	ID       int `json:"id"`
+	ParentID int `json:"parent_id"`
+	Depth    int `json:"depth"`
+}
+
+type CategoryDef struct {
+	ID       int      `json:"id"`
+	ParentID int      `json:"parent_id"`
+	Name     string   `json:"name"`
+	Tree     *TreeDef `json:"tree" ref:"inner;parent_id=id"`
+}
+
+type StatUserDef struct {
+	ID   int      `json:"id"`
+	Name string   `json:"name"`
+	Stat *StatDef `json:"stat" ref:"left;id=ref_id"`
+}
+
+func TestORM_With(t *testing.T) {
+	defs := []interface{}{"tree", TreeDef{}, "category", CategoryDef{}, "stat", StatDef{}, "users", StatUserDef{}}
+	tree := func(ref *Reference) *ORM {
+		anchor := NewORM(context.Background(), "category", &fakeExecutor{}, ref).Select("id", "parent_id").
+			SelectExpr(Raw("1").As("depth")).Where("parent_id", 0)
+		recursive := NewORM(context.Background(), "category", &fakeExecutor{}, ref).Select("id", "parent_id").
+			SelectExpr(Arith(Col("tree.depth"), "+", 1).As("depth"))
+		return NewORM(context.Background(), "tree", &fakeExecutor{}, ref).WithRecursive("tree", anchor, recursive)
+	}
+
+	ref := testRef(dbtype.MySQL, "8.0", defs...)
+	s, args, err := tree(ref).Where("depth__lte", 3).ToSQLWithArgs(true)
+	fmt.Println(s, args)
+	if err != nil || s != "with recursive `tree` as (select `category`.`id`,`category`.`parent_id`,1 as `depth` from `category` "+
+		"where `category`.`parent_id`=? union all select `category`.`id`,`category`.`parent_id`,(`orm_tree`.`depth`+?) as `depth` "+
+		"from `category` inner join `tree` as `orm_tree` on `category`.`parent_id`=`orm_tree`.`id`) "+
+		"select `tree`.`id`,`tree`.`parent_id`,`tree`.`depth` from `tree` where `tree`.`depth`<=?" ||
+		len(args) != 3 || args[0] != int64(0) || args[2] != int64(3) {
+		t.Fatalf("with recursive error: %s %v %v", s, args, err)
+	}
+
+	s, _, err = tree(testRef(dbtype.Oracle, "", defs...)).ToSQLWithArgs(false)
+	if err != nil || !strings.HasPrefix(s, `with "tree"("id","parent_id","depth") as (select `) {
+		t.Fatalf("oracle with recursive error: %s %v", s, err)
+	}
+	s, _, err = tree(testRef(dbtype.SQLServer, "", defs...)).ToSQLWithArgs(false)
+	if err != nil || !strings.HasPrefix(s, `with [tree] as (select `) {
+		t.Fatalf("sqlserver with recursive error: %s %v", s, err)
+	}
+	for _, c := range [][2]interface{}{{dbtype.MySQL, "5.7"}, {dbtype.ClickHouse, ""}, {dbtype.SQLite3, "3.8.2"}} {
+		if _, _, err = tree(testRef(c[0].(int), c[1].(string), defs...)).ToSQLWithArgs(false); !errors.Is(err, ErrDBFunc) {
+			t.Fatalf("with %v %v should not be supported: %v", c[0], c[1], err)
+		}
+	}
+
+	sub := NewORM(context.Background(), "table1", &fakeExecutor{}, ref).Select("ref_id", "#count(*) cnt").GroupBy("ref_id")
+	dao := NewORM(context.Background(), "users", &fakeExecutor{}, ref).With("stat", sub)
+	s = dao.Select("name", "stat.cnt").Where("stat.cnt__gt", 1).ToSQL(true)
+	if s != "with `stat` as (select `table1`.`ref_id`,count(*) cnt from `table1` group by `table1`.`ref_id`) "+
+		"select `users`.`name`,`orm_stat`.`cnt` as `stat_cnt` from `users` "+
+		"left join `stat` as `orm_stat` on `users`.`id`=`orm_stat`.`ref_id` where `orm_stat`.`cnt`>1" {
+		t.Fatalf("with join error: %s", s)
+	}
+	s = dao.ClearCache().cond(true).Count()
+	if !strings.HasPrefix(s, "with `stat` as (select `table1`.`ref_id`,count(*) cnt from `table1` group by `table1`.`ref_id`) SELECT COUNT(*)") {
+		t.Fatalf("with count error: %s", s)
+	}
+}
+
+func TestORM_Union(t *testing.T) {
+	ref := testRef(dbtype.MySQL, "")
+	other := NewORM(context.Background(), "table2", &fakeExecutor{}, ref).Select("id", "name").Where("id__gt", 10)
+	dao := NewORM(context.Background(), "table1", &fakeExecutor{}, ref).Select("id", "tb2.name").Where("name", "a")
+	s, args, err := dao.Union(other).Order("-tb2.name", "id").Page(2, 10).ToSQLWithArgs(true)
+	fmt.Println(s, args)
+	if err != nil || s != "select * from (select `table1`.`id`,`orm_tb2`.`name` as `tb2_name` from `table1` "+
+		"left join `table2` as `orm_tb2` on `table1`.`id`=`orm_tb2`.`id` where `table1`.`name`=? "+
+		"union select `table2`.`id`,`table2`.`name` from `table2` where `table2`.`id`>?) as `orm_set` "+
+		"order by `orm_set`.`tb2_name` desc,`orm_set`.`id` asc limit 10 offset 10" ||
+		len(args) != 2 || args[0] != "a" || args[1] != int64(10) {
+		t.Fatalf("union error: %s %v %v", s, args, err)
+	}
+
+	s = dao.ClearCache().Select("id").UnionAll(other.ClearCache().Select("id"), other).cond(true).Count()
+	if s != "SELECT COUNT(*) as `c` from (select * from (select `table1`.`id` from `table1` union all "+
+		"select `table2`.`id` from `table2` union all select `table2`.`id` from `table2`) as `orm_set`) as `count_tb`" {
+		t.Fatalf("union count error: %s", s)
+	}
+
+	oracle := testRef(dbtype.Oracle, "")
+	s = NewORM(context.Background(), "table1", &fakeExecutor{}, oracle).Select("id").
+		Except(NewORM(context.Background(), "table2", &fakeExecutor{}, oracle).Select("id")).ToSQL(true)
+	if s != `select * from (select "table1"."id" from "table1" minus select "table2"."id" from "table2") "orm_set"` {
+		t.Fatalf("oracle except error: %s", s)
+	}
+
+	mysql57 := testRef(dbtype.MySQL, "5.7")
+	_, _, err = NewORM(context.Background(), "table1", &fakeExecutor{}, mysql57).Select("id").
+		Intersect(NewORM(context.Background(), "table2", &fakeExecutor{}, mysql57).Select("id")).ToSQLWithArgs(true)
+	if !errors.Is(err, ErrDBFunc) {
+		t.Fatalf("mysql 5.7 intersect should not be supported: %v", err)
+	}
+
+	for _, fn := range []func() *ORM{
+		func() *ORM {
+			return NewORM(context.Background(), "table1", &fakeExecutor{}, ref).Select("id", "name").
+				Union(NewORM(context.Background(), "table2", &fakeExecutor{}, ref).Select("id"))
+		},
+		func() *ORM {
+			return NewORM(context.Background(), "table1", &fakeExecutor{}, ref).Select("id").
+				Union(NewORM(context.Background(), "table2", &fakeExecutor{}, ref).Select("id").Limit(1))
+		},
+	} {
+		if _, _, err = fn().ToSQLWithArgs(true); err == nil {
+			t.Fatalf("union should return error")
+		}
+	}
+}
+
+func TestORM_Aggregate(t *testing.T) {
+	ref := testRef(dbtype.MySQL, "")
+
+	executor := &fakeExecutor{}
+	dao := NewORM(context.Background(), "table1", executor, ref).KeepQuery(false)
+	var total int64
+	err := dao.Where("tb2.name", "a").Order("-id").Limit(10).Sum("tb2.id", &total)
+	if err != nil || total != 0 || executor.query != "select sum(`orm_tb2`.`id`) as `v` from `table1` "+
+		"left join `table2` as `orm_tb2` on `table1`.`id`=`orm_tb2`.`id` where `orm_tb2`.`name`='a' limit 1" {
+		t.Fatalf("sum error: %s %v %v", executor.query, total, err)
+	}
+	if len(dao.Q.Where) != 0 {
+		t.Fatalf("aggregate should clear query")
+	}
+
+	var m map[string]interface{}
+	err = dao.Where("id__gt", 1).Aggregate(map[string]string{
+		"cnt":   "count(*)",
+		"names": "count(distinct name)",
+		"last":  "max(created_at|day)",
+	}, &m)
+	if err != nil || m != nil || executor.query != "select count(*) as `cnt`,"+
+		"max(DAY(`table1`.`created_at`)) as `last`,count(distinct `table1`.`name`) as `names` "+
+		"from `table1` where `table1`.`id`>1 limit 1" {
+		t.Fatalf("aggregate error: %s %v %v", executor.query, m, err)
+	}
+
+	var last time.Time
+	err = dao.Where("id__gt", 1).Max("tb2.created_at", &last)
+	if err != nil || !last.IsZero() || executor.query != "select max(`orm_tb2`.`created_at`) as `v` from `table1` "+
+		"left join `table2` as `orm_tb2` on `table1`.`id`=`orm_tb2`.`id` where `table1`.`id`>1 limit 1" {
+		t.Fatalf("max error: %s %v", executor.query, err)
+	}
+	var minName sql.NullString
+	if err = dao.Min("name", &minName); err != nil || minName.Valid {
+		t.Fatalf("min error: %v %v", minName, err)
+	}
+
+	other := NewORM(context.Background(), "table2", executor, ref).Select("id")
+	if err = dao.Select("id").Union(other).Sum("id", &total); err != ErrSetQuery {
+		t.Fatalf("aggregate of set query error: %v", err)
+	}
+
+	for _, aggs := range []map[string]string{nil, {"a b": "sum(id)"}, {"a": "sum(id"}, {"a": "sum()"}} {
+		if err = dao.Aggregate(aggs, &m); err == nil {
+			t.Fatalf("aggregate %v should return error", aggs)
+		}
+	}
+
+	if err = dao.Avg("id", total); err != ErrTargetNotSettable {
+		t.Fatalf("avg dest error: %v", err)
+	}
+}
+
+func TestORM_Pluck(t *testing.T) {
+	ref := testRef(dbtype.MySQL, "")
+
+	executor := &fakeExecutor{}
+	dao := NewORM(context.Background(), "table1", executor, ref)
+	var names []string
+	err := dao.Select("id", "name").Where("id__gt", 1).Order("-id").Pluck("tb2.name", &names)
+	if err != nil || names != nil || executor.query != "select `orm_tb2`.`name` from `table1` "+
+		"left join `table2` as `orm_tb2` on `table1`.`id`=`orm_tb2`.`id` where `table1`.`id`>1 order by `table1`.`id` desc" {
+		t.Fatalf("pluck error: %s %v", executor.query, err)
+	}
+
+	var m map[int64]string
+	err = dao.ClearCache().SetDefLimit(100).ValuesMap("id", "tb2.name", &m)
+	if err != nil || m != nil || executor.query != "select `table1`.`id`,`orm_tb2`.`name` from `table1` "+
+		"left join `table2` as `orm_tb2` on `table1`.`id`=`orm_tb2`.`id` limit 100" {
+		t.Fatalf("values map error: %s %v", executor.query, err)
+	}
+
+	var id int64
+	if err = dao.Pluck("id", &id); err != ErrTargetNotSettable {
+		t.Fatalf("pluck dest error: %v", err)
+	}
+	if err = dao.ValuesMap("id", "name", names); err != ErrTargetNotSettable {
+		t.Fatalf("values map dest error: %v", err)
+	}
+	other := NewORM(context.Background(), "table2", executor, ref).Select("name")
+	if err = dao.ClearCache().Select("name").Union(other).Pluck("name", &names); err != ErrSetQuery {
+		t.Fatalf("pluck of set query error: %v", err)
+	}
+	if err = dao.ValuesMap("id", "name", &m); err != ErrSetQuery {
+		t.Fatalf("values map of set query error: %v", err)
+	}
+	if err = dao.ClearCache().Pluck("", &names); err == nil {
+		t.Fatalf("pluck empty column should return error")
+	}
+}
+
+func TestORM_SeekPage(t *testing.T) {
+	ref := testRef(dbtype.MySQL, "")
+
+	executor := &fakeExecutor{}
+	dao := NewORM(context.Background(), "table1", executor, ref)
+	var rows []map[string]interface{}
+	sp, err := dao.Select("name").Where("id__gt", 1).Order("-tb2.name").SeekPage(&rows, "", 10)
+	if err != nil || sp.Next != "" || sp.Prev != "" || executor.query != "select `table1`.`name`,`orm_tb2`.`name` as `tb2_name`,"+
+		"`table1`.`id` from `table1` left join `table2` as `orm_tb2` on `table1`.`id`=`orm_tb2`.`id` "+
+		"where `table1`.`id`>1 order by `orm_tb2`.`name` desc,`table1`.`id` desc limit 11" {
+		t.Fatalf("seek first page error: %s %v", executor.query, err)
+	}
+
+	sign := dao.seekSign(dao.seekKeys())
+	next, err := encodeCursor([]interface{}{[]byte("a"), int64(5)}, false, sign)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = dao.SeekPage(&rows, next, 10)
+	if err != nil || !strings.Contains(executor.query, "where ((`orm_tb2`.`name`,`table1`.`id`)<('a',5)) and `table1`.`id`>1 "+
+		"order by `orm_tb2`.`name` desc,`table1`.`id` desc limit 11") {
+		t.Fatalf("seek next page error: %s %v", executor.query, err)
+	}
+
+	prev, _ := encodeCursor([]interface{}{"a", int64(5)}, true, sign)
+	_, err = dao.SeekPage(&rows, prev, 10)
+	if err != nil || !strings.Contains(executor.query, "where ((`orm_tb2`.`name`,`table1`.`id`)>('a',5)) and `table1`.`id`>1 "+
+		"order by `orm_tb2`.`name` asc,`table1`.`id` asc limit 11") {
+		t.Fatalf("seek prev page error: %s %v", executor.query, err)
+	}
+
+	if _, err = dao.SeekPage(&rows, next[:len(next)-2]+"xx", 10); err != ErrCursor {
+		t.Fatalf("tampered cursor error: %v", err)
+	}
+	if _, err = dao.Order("name").SeekPage(&rows, next, 10); err != ErrCursor {
+		t.Fatalf("cursor of other order error: %v", err)
+	}
+
+	// 不支持行值比较，或排序方向不一致时使用 or 展开
+	sqlserverRef := testRef(dbtype.SQLServer, "")
+	dao = NewORM(context.Background(), "table1", executor, sqlserverRef).Order("tb2.name", "-id")
+	next, _ = encodeCursor([]interface{}{"a", int64(5)}, false, dao.seekSign(dao.seekKeys()))
+	_, err = dao.SeekPage(&rows, next, 10)
+	if err != nil || !strings.Contains(executor.query, "where (([orm_tb2].[name]>'a') or ([orm_tb2].[name]='a' and [table1].[id]<5)) "+
+		"order by [orm_tb2].[name] asc,[table1].[id] desc") {
+		t.Fatalf("seek or condition error: %s %v", executor.query, err)
+	}
+
+	keys := dao.seekKeys()
+	values := seekRowValues(reflect.ValueOf(map[string]interface{}{"tb2_name": "b", "id": int64(7)}), keys, nil)
+	if !reflect.DeepEqual(values, []interface{}{"b", int64(7)}) {
+		t.Fatalf("seek map values error: %v", values)
+	}
+	if _, err = dao.Order("#name").SeekPage(&rows, "", 10); err == nil {
+		t.Fatalf("seek raw order column should return error")
+	}
+}
+
+func TestORM_OrderNulls(t *testing.T) {
+	for _, c := range []struct {
+		tp    int
+		order string
+	}{
+		{dbtype.MySQL, "order by field(`orm_tb2`.`name`,'a','b') desc,case when `orm_tb2`.`name` is null then 1 else 0 end," +
+			"`orm_tb2`.`name` desc,case when `table1`.`name` is null then 0 else 1 end,`table1`.`name` asc,`table1`.`id` asc"},
+		{dbtype.SQLServer, "order by case [orm_tb2].[name] when 'b' then 0 when 'a' then 1 else 2 end,case when [orm_tb2].[name] is null then 1 else 0 end," +
+			"[orm_tb2].[name] desc,case when [table1].[name] is null then 0 else 1 end,[table1].[name] asc,[table1].[id] asc"},
+		{dbtype.Postgres, `order by case "orm_tb2"."name" when 'b' then 0 when 'a' then 1 else 2 end,` +
+			`"orm_tb2"."name" desc nulls last,"table1"."name" asc nulls first,"table1"."id" asc`},
+	} {
+		ref := testRef(c.tp, "")
+
+		dao := NewORM(context.Background(), "table1", &fakeExecutor{}, ref)
+		s := dao.Select("id").Order("-tb2.name|nulls_last", "+name|nulls_first", "id").OrderByValues("tb2.name", "b", "a").ToSQL(true)
+		if !strings.HasSuffix(s, c.order) {
+			t.Fatalf("order error: %s", s)
+		}
+	}
+
+	ref := testRef(dbtype.SQLite3, "3.28")
+	s := NewORM(context.Background(), "table1", &fakeExecutor{}, ref).Select("id").Order("-ref_id|month|nulls_first").ToSQL(true)
+	if s != `select "table1"."id" from "table1" order by case when CAST(strftime('%m',"table1"."ref_id") AS INTEGER) is null then 0 else 1 end,`+
+		`CAST(strftime('%m',"table1"."ref_id") AS INTEGER) desc` {
+		t.Fatalf("sqlite order error: %s", s)
+	}
+}
+
+type GroupingStat struct {
+	RefID        *int64  `json:"ref_id"`
+	TB2Name      *string `json:"tb2_name"`
+	C            int64   `json:"c"`
+	NameGrouping int     `json:"tb2_name_grouping"`
+}
+
+func TestORM_Grouping(t *testing.T) {
+	for _, c := range []struct {
+		tp    int
+		group string
+	}{
+		{dbtype.MySQL, "group by `table1`.`ref_id`,`orm_tb2`.`name` with rollup"},
+		{dbtype.SQLServer, "group by rollup([table1].[ref_id],[orm_tb2].[name])"},
+		{dbtype.Postgres, `group by rollup("table1"."ref_id","orm_tb2"."name")`},
+	} {
+		ref := testRef(c.tp, "")
+
+		executor := &fakeExecutor{}
+		dao := NewORM(context.Background(), "table1", executor, ref)
+		var result []GroupingStat
+		err := dao.Select("ref_id", "tb2.name", "#count(*) c").SelectExpr(Grouping("tb2.name")).
+			GroupByRollup("ref_id", "tb2.name").ToData(&result, true)
+		if err != nil || !strings.HasSuffix(executor.query, c.group) ||
+			!strings.Contains(executor.query, "GROUPING(") || !strings.Contains(executor.query, "tb2_name_grouping") {
+			t.Fatalf("rollup error: %s %v", executor.query, err)
+		}
+	}
+
+	ref := testRef(dbtype.Postgres, "")
+	dao := NewORM(context.Background(), "table1", &fakeExecutor{}, ref)
+	s := dao.Select("#count(*) c").GroupBy("name").GroupingSets([]string{"ref_id", "tb2.name"}, nil).ToSQL(true)
+	if !strings.HasSuffix(s, `group by "table1"."name",grouping sets(("table1"."ref_id","orm_tb2"."name"),())`) {
+		t.Fatalf("grouping sets error: %s", s)
+	}
+	s = dao.ClearCache().Select("#count(*) c").GroupByCube("ref_id", "name").ToSQL(true)
+	if !strings.HasSuffix(s, `group by cube("table1"."ref_id","table1"."name")`) {
+		t.Fatalf("cube error: %s", s)
+	}
+
+	mysqlRef := testRef(dbtype.MySQL, "")
+	var result []map[string]interface{}
+	err := NewORM(context.Background(), "table1", &fakeExecutor{}, mysqlRef).GroupByCube("ref_id").ToData(&result, true)
+	if err != ErrDBFunc {
+		t.Fatalf("mysql cube error: %v", err)
+	}
+
+	sqliteRef := testRef(dbtype.SQLite3, "")
+	err = NewORM(context.Background(), "table1", &fakeExecutor{}, sqliteRef).GroupByRollup("ref_id").ToData(&result, true)
+	if err != ErrDBFunc {
+		t.Fatalf("sqlite rollup error: %v", err)
+	}
+
+	for _, r := range []*Reference{testRef(dbtype.MySQL, "5.7.30"), testRef(dbtype.MariaDB, ""), sqliteRef} {
+		err = NewORM(context.Background(), "table1", &fakeExecutor{}, r).Select("ref_id").
+			SelectExpr(Grouping("ref_id")).GroupBy("ref_id").ToData(&result, true)
+		if err != ErrDBFunc {
+			t.Fatalf("grouping function error: %v", err)
+		}
+	}
+}
@@ -190,7 +190,8 @@ func toFirstDataMap(ctx context.Context, sqlDB db.BaseExecutor, q *BaseQuery, fl
 	var rows db.Rows
 	var err error
 	if sqlDB != nil {
-		rows, err = sqlDB.QueryContext(ctx, q.SQL())
+		sqlStr, args := q.build()
+		rows, err = queryContext(ctx, sqlDB, sqlStr, args)
 	} else {
 		return nil, ErrClient
 	}
@@ -204,7 +205,7 @@ func toFirstDataMap(ctx context.Context, sqlDB db.BaseExecutor, q *BaseQuery, fl
 		return nil, err
 	}
 
-	if result.Len() <= 0 {
+	if result == nil || result.Len() <= 0 {
 		return nil, nil
 	}
 
@@ -226,7 +227,8 @@ func toListDataMap(ctx context.Context, sqlDB db.BaseExecutor, q *BaseQuery, fla
 	var rows db.Rows
 	var err error
 	if sqlDB != nil {
-		rows, err = sqlDB.QueryContext(ctx, q.SQL())
+		sqlStr, args := q.build()
+		rows, err = queryContext(ctx, sqlDB, sqlStr, args)
 	} else {
 		return nil, ErrClient
 	}
@@ -247,7 +249,7 @@ func toListDataMap(ctx context.Context, sqlDB db.BaseExecutor, q *BaseQuery, fla
 		return nil, err
 	}
 
-	if result.Len() <= 0 {
+	if result == nil || result.Len() <= 0 {
 		return nil, nil
 	}
 
@@ -356,7 +358,8 @@ func toListStruct(ctx context.Context, sqlDB db.BaseExecutor, q *BaseQuery, flat
 	var rows db.Rows
 	var err error
 	if sqlDB != nil {
-		rows, err = sqlDB.QueryContext(ctx, q.SQL())
+		sqlStr, args := q.build()
+		rows, err = queryContext(ctx, sqlDB, sqlStr, args)
 	} else {
 		return nil, ErrClient
 	}
@@ -377,7 +380,7 @@ func toListStruct(ctx context.Context, sqlDB db.BaseExecutor, q *BaseQuery, flat
 		return nil, err
 	}
 
-	if result.Len() <= 0 {
+	if result == nil || result.Len() <= 0 {
 		return nil, nil
 	}
 	return result, nil
@@ -399,7 +402,8 @@ func toFirstStruct(ctx context.Context, sqlDB db.BaseExecutor, q *BaseQuery, fla
 	var rows db.Rows
 	var err error
 	if sqlDB != nil {
-		rows, err = sqlDB.QueryContext(ctx, q.SQL())
+		sqlStr, args := q.build()
+		rows, err = queryContext(ctx, sqlDB, sqlStr, args)
 	} else {
 		return nil, ErrClient
 	}
@@ -413,7 +417,7 @@ func toFirstStruct(ctx context.Context, sqlDB db.BaseExecutor, q *BaseQuery, fla
 		return nil, err
 	}
 
-	if result.Len() <= 0 {
+	if result == nil || result.Len() <= 0 {
 		return nil, nil
 	}
 
@@ -549,7 +553,8 @@ func toFirstSingleData(ctx context.Context, sqlDB db.BaseExecutor, q *BaseQuery,
 
 	var rows db.Rows
 	if sqlDB != nil {
-		rows, err = sqlDB.QueryContext(ctx, q.SQL())
+		sqlStr, args := q.build()
+		rows, err = queryContext(ctx, sqlDB, sqlStr, args)
 	} else {
 		return nil, ErrClient
 	}
@@ -563,7 +568,7 @@ func toFirstSingleData(ctx context.Context, sqlDB db.BaseExecutor, q *BaseQuery,
 		return nil, err
 	}
 
-	if result.Len() <= 0 {
+	if result == nil || result.Len() <= 0 {
 		return nil, nil
 	}
 
@@ -578,7 +583,8 @@ func toListSingleData(ctx context.Context, sqlDB db.BaseExecutor, q *BaseQuery,
 
 	var rows db.Rows
 	if sqlDB != nil {
-		rows, err = sqlDB.QueryContext(ctx, q.SQL())
+		sqlStr, args := q.build()
+		rows, err = queryContext(ctx, sqlDB, sqlStr, args)
 	} else {
 		return nil, ErrClient
 	}
@@ -599,7 +605,7 @@ func toListSingleData(ctx context.Context, sqlDB db.BaseExecutor, q *BaseQuery,
 		return nil, err
 	}
 
-	if result.Len() <= 0 {
+	if result == nil || result.Len() <= 0 {
 		return nil, nil
 	}
 	return result, nil
@@ -659,3 +665,87 @@ func scanSingleList(rows db.Rows, cacheLen int, tp reflect.Type) (result *reflec
 
 	return result, nil
 }
+
+// toValuesMap 两个字段的结果转为 map，第一个字段为 key，第二个字段为 value
+func toValuesMap(ctx context.Context, sqlDB db.BaseExecutor, q *BaseQuery, mapType reflect.Type) (ret *reflect.Value, err error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	var rows db.Rows
+	if sqlDB != nil {
+		sqlStr, args := q.build()
+		rows, err = queryContext(ctx, sqlDB, sqlStr, args)
+	} else {
+		return nil, ErrClient
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := scanValuesMap(rows, mapType)
+	if err != nil {
+		return nil, err
+	}
+
+	if result == nil || result.Len() <= 0 {
+		return nil, nil
+	}
+	return result, nil
+}
+
+// scanValuesMap 转换规则与 scanSingleList 一致，NULL 为零值
+func scanValuesMap(rows db.Rows, mapType reflect.Type) (result *reflect.Value, err error) {
+	if rows != nil {
+		defer func(rows db.Rows) {
+			closeErr := rows.Close()
+			if err != nil {
+				err = fmt.Errorf("%w %v", err, closeErr)
+			} else {
+				err = closeErr
+			}
+		}(rows)
+	} else {
+		return nil, nil
+	}
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	if len(cols) < 2 {
+		return nil, ErrTooFewColumn
+	}
+	if len(cols) > 2 {
+		return nil, ErrTooManyColumn
+	}
+
+	m := reflect.MakeMap(mapType)
+	result = &m
+
+	keyType, valType := mapType.Key(), mapType.Elem()
+	nullKey, nullVal := reflect.Zero(keyType), reflect.Zero(valType)
+	key, val := reflect.New(reflect.PtrTo(keyType)), reflect.New(reflect.PtrTo(valType))
+	for {
+		if !rows.Next() {
+			break
+		}
+
+		err = rows.Scan(key.Interface(), val.Interface())
+		if err != nil {
+			return nil, err
+		}
+
+		k, v := reflect.Indirect(reflect.Indirect(key)), reflect.Indirect(reflect.Indirect(val))
+		if !k.IsValid() {
+			k = nullKey
+		}
+		if !v.IsValid() {
+			v = nullVal
+		}
+		m.SetMapIndex(k, v)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,128 @@
+package orm
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/assembly-hub/db"
+)
+
+// Pluck 查询单个字段到切片，dest 为切片的指针，如：var ids []int64; tb.Where("tb2.name", "a").Pluck("id", &ids)，
+// col 的规则与 Select 一致（支持 tag、json 路径、日期函数、# 原始字段），类型转换与 ToData 的单值切片一致，NULL 为零值，集合运算（Union 等）的查询返回 ErrSetQuery
+func (orm *ORM) Pluck(col string, dest interface{}) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			switch p := p.(type) {
+			case error:
+				err = p
+			default:
+				err = fmt.Errorf("%v", p)
+			}
+		}
+	}()
+
+	dataValue := reflect.ValueOf(dest)
+	if dest == nil || dataValue.Kind() != reflect.Ptr || dataValue.IsNil() || dataValue.Elem().Kind() != reflect.Slice {
+		return ErrTargetNotSettable
+	}
+
+	if orm.customSQL != "" {
+		return ErrCustomSQL
+	}
+
+	if len(orm.Q.SetQuery) > 0 {
+		return ErrSetQuery
+	}
+
+	if !orm.keepQuery {
+		defer func() {
+			orm.ClearCache()
+		}()
+	}
+
+	var sqlDB db.BaseExecutor = orm.tx
+	if sqlDB == nil {
+		sqlDB = orm.executor
+	}
+
+	dataValue = dataValue.Elem()
+	ret, err := toListSingleData(orm.ctx, sqlDB, orm.valuesQuery(col), dataValue.Type().Elem())
+	if err != nil {
+		return err
+	}
+
+	if ret != nil {
+		dataValue.Set(*ret)
+	}
+	return nil
+}
+
+// ValuesMap 查询两个字段到 map，keyCol 为 key，valCol 为 value，dest 为 map 的指针，如：
+// var names map[int64]string; tb.ValuesMap("id", "tb2.name", &names)，字段规则与 Pluck 一致，key 重复时保留最后一条；
+// 集合运算（Union 等）的查询返回 ErrSetQuery
+func (orm *ORM) ValuesMap(keyCol, valCol string, dest interface{}) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			switch p := p.(type) {
+			case error:
+				err = p
+			default:
+				err = fmt.Errorf("%v", p)
+			}
+		}
+	}()
+
+	dataValue := reflect.ValueOf(dest)
+	if dest == nil || dataValue.Kind() != reflect.Ptr || dataValue.IsNil() || dataValue.Elem().Kind() != reflect.Map {
+		return ErrTargetNotSettable
+	}
+
+	if orm.customSQL != "" {
+		return ErrCustomSQL
+	}
+
+	if len(orm.Q.SetQuery) > 0 {
+		return ErrSetQuery
+	}
+
+	if !orm.keepQuery {
+		defer func() {
+			orm.ClearCache()
+		}()
+	}
+
+	var sqlDB db.BaseExecutor = orm.tx
+	if sqlDB == nil {
+		sqlDB = orm.executor
+	}
+
+	dataValue = dataValue.Elem()
+	ret, err := toValuesMap(orm.ctx, sqlDB, orm.valuesQuery(keyCol, valCol), dataValue.Type())
+	if err != nil {
+		return err
+	}
+
+	if ret != nil {
+		dataValue.Set(*ret)
+	}
+	return nil
+}
+
+// valuesQuery 只查询指定字段（不使用别名）
+func (orm *ORM) valuesQuery(cols ...string) *BaseQuery {
+	for _, col := range cols {
+		if col == "" {
+			panic("column cannot be empty")
+		}
+	}
+
+	q := orm.cond(true)
+	q.SelectRaw = true
+	q.Select = cols
+	q.SelectExpr = nil
+	q.BindParams = orm.bindParams
+	if len(q.Limit) <= 0 && orm.limit > 0 {
+		q.Limit = []uint{orm.limit}
+	}
+	return q
+}
@@ -1,10 +1,11 @@
 package orm
 
 import (
+	"strconv"
 	"strings"
 )
 
-func (p *queryModel) postgresIgnoreFormatSubSQL(colOperator string, colName string, val, rawVal string, rawStrArr []string,
+func postgresIgnoreFormatSubSQL(colOperator string, colName string, val, rawVal string, rawStrArr []string,
 	colData interface{}) string {
 	var subSQL strings.Builder
 	subSQL.Grow(20)
@@ -32,14 +33,14 @@ func (p *queryModel) postgresIgnoreFormatSubSQL(colOperator string, colName stri
 		val = connectStrArr(rawStrArr, ",", "LOWER('", "')")
 		subSQL.WriteString(" not in (")
 	default:
-		return p.postgresIgnoreLikeSQL(colOperator, colName, val, rawVal, rawStrArr)
+		return postgresIgnoreLikeSQL(colOperator, colName, val, rawVal, rawStrArr)
 	}
 	subSQL.WriteString(val)
 	subSQL.WriteByte(')')
 	return subSQL.String()
 }
 
-func (p *queryModel) postgresIgnoreLikeSQL(colOperator string, colName string, val, rawVal string, rawStrArr []string) string {
+func postgresIgnoreLikeSQL(colOperator string, colName string, val, rawVal string, rawStrArr []string) string {
 	var subSQL strings.Builder
 	subSQL.Grow(20)
 	switch colOperator {
@@ -98,12 +99,12 @@ func (p *queryModel) postgresIgnoreLikeSQL(colOperator string, colName string, v
 			subSQL.WriteByte(')')
 		}
 	default:
-		return p.postgresIgnoreOrLikeSQL(colOperator, colName, val, rawVal, rawStrArr)
+		return postgresIgnoreOrLikeSQL(colOperator, colName, val, rawVal, rawStrArr)
 	}
 	return subSQL.String()
 }
 
-func (p *queryModel) postgresIgnoreOrLikeSQL(colOperator string, colName string, val, rawVal string, rawStrArr []string) string {
+func postgresIgnoreOrLikeSQL(colOperator string, colName string, val, rawVal string, rawStrArr []string) string {
 	var subSQL strings.Builder
 	subSQL.Grow(20)
 	switch colOperator {
@@ -188,7 +189,93 @@ func (p *queryModel) postgresIgnoreOrLikeSQL(colOperator string, colName string,
 			subSQL.WriteByte(')')
 		}
 	default:
-		panic("no definition")
+		panic("is not defined")
 	}
 	return subSQL.String()
 }
+
+// postgresDialect postgres，$n 占位符，upsert 使用 on conflict
+type postgresDialect struct {
+	BaseDialect
+}
+
+func (d postgresDialect) Placeholder(n int) string {
+	return "$" + strconv.Itoa(n)
+}
+
+func (d postgresDialect) IgnoreCondition(c *Condition) (string, bool) {
+	return postgresIgnoreFormatSubSQL(c.Operator, c.Column, c.Value, c.Raw, c.RawList, c.Data), true
+}
+
+// Regex ~ 区分大小写，~* 忽略大小写，! 为不匹配
+func (d postgresDialect) Regex(column, pattern string, flag RegexFlag) (string, error) {
+	op := "~"
+	if flag.Has(RegexIgnoreCase) {
+		op = "~*"
+	}
+	if flag.Has(RegexNot) {
+		op = "!" + op
+	}
+	return column + " " + op + " " + pattern, nil
+}
+
+// Search 使用默认的分词配置（default_text_search_config），中文需要安装分词插件，如：zhparser
+func (d postgresDialect) Search(column, query string) (string, error) {
+	return "to_tsvector(" + column + ") @@ plainto_tsquery(" + query + ")", nil
+}
+
+func (d postgresDialect) SearchRank(column, query string) (string, error) {
+	return "ts_rank(to_tsvector(" + column + "),plainto_tsquery(" + query + "))", nil
+}
+
+func (d postgresDialect) DatePart(column, part string) (string, error) {
+	field := strings.ToUpper(part)
+	if part == "weekday" {
+		field = "ISODOW"
+	}
+	return "EXTRACT(" + field + " FROM " + column + ")::int", nil
+}
+
+// DateTrunc date_trunc，week 为周一
+func (d postgresDialect) DateTrunc(column, unit string) (string, error) {
+	return "date_trunc('" + unit + "'," + column + ")", nil
+}
+
+// JSONPath 字段::jsonb#>>'{"a","b"}'
+func (d postgresDialect) JSONPath(column string, path []string) string {
+	var buf strings.Builder
+	buf.Grow(len(column) + len(path)*10 + 20)
+	buf.WriteByte('(')
+	buf.WriteString(column)
+	buf.WriteString("::jsonb#>>'{")
+	for i, k := range path {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteByte('"')
+		buf.WriteString(strings.ReplaceAll(k, `"`, `\"`))
+		buf.WriteByte('"')
+	}
+	buf.WriteString("}')")
+	return buf.String()
+}
+
+func (d postgresDialect) JSONContains(column, value string) (string, error) {
+	return column + "::jsonb@>" + value + "::jsonb", nil
+}
+
+func (d postgresDialect) JSONHasKey(column, key string) (string, error) {
+	return column + "::jsonb?'" + key + "'", nil
+}
+
+func (d postgresDialect) RowCompare(left, right []string, op string) (string, error) {
+	return rowCompareSQL(left, right, op), nil
+}
+
+func (d postgresDialect) Upsert(w *WriteParts) (string, error) {
+	return conflictUpsertSQL(d.EscStart, d.EscEnd, w)
+}
+
+func (d postgresDialect) LastInsertID() bool {
+	return false
+}
@@ -8,8 +8,6 @@ import (
 	"time"
 
 	"github.com/assembly-hub/basics/util"
-
-	"github.com/assembly-hub/orm/dbtype"
 )
 
 type selectModel struct {
@@ -17,9 +15,14 @@ type selectModel struct {
 	Cols  []string
 }
 
+// colValue 格式化后的字段引用（Col），直接拼接在SQL中
+type colValue string
+
 type orderModel struct {
 	Table string
 	Cols  []string
+	// Nulls 与 Cols 对应的空值位置：空、first、last
+	Nulls []string
 }
 
 type joinModel struct {
@@ -40,11 +43,31 @@ type queryModel struct {
 	SelectForUpdate bool
 	Select          []*selectModel
 	Order           []*orderModel
+	SearchOrder     [][2]string
+	ValuesOrder     []*RawSQL
+	OrderExpr       []*RawSQL
+	SelectExpr      []*RawSQL
+	GroupByExpr     []*RawSQL
 	Limit           []uint
 	Where           map[string]interface{}
 	JoinList        []*joinModel
 	GroupBy         []string
+	Grouping        *groupingModel
 	Having          map[string]interface{}
+	// 条件值以占位标记代替，由 resolveBind 转换为数据库占位符
+	BindParams bool
+	// ClickHouse 查询修饰
+	Final       bool
+	Sample      string
+	LimitBy     []uint
+	LimitByCols []string
+	Settings    string
+
+	// FromQuery 派生表的子查询，MainAlias 为其别名
+	FromQuery *queryModel
+	With      []*withModel
+	// SetQuery 集合运算的查询，第一个为主查询，MainAlias 为结果的别名
+	SetQuery []*setModel
 }
 
 func (p *queryModel) selectSQL() string {
@@ -63,6 +86,22 @@ func (p *queryModel) selectSQL() string {
 			}
 		}
 	}
+	for _, r := range p.SelectExpr {
+		if sqlBuff.Len() > 0 {
+			sqlBuff.WriteByte(',')
+		}
+		sqlBuff.WriteString(p.rawSQL(r))
+		alias := r.alias
+		if alias == "" {
+			alias = r.name
+		}
+		if alias != "" {
+			sqlBuff.WriteString(" as ")
+			sqlBuff.WriteString(p.DBCore.EscStart)
+			sqlBuff.WriteString(alias)
+			sqlBuff.WriteString(p.DBCore.EscEnd)
+		}
+	}
 
 	if sqlBuff.Len() <= 0 {
 		sqlBuff.WriteByte('*')
@@ -87,21 +126,10 @@ func (p *queryModel) joinSQL() string {
 			joinStr = join.Type.value() + " "
 		}
 		if join.JoinAlias != "" {
-			if p.DBCore.DBType == dbtype.Oracle {
-				sqlBuff.WriteByte(' ')
-				sqlBuff.WriteString(joinStr)
-				sqlBuff.WriteString("join ")
-				sqlBuff.WriteString(join.JoinTable)
-				sqlBuff.WriteByte(' ')
-				sqlBuff.WriteString(join.JoinAlias)
-			} else {
-				sqlBuff.WriteByte(' ')
-				sqlBuff.WriteString(joinStr)
-				sqlBuff.WriteString("join ")
-				sqlBuff.WriteString(join.JoinTable)
-				sqlBuff.WriteString(" as ")
-				sqlBuff.WriteString(join.JoinAlias)
-			}
+			sqlBuff.WriteByte(' ')
+			sqlBuff.WriteString(joinStr)
+			sqlBuff.WriteString("join ")
+			sqlBuff.WriteString(p.DBCore.Dialect.TableAlias(join.JoinTable, join.JoinAlias))
 		} else {
 			sqlBuff.WriteByte(' ')
 			sqlBuff.WriteString(joinStr)
@@ -144,14 +172,31 @@ func (p *queryModel) joinSQL() string {
 func (p *queryModel) orderSQL() string {
 	var sql strings.Builder
 	sql.Grow(50)
+	for _, so := range p.SearchOrder {
+		val, _, _ := p.formatSQLValue("search", so[0], so[1])
+		rank, err := p.DBCore.Dialect.SearchRank(so[0], val)
+		if err != nil {
+			panic(err)
+		}
+
+		if sql.Len() > 0 {
+			sql.WriteByte(',')
+		}
+		sql.WriteString(rank)
+		sql.WriteString(" desc")
+	}
+	for _, r := range p.ValuesOrder {
+		if sql.Len() > 0 {
+			sql.WriteByte(',')
+		}
+		sql.WriteString(p.rawSQL(r))
+	}
 	for _, sel := range p.Order {
-		for _, col := range sel.Cols {
-			if col[0] == '-' {
-				col = col[1:] + " desc"
-			} else if col[0] == '+' {
-				col = col[1:] + " asc"
-			} else {
-				col = col + " asc"
+		for i, col := range sel.Cols {
+			desc := false
+			if col[0] == '-' || col[0] == '+' {
+				desc = col[0] == '-'
+				col = col[1:]
 			}
 
 			if sel.Table != "" {
@@ -161,13 +206,43 @@ func (p *queryModel) orderSQL() string {
 				sql.WriteByte(',')
 			}
 
-			sql.WriteString(col)
+			if i < len(sel.Nulls) && sel.Nulls[i] != "" {
+				sql.WriteString(p.DBCore.Dialect.NullsOrder(col, desc, sel.Nulls[i] == "first"))
+			} else if desc {
+				sql.WriteString(col + " desc")
+			} else {
+				sql.WriteString(col + " asc")
+			}
 		}
 	}
 
+	for _, r := range p.OrderExpr {
+		if sql.Len() > 0 {
+			sql.WriteByte(',')
+		}
+		sql.WriteString(p.rawSQL(r))
+	}
+
 	return sql.String()
 }
 
+func (p *queryModel) groupSQL() string {
+	groupBy := util.JoinArr(p.GroupBy, ",")
+	if len(p.GroupByExpr) > 0 {
+		cols := make([]string, 0, len(p.GroupBy)+len(p.GroupByExpr))
+		cols = append(cols, p.GroupBy...)
+		for _, r := range p.GroupByExpr {
+			cols = append(cols, p.rawSQL(r))
+		}
+		groupBy = util.JoinArr(cols, ",")
+	}
+
+	if p.Grouping != nil {
+		return p.groupingSQL(groupBy)
+	}
+	return groupBy
+}
+
 func (p *queryModel) orSQL(where map[string]interface{}) string {
 	return p.whereSQL(where, " or ")
 }
@@ -180,7 +255,7 @@ func (p *queryModel) formatSubSQL(colOperator string, colName string, val, rawVa
 	colData interface{}) string {
 	subSQL := ""
 
-	opArr := strings.Split(colOperator, "_")
+	opArr := strings.SplitN(colOperator, "_", 2)
 
 	if len(opArr) == 0 {
 		panic(fmt.Errorf("operator[] cannot be empty"))
@@ -188,6 +263,9 @@ func (p *queryModel) formatSubSQL(colOperator string, colName string, val, rawVa
 
 	defer func() {
 		if e := recover(); e != nil {
+			if err, ok := e.(error); ok {
+				panic(fmt.Errorf("field[%s]'s operator[%s] %w", colName, colOperator, err))
+			}
 			panic(fmt.Sprintf("field[%s]'s operator[%s] %v", colName, colOperator, e))
 		}
 	}()
@@ -199,13 +277,15 @@ func (p *queryModel) formatSubSQL(colOperator string, colName string, val, rawVa
 	} else if opArr[0] == "ignore" || opArr[0] == "i" {
 		subSQL = p.innerIgnoreFormatSubSQL(opArr[1], colName, val, rawVal, rawStrArr, colData)
 	} else {
-		panic(fmt.Errorf("operator[%s] is not supported", colOperator))
+		// 操作符本身包含下划线，如：json_contains has_key
+		subSQL = p.innerFormatSubSQL(colOperator, colName, val, rawVal, rawStrArr, colData)
 	}
 
 	return subSQL
 }
 
 func (p *queryModel) formatTimeValue(colOperator, colName string, colData interface{}) (val string, rawVal string, rawStrArr []string) {
+	dialect := p.DBCore.Dialect
 	switch colData := colData.(type) {
 	case time.Time, *time.Time:
 		if colOperator == "between" {
@@ -213,29 +293,17 @@ func (p *queryModel) formatTimeValue(colOperator, colName string, colData interf
 		}
 
 		rawVal = time2Str(colData)
-		if p.DBCore.DBType == dbtype.Oracle {
-			val = oracleDateTime(rawVal, false)
-		} else {
-			val = innerDateTime(rawVal, colOperator)
-		}
+		val = dialect.TimeLiteral(innerDateTime(rawVal, colOperator))
 	case []time.Time:
 		if colOperator == "between" {
 			if len(colData) != 2 {
 				panic(ErrBetweenValueMatch)
 			}
-			if p.DBCore.DBType == dbtype.Oracle {
-				val = oracleDateTime(time2Str(colData[0]), false) + " and " +
-					oracleDateTime(time2Str(colData[1]), false)
-			} else {
-				val = "'" + time2Str(colData[0]) + "' and '" + time2Str(colData[1]) + "'"
-			}
+			val = dialect.TimeLiteral("'"+time2Str(colData[0])+"'") + " and " +
+				dialect.TimeLiteral("'"+time2Str(colData[1])+"'")
 		} else {
 			for _, v := range colData {
-				if p.DBCore.DBType == dbtype.Oracle {
-					rawStrArr = append(rawStrArr, oracleDateTime(time2Str(v), false))
-				} else {
-					rawStrArr = append(rawStrArr, "'"+time2Str(v)+"'")
-				}
+				rawStrArr = append(rawStrArr, dialect.TimeLiteral("'"+time2Str(v)+"'"))
 			}
 			val = "(" + util.JoinArr[string](rawStrArr, ",") + ")"
 		}
@@ -244,19 +312,11 @@ func (p *queryModel) formatTimeValue(colOperator, colName string, colData interf
 			if len(colData) != 2 {
 				panic(ErrBetweenValueMatch)
 			}
-			if p.DBCore.DBType == dbtype.Oracle {
-				val = oracleDateTime(time2Str(colData[0]), false) + " and " +
-					oracleDateTime(time2Str(colData[1]), false)
-			} else {
-				val = "'" + time2Str(colData[0]) + "' and '" + time2Str(colData[1]) + "'"
-			}
+			val = dialect.TimeLiteral("'"+time2Str(colData[0])+"'") + " and " +
+				dialect.TimeLiteral("'"+time2Str(colData[1])+"'")
 		} else {
 			for _, v := range colData {
-				if p.DBCore.DBType == dbtype.Oracle {
-					rawStrArr = append(rawStrArr, oracleDateTime(time2Str(v), false))
-				} else {
-					rawStrArr = append(rawStrArr, "'"+time2Str(v)+"'")
-				}
+				rawStrArr = append(rawStrArr, dialect.TimeLiteral("'"+time2Str(v)+"'"))
 			}
 			val = "(" + util.JoinArr[string](rawStrArr, ",") + ")"
 		}
@@ -265,6 +325,24 @@ func (p *queryModel) formatTimeValue(colOperator, colName string, colData interf
 }
 
 func (p *queryModel) formatSQLValue(colOperator, colName string, colData interface{}) (val string, rawVal string, rawStrArr []string) {
+	if colOperator == "json_contains" {
+		colData = jsonValue(colData)
+	}
+
+	if c, ok := colData.(colValue); ok {
+		if !isCompareOperator(colOperator) {
+			panic(fmt.Errorf("operator[%s] does not support column value", colOperator))
+		}
+		val = string(c)
+		rawVal = val
+		return
+	}
+
+	// date 需要解析日期计算区间，值已经过时间格式校验，直接拼接即可；has_key 的 key 为 json path 的一部分
+	if p.BindParams && colOperator != "date" && colOperator != "has_key" {
+		return p.formatBindValue(colOperator, colName, colData)
+	}
+
 	switch colData := colData.(type) {
 	case queryModel:
 		if colOperator == "between" {
@@ -372,9 +450,145 @@ func (p *queryModel) formatSQLValue(colOperator, colName string, colData interfa
 	return
 }
 
+// formatBindValue 绑定参数模式下格式化数据，值以占位标记代替，like 等引号包裹的标记在 whereSQL 中合并
+func (p *queryModel) formatBindValue(colOperator, colName string, colData interface{}) (val string, rawVal string, rawStrArr []string) {
+	switch colData := colData.(type) {
+	case queryModel:
+		if colOperator == "between" {
+			panic(ErrBetweenValueMatch)
+		}
+
+		colData.BindParams = true
+		rawVal = colData.SQL()
+		if rawVal != "" {
+			val = "(" + rawVal + ")"
+		}
+	case *queryModel:
+		if colOperator == "between" {
+			panic(ErrBetweenValueMatch)
+		}
+
+		colData.BindParams = true
+		rawVal = colData.SQL()
+		if rawVal != "" {
+			val = "(" + rawVal + ")"
+		}
+	case string:
+		if colOperator == "between" {
+			panic(ErrBetweenValueMatch)
+		}
+
+		val = bindParam(colData)
+		if colData != "" {
+			rawVal = val
+		}
+	case []string:
+		if colOperator == "between" {
+			if len(colData) != 2 {
+				panic(ErrBetweenValueMatch)
+			}
+			val = bindParam(colData[0]) + " and " + bindParam(colData[1])
+		} else {
+			for _, v := range colData {
+				rawStrArr = append(rawStrArr, bindParam(v))
+			}
+			val = "(" + util.JoinArr(rawStrArr, ",") + ")"
+		}
+	case []interface{}:
+		if colOperator == "between" {
+			if len(colData) != 2 {
+				panic(ErrBetweenValueMatch)
+			}
+			val = p.bindElem(colData[0]) + " and " + p.bindElem(colData[1])
+		} else {
+			for _, vv := range colData {
+				rawStrArr = append(rawStrArr, p.bindElem(vv))
+			}
+			val = "(" + util.JoinArr(rawStrArr, ",") + ")"
+		}
+	case bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		if colOperator == "between" {
+			panic(ErrBetweenValueMatch)
+		}
+
+		val = bindParam(colData)
+		rawVal = val
+	case []int, []int8, []int16, []int32, []int64, []uint, []uint8, []uint16, []uint32, []uint64, []float32, []float64:
+		slice := reflect.ValueOf(colData)
+		if slice.Len() <= 0 {
+			panic(fmt.Sprintf("colName:[%s] slice not empty", colName))
+		}
+
+		if colOperator == "between" {
+			if slice.Len() != 2 {
+				panic(ErrBetweenValueMatch)
+			}
+			val = bindParam(slice.Index(0).Interface()) + " and " + bindParam(slice.Index(1).Interface())
+		} else {
+			arr := make([]string, 0, slice.Len())
+			for i := 0; i < slice.Len(); i++ {
+				arr = append(arr, bindParam(slice.Index(i).Interface()))
+			}
+			val = "(" + util.JoinArr(arr, ",") + ")"
+		}
+	case time.Time, *time.Time:
+		if colOperator == "between" {
+			panic(ErrBetweenValueMatch)
+		}
+
+		val = bindTime(p.DBCore.Dialect, time2Str(colData))
+		rawVal = val
+	case []time.Time:
+		if colOperator == "between" {
+			if len(colData) != 2 {
+				panic(ErrBetweenValueMatch)
+			}
+			val = bindTime(p.DBCore.Dialect, time2Str(colData[0])) + " and " + bindTime(p.DBCore.Dialect, time2Str(colData[1]))
+		} else {
+			for _, v := range colData {
+				rawStrArr = append(rawStrArr, bindTime(p.DBCore.Dialect, time2Str(v)))
+			}
+			val = "(" + util.JoinArr(rawStrArr, ",") + ")"
+		}
+	case []*time.Time:
+		if colOperator == "between" {
+			if len(colData) != 2 {
+				panic(ErrBetweenValueMatch)
+			}
+			val = bindTime(p.DBCore.Dialect, time2Str(colData[0])) + " and " + bindTime(p.DBCore.Dialect, time2Str(colData[1]))
+		} else {
+			for _, v := range colData {
+				rawStrArr = append(rawStrArr, bindTime(p.DBCore.Dialect, time2Str(v)))
+			}
+			val = "(" + util.JoinArr(rawStrArr, ",") + ")"
+		}
+	default:
+		if colOperator == "between" {
+			panic(ErrBetweenValueMatch)
+		}
+
+		val = bindParam(colData)
+		rawVal = val
+	}
+
+	return
+}
+
+// bindElem []interface{} 中的值，类型与单个值一致，时间由数据库方言包裹
+func (p *queryModel) bindElem(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return bindParam(fmt.Sprintf("%v", v))
+	case time.Time, *time.Time:
+		return bindTime(p.DBCore.Dialect, time2Str(v))
+	}
+	return bindParam(v)
+}
+
 func (p *queryModel) whereSQL(where map[string]interface{}, linker string) string {
 	sql := ""
-	for colKey, colData := range where {
+	for _, colKey := range sortedKeys(where) {
+		colData := where[colKey]
 		if colKey == "" || colData == nil {
 			continue
 		}
@@ -386,7 +600,11 @@ func (p *queryModel) whereSQL(where map[string]interface{}, linker string) strin
 		}
 
 		subSQL := ""
-		if colKey == "$or" {
+		if c, ok := colData.(*Cond); ok {
+			subSQL = p.condSQL(c)
+		} else if colKey == "$raw" {
+			subSQL = p.rawWhereSQL(rawList(colData))
+		} else if colKey == "$or" {
 			switch colData := colData.(type) {
 			case map[string]interface{}:
 				if len(colData) <= 0 {
@@ -465,7 +683,26 @@ func (p *queryModel) whereSQL(where map[string]interface{}, linker string) strin
 			}
 
 			val, rawVal, rawStrArr := p.formatSQLValue(colOperator, colName, colData)
-			subSQL = p.formatSubSQL(colOperator, colName, val, rawVal, rawStrArr, colData)
+			if name, fn, bin, ignore := lookupOperator(colOperator); fn != nil {
+				c := &Condition{
+					Operator: name,
+					Column:   colName,
+					Value:    val,
+					Raw:      rawVal,
+					RawList:  rawStrArr,
+					Data:     colData,
+					Bin:      bin,
+					Ignore:   ignore,
+				}
+				// 自定义操作符已处理取反
+				subSQL = p.customSubSQL(fn, c, colOperator, not)
+				not = false
+			} else {
+				subSQL = p.formatSubSQL(colOperator, colName, val, rawVal, rawStrArr, colData)
+			}
+			if p.BindParams {
+				subSQL = foldBind(subSQL)
+			}
 		}
 
 		if subSQL == "" {
@@ -498,17 +735,53 @@ func (p *queryModel) Count() string {
 	return p.countDB()
 }
 
-func (p *queryModel) SQL() string {
-	switch p.DBCore.DBType {
-	case dbtype.MySQL, dbtype.MariaDB, dbtype.OpenGauss,
-		dbtype.Postgres, dbtype.ClickHouse:
-		return p.queryMySQL()
-	case dbtype.SQLite2, dbtype.SQLite3:
-		return p.querySQLite()
-	case dbtype.SQLServer:
-		return p.querySQLServer()
-	case dbtype.Oracle:
-		return p.queryOracle()
+// selectParts 查询语句的各个部分，count 为 true 时不需要排序、分页与锁
+func (p *queryModel) selectParts(count bool) *SelectParts {
+	q := &SelectParts{
+		Columns:    p.selectSQL(),
+		Table:      p.mainTableSQL(),
+		Alias:      p.MainAlias,
+		Join:       p.joinSQL(),
+		Where:      p.andSQL(p.Where),
+		PrimaryKey: p.PrivateKey,
+		Final:      p.Final,
+		Sample:     p.Sample,
+		Settings:   p.Settings,
+	}
+
+	if len(p.LimitByCols) > 0 {
+		q.LimitBy = p.LimitBy
+		q.LimitByCols = util.JoinArr(p.LimitByCols, ",")
+	}
+
+	if groupBy := p.groupSQL(); groupBy != "" {
+		q.GroupBy = groupBy
+		if len(p.Having) > 0 {
+			q.Having = p.andSQL(p.Having)
+		}
 	}
-	return ""
+
+	if !count {
+		q.OrderBy = p.orderSQL()
+		q.Limit = p.Limit
+		q.ForUpdate = p.SelectForUpdate
+	}
+	return q
+}
+
+// mainTableSQL 主表，派生表为 (子查询)，集合运算为 (q union q1 ...)
+func (p *queryModel) mainTableSQL() string {
+	if len(p.SetQuery) > 0 {
+		return "(" + p.setSQL() + ")"
+	}
+	if p.FromQuery == nil {
+		return p.MainTable
+	}
+
+	p.FromQuery.BindParams = p.BindParams
+	return "(" + p.FromQuery.SQL() + ")"
+}
+
+func (p *queryModel) SQL() string {
+	return p.withSQL() + p.DBCore.Dialect.Select(p.selectParts(false))
 }
@@ -3,12 +3,11 @@ package orm
 import (
 	"fmt"
 	"reflect"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/assembly-hub/basics/util"
-
-	"github.com/assembly-hub/orm/dbtype"
 )
 
 func innerDateTime(s string, op string) string {
@@ -26,72 +25,18 @@ func innerDateTime(s string, op string) string {
 
 // query
 func (p *queryModel) countDB() string {
-	var sql strings.Builder
-	sql.Grow(100)
-
-	sql.WriteString("select ")
-	sel := p.selectSQL()
-	if p.MainTable == "" {
-		panic("MainTable is nil")
-	}
-	if p.MainAlias != "" {
-		if p.DBCore.DBType == dbtype.Oracle {
-			sql.WriteString(sel)
-			sql.WriteString(" from ")
-			sql.WriteString(p.MainTable)
-			sql.WriteByte(' ')
-			sql.WriteString(p.MainAlias)
-		} else {
-			sql.WriteString(sel)
-			sql.WriteString(" from ")
-			sql.WriteString(p.MainTable)
-			sql.WriteString(" as ")
-			sql.WriteString(p.MainAlias)
-		}
-	} else {
-		sql.WriteString(sel)
-		sql.WriteString(" from ")
-		sql.WriteString(p.MainTable)
-	}
-
-	join := p.joinSQL()
-	if join != "" {
-		sql.WriteString(join)
-	}
-
-	where := p.andSQL(p.Where)
-	if where != "" {
-		sql.WriteString(" where ")
-		sql.WriteString(where)
-	}
-
-	if len(p.GroupBy) > 0 {
-		sql.WriteString(" group by ")
-		sql.WriteString(util.JoinArr(p.GroupBy, ","))
-	}
+	dialect := p.DBCore.Dialect
+	rawSQL := dialect.Select(p.selectParts(true))
 
-	if len(p.GroupBy) > 0 && len(p.Having) > 0 {
-		sql.WriteString(" having ")
-		sql.WriteString(p.andSQL(p.Having))
-	}
-
-	rawSQL := sql.String()
-	sql.Reset()
+	var sql strings.Builder
 	sql.Grow(len(rawSQL) + 50)
+	sql.WriteString(p.withSQL())
 	sql.WriteString("SELECT COUNT(*) as ")
 	sql.WriteString(p.DBCore.EscStart)
 	sql.WriteByte('c')
 	sql.WriteString(p.DBCore.EscEnd)
-	sql.WriteString(" from (")
-	sql.WriteString(rawSQL)
-	if p.DBCore.DBType == dbtype.Oracle {
-		sql.WriteString(") ")
-	} else {
-		sql.WriteString(") as ")
-	}
-	sql.WriteString(p.DBCore.EscStart)
-	sql.WriteString("count_tb")
-	sql.WriteString(p.DBCore.EscEnd)
+	sql.WriteString(" from ")
+	sql.WriteString(dialect.TableAlias("("+rawSQL+")", p.DBCore.EscStart+"count_tb"+p.DBCore.EscEnd))
 	return sql.String()
 }
 
@@ -143,24 +88,13 @@ func (p *queryModel) innerFormatSubSQL(colOperator string, colName string, val,
 		nextDay := time2Str(str2Time(rawVal).Add(time.Hour * 24))
 		strDate := strings.Split(rawVal, " ")[0] + " 00:00:00"
 		strNext := strings.Split(nextDay, " ")[0] + " 00:00:00"
-		if p.DBCore.DBType == dbtype.Oracle {
-			subSQL.WriteString(colName)
-			subSQL.WriteString(">=")
-			subSQL.WriteString(oracleDateTime(strDate, false))
-			subSQL.WriteString(" and ")
-			subSQL.WriteString(colName)
-			subSQL.WriteString("<")
-			subSQL.WriteString(oracleDateTime(strNext, false))
-		} else {
-			subSQL.WriteString(colName)
-			subSQL.WriteString(">='")
-			subSQL.WriteString(strDate)
-			subSQL.WriteString("' and ")
-			subSQL.WriteString(colName)
-			subSQL.WriteString("<'")
-			subSQL.WriteString(strNext)
-			subSQL.WriteByte('\'')
-		}
+		subSQL.WriteString(colName)
+		subSQL.WriteString(">=")
+		subSQL.WriteString(p.DBCore.Dialect.TimeLiteral("'" + strDate + "'"))
+		subSQL.WriteString(" and ")
+		subSQL.WriteString(colName)
+		subSQL.WriteString("<")
+		subSQL.WriteString(p.DBCore.Dialect.TimeLiteral("'" + strNext + "'"))
 	case "null":
 		subSQL.WriteString(colName)
 		if colData.(bool) {
@@ -168,41 +102,157 @@ func (p *queryModel) innerFormatSubSQL(colOperator string, colName string, val,
 		} else {
 			subSQL.WriteString(" is not null")
 		}
+	case "regex", "iregex", "nregex":
+		return p.regexSQL(colOperator, colName, val, 0)
+	case "search":
+		sql, err := p.DBCore.Dialect.Search(colName, val)
+		if err != nil {
+			panic(err)
+		}
+		subSQL.WriteString(sql)
+	case "json_contains":
+		sql, err := p.DBCore.Dialect.JSONContains(colName, val)
+		if err != nil {
+			panic(err)
+		}
+		subSQL.WriteString(sql)
+	case "has_key":
+		if _, ok := colData.(string); !ok || rawVal == "" {
+			panic("has_key value must be a non-empty string")
+		}
+		sql, err := p.DBCore.Dialect.JSONHasKey(colName, rawVal)
+		if err != nil {
+			panic(err)
+		}
+		subSQL.WriteString(sql)
 	default:
+		if part, op, ok := datePartOperator(colOperator); ok {
+			return p.datePartSQL(part, op, colName, val, colData)
+		}
 		return p.formatLikeSQL(colOperator, colName, val, rawVal, rawStrArr)
 	}
 	return subSQL.String()
 }
 
+// datePartSQL 日期部分的比较，year 的比较转换为日期区间，可以使用索引
+func (p *queryModel) datePartSQL(part, op, colName, val string, colData interface{}) string {
+	if part == "year" && op != "in" && op != "nin" {
+		return p.yearRangeSQL(op, colName, colData)
+	}
+
+	expr, err := p.DBCore.Dialect.DatePart(colName, part)
+	if err != nil {
+		panic(err)
+	}
+
+	var subSQL strings.Builder
+	subSQL.Grow(len(expr) + len(val) + 10)
+	subSQL.WriteString(expr)
+	switch op {
+	case "eq":
+		subSQL.WriteByte('=')
+	case "ne":
+		subSQL.WriteString("<>")
+	case "lt":
+		subSQL.WriteByte('<')
+	case "lte":
+		subSQL.WriteString("<=")
+	case "gt":
+		subSQL.WriteByte('>')
+	case "gte":
+		subSQL.WriteString(">=")
+	case "in":
+		subSQL.WriteString(" in ")
+	case "nin":
+		subSQL.WriteString(" not in ")
+	}
+	subSQL.WriteString(val)
+	return subSQL.String()
+}
+
+// yearRangeSQL 年份比较：字段>='2024-01-01 00:00:00' and 字段<'2025-01-01 00:00:00'
+func (p *queryModel) yearRangeSQL(op, colName string, colData interface{}) string {
+	year, err := strconv.Atoi(fmt.Sprintf("%v", colData))
+	if err != nil || year <= 0 || year >= 9999 {
+		panic(fmt.Sprintf("year[%v] is invalid", colData))
+	}
+
+	dialect := p.DBCore.Dialect
+	start := dialect.TimeLiteral(fmt.Sprintf("'%04d-01-01 00:00:00'", year))
+	end := dialect.TimeLiteral(fmt.Sprintf("'%04d-01-01 00:00:00'", year+1))
+	switch op {
+	case "eq":
+		return colName + ">=" + start + " and " + colName + "<" + end
+	case "ne":
+		return "(" + colName + "<" + start + " or " + colName + ">=" + end + ")"
+	case "lt":
+		return colName + "<" + start
+	case "lte":
+		return colName + "<" + end
+	case "gt":
+		return colName + ">=" + end
+	default:
+		return colName + ">=" + start
+	}
+}
+
+// regexSQL 正则匹配，flag 为 bin ignore 前缀对应的大小写方式
+func (p *queryModel) regexSQL(colOperator string, colName string, val string, flag RegexFlag) string {
+	switch colOperator {
+	case "iregex":
+		flag |= RegexIgnoreCase
+	case "nregex":
+		flag |= RegexNot
+	}
+	if flag.Has(RegexIgnoreCase) && flag.Has(RegexCaseSensitive) {
+		panic("bin and ignore cannot be used at the same time")
+	}
+
+	subSQL, err := p.DBCore.Dialect.Regex(colName, val, flag)
+	if err != nil {
+		panic(err)
+	}
+	return subSQL
+}
+
 func (p *queryModel) innerBinFormatSubSQL(colOperator string, colName string, val, rawVal string, rawStrArr []string,
 	colData interface{}) string {
-	subSQL := ""
-	switch p.DBCore.DBType {
-	case dbtype.MySQL, dbtype.MariaDB, dbtype.ClickHouse:
-		subSQL = p.mysqlBinFormatSubSQL(colOperator, colName, val, rawVal, rawStrArr, colData)
-	case dbtype.SQLServer:
-		subSQL = p.sqlserverBinFormatSubSQL(colOperator, colName, val, rawVal, rawStrArr, colData)
-	case dbtype.Oracle, dbtype.Postgres, dbtype.OpenGauss, dbtype.SQLite2, dbtype.SQLite3:
-		return p.innerFormatSubSQL(colOperator, colName, val, rawVal, rawStrArr, colData)
+	if isRegexOperator(colOperator) {
+		return p.regexSQL(colOperator, colName, val, RegexCaseSensitive)
 	}
 
-	return subSQL
+	c := &Condition{
+		Operator: colOperator,
+		Column:   colName,
+		Value:    val,
+		Raw:      rawVal,
+		RawList:  rawStrArr,
+		Data:     colData,
+	}
+	if subSQL, ok := p.DBCore.Dialect.BinCondition(c); ok {
+		return subSQL
+	}
+	return p.innerFormatSubSQL(colOperator, colName, val, rawVal, rawStrArr, colData)
 }
 
 func (p *queryModel) innerIgnoreFormatSubSQL(colOperator string, colName string, val, rawVal string, rawStrArr []string,
 	colData interface{}) string {
-	subSQL := ""
-	switch p.DBCore.DBType {
-	case dbtype.SQLite2, dbtype.SQLite3:
-		subSQL = p.sqliteIgnoreFormatSubSQL(colOperator, colName, val, rawVal, rawStrArr, colData)
-	case dbtype.Oracle:
-		subSQL = p.oracleIgnoreFormatSubSQL(colOperator, colName, val, rawVal, rawStrArr, colData)
-	case dbtype.Postgres, dbtype.OpenGauss:
-		subSQL = p.postgresIgnoreFormatSubSQL(colOperator, colName, val, rawVal, rawStrArr, colData)
-	case dbtype.MySQL, dbtype.MariaDB, dbtype.SQLServer, dbtype.ClickHouse:
-		subSQL = p.innerFormatSubSQL(colOperator, colName, val, rawVal, rawStrArr, colData)
+	if isRegexOperator(colOperator) {
+		return p.regexSQL(colOperator, colName, val, RegexIgnoreCase)
 	}
-	return subSQL
+
+	c := &Condition{
+		Operator: colOperator,
+		Column:   colName,
+		Value:    val,
+		Raw:      rawVal,
+		RawList:  rawStrArr,
+		Data:     colData,
+	}
+	if subSQL, ok := p.DBCore.Dialect.IgnoreCondition(c); ok {
+		return subSQL
+	}
+	return p.innerFormatSubSQL(colOperator, colName, val, rawVal, rawStrArr, colData)
 }
 
 func (p *queryModel) formatLikeSQL(colOperator string, colName string, val, rawVal string, rawStrArr []string) string {
@@ -355,250 +405,15 @@ func (p *queryModel) formatOrLikeSQL(colOperator string, colName string, val, ra
 			subSQL.WriteByte(')')
 		}
 	default:
-		panic("no definition")
+		panic("is not defined")
 	}
 	return subSQL.String()
 }
 
-// orm
-func (orm *ORM) innerInsertOrReplaceSQL(tp string, data interface{}) (string, error) {
-	dbCore := orm.ref.getDBConf()
-	escLen := len(dbCore.EscStart) + len(dbCore.EscEnd)
-
-	var insertSQL strings.Builder
-	insertSQL.Grow(200)
-	insertSQL.WriteString(tp)
-	insertSQL.WriteString(" into ")
-	insertSQL.WriteString(dbCore.EscStart)
-	insertSQL.WriteString(orm.tableName)
-	insertSQL.WriteString(dbCore.EscEnd)
-	insertSQL.WriteByte('(')
-
-	if data == nil {
-		return "", fmt.Errorf("insert data is nil")
-	}
-
-	typeErrStr := "type of data is map[string]interface{} or *struct or struct"
-	var cols []string
-	var values []string
-
-	switch data := data.(type) {
-	case map[string]interface{}:
-		cols = make([]string, 0, len(data))
-		values = make([]string, 0, len(data))
-		for k, v := range data {
-			if k[0] == '#' {
-				k = k[1:]
-				err := globalVerifyObj.VerifyFieldName(k)
-				if err != nil {
-					return "", err
-				}
-				var formatKey strings.Builder
-				formatKey.Grow(escLen + len(k))
-				formatKey.WriteString(dbCore.EscStart)
-				formatKey.WriteString(k)
-				formatKey.WriteString(dbCore.EscEnd)
-
-				cols = append(cols, formatKey.String())
-				values = append(values, util.Any2String(v))
-				continue
-			}
-
-			err := globalVerifyObj.VerifyFieldName(k)
-			if err != nil {
-				return "", err
-			}
-
-			val, timeEmpty := orm.formatValue(v)
-			if k == orm.primaryKey && (val == "" || val == "0") {
-				continue
-			}
-			if timeEmpty {
-				continue
-			} else {
-				var formatKey strings.Builder
-				formatKey.Grow(escLen + len(k))
-				formatKey.WriteString(dbCore.EscStart)
-				formatKey.WriteString(k)
-				formatKey.WriteString(dbCore.EscEnd)
-
-				cols = append(cols, formatKey.String())
-				values = append(values, val)
-			}
-		}
-	default:
-		dataValue := reflect.ValueOf(data)
-		if dataValue.Type().Kind() != reflect.Struct && dataValue.Type().Kind() != reflect.Ptr {
-			return "", fmt.Errorf(typeErrStr)
-		}
-
-		if dataValue.Type().Kind() == reflect.Ptr {
-			dataValue = dataValue.Elem()
-		}
-
-		if dataValue.Type().Kind() != reflect.Struct {
-			return "", fmt.Errorf(typeErrStr)
-		}
-
-		for i := 0; i < dataValue.NumField(); i++ {
-			colName := dataValue.Type().Field(i).Tag.Get("json")
-			ref := dataValue.Type().Field(i).Tag.Get("ref")
-			if ref != "" || colName == "" || !dataValue.Type().Field(i).IsExported() {
-				continue
-			}
-
-			err := globalVerifyObj.VerifyFieldName(colName)
-			if err != nil {
-				return "", err
-			}
-
-			val, timeEmpty := orm.formatValue(dataValue.Field(i).Interface())
-			if colName == orm.primaryKey && (val == "" || val == "0") {
-				continue
-			}
-			if timeEmpty {
-				continue
-			} else {
-				var formatKey strings.Builder
-				formatKey.Grow(escLen + len(colName))
-				formatKey.WriteString(dbCore.EscStart)
-				formatKey.WriteString(colName)
-				formatKey.WriteString(dbCore.EscEnd)
-
-				cols = append(cols, formatKey.String())
-				values = append(values, val)
-			}
-		}
-	}
-	if len(cols) <= 0 || len(values) <= 0 {
-		return "", fmt.Errorf("sql data is empty, please check it")
-	}
-
-	insertSQL.WriteString(util.JoinArr(cols, ","))
-	insertSQL.WriteString(") values(")
-	insertSQL.WriteString(util.JoinArr(values, ","))
-	insertSQL.WriteByte(')')
-	return insertSQL.String(), nil
-}
-
-func (orm *ORM) innerInsertOrReplaceManySQL(tp string, dataList []interface{}, cols []string) (string, error) {
-	if len(dataList) <= 0 {
-		return "", fmt.Errorf("insert data is nil")
-	}
-
-	dbCore := orm.ref.getDBConf()
-
-	escLen := len(dbCore.EscStart) + len(dbCore.EscEnd)
-
-	var insertSQL strings.Builder
-	insertSQL.Grow(len(dataList)*len(cols)*5 + 100)
-
-	insertSQL.WriteString(tp)
-	insertSQL.WriteString(" into ")
-	insertSQL.WriteString(dbCore.EscStart)
-	insertSQL.WriteString(orm.tableName)
-	insertSQL.WriteString(dbCore.EscEnd)
-
-	typeErrStr := "type of data is []map[string]interface{} or []*struct or []struct"
-
-	valArr := make([]string, 0, len(dataList))
-	for _, data := range dataList {
-		var valMap map[string]interface{}
-		switch data := data.(type) {
-		case map[string]interface{}:
-			valMap = data
-		default:
-			dataValue := reflect.ValueOf(data)
-			if dataValue.Type().Kind() != reflect.Struct && dataValue.Type().Kind() != reflect.Ptr {
-				return "", fmt.Errorf(typeErrStr)
-			}
-
-			if dataValue.Type().Kind() == reflect.Ptr {
-				dataValue = dataValue.Elem()
-			}
-
-			if dataValue.Type().Kind() != reflect.Struct {
-				return "", fmt.Errorf(typeErrStr)
-			}
-
-			valMap = map[string]interface{}{}
-
-			for i := 0; i < dataValue.NumField(); i++ {
-				colName := dataValue.Type().Field(i).Tag.Get("json")
-				ref := dataValue.Type().Field(i).Tag.Get("ref")
-				if ref != "" || colName == "" || !dataValue.Type().Field(i).IsExported() {
-					continue
-				}
-
-				valMap[colName] = dataValue.Field(i).Interface()
-			}
-		}
-		if len(valMap) <= 0 {
-			return "", fmt.Errorf("sql data is empty, please check it")
-		}
-
-		var subVal strings.Builder
-		subVal.Grow(len(cols) * 10)
-		subVal.WriteByte('(')
-		for i := range cols {
-			if i > 0 {
-				subVal.WriteByte(',')
-			}
-			if v, ok := valMap[cols[i]]; ok {
-				val, timeEmpty := orm.formatValue(v)
-				if (cols[i] == orm.primaryKey && (val == "" || val == "0")) || timeEmpty {
-					subVal.WriteString("null")
-					continue
-				}
-
-				subVal.WriteString(val)
-			} else if v, ok = valMap["#"+cols[i]]; ok {
-				subVal.WriteString(util.Any2String(v))
-			} else {
-				subVal.WriteString("null")
-			}
-		}
-		subVal.WriteByte(')')
-		valArr = append(valArr, subVal.String())
-	}
-
-	if len(valArr) <= 0 {
-		return "", fmt.Errorf("insert data is empty")
-	}
-
-	newCols := make([]string, 0, len(cols))
-	for _, k := range cols {
-		err := globalVerifyObj.VerifyFieldName(k)
-		if err != nil {
-			return "", err
-		}
-
-		var buf strings.Builder
-		buf.Grow(escLen + len(k))
-		buf.WriteString(dbCore.EscStart)
-		buf.WriteString(k)
-		buf.WriteString(dbCore.EscEnd)
-
-		newCols = append(newCols, buf.String())
-	}
-	insertSQL.WriteByte('(')
-	insertSQL.WriteString(util.JoinArr(newCols, ","))
-	insertSQL.WriteString(") values")
-	insertSQL.WriteString(util.JoinArr(valArr, ","))
-	return insertSQL.String(), nil
-}
-
 func (orm *ORM) innerUpdateSQL(data interface{}) (string, error) {
 	dbCore := orm.ref.getDBConf()
 	escLen := len(dbCore.EscStart) + len(dbCore.EscEnd)
 
-	var updateSQL strings.Builder
-	updateSQL.WriteString("update ")
-	updateSQL.WriteString(dbCore.EscStart)
-	updateSQL.WriteString(orm.tableName)
-	updateSQL.WriteString(dbCore.EscEnd)
-	updateSQL.WriteString(" set ")
-
 	if data == nil {
 		return "", fmt.Errorf("update data is nil")
 	}
@@ -610,7 +425,8 @@ func (orm *ORM) innerUpdateSQL(data interface{}) (string, error) {
 
 	switch data := data.(type) {
 	case map[string]interface{}:
-		for k, v := range data {
+		for _, k := range sortedKeys(data) {
+			v := data[k]
 			if k[0] == '#' {
 				k = k[1:]
 				err := globalVerifyObj.VerifyFieldName(k)
@@ -706,12 +522,52 @@ func (orm *ORM) innerUpdateSQL(data interface{}) (string, error) {
 
 	primaryVal = strings.ReplaceAll(primaryVal, "'", "''")
 
-	updateSQL.WriteString(util.JoinArr(upSet, ","))
-	updateSQL.WriteString(" where ")
-	updateSQL.WriteString(dbCore.EscStart)
-	updateSQL.WriteString(orm.primaryKey)
-	updateSQL.WriteString(dbCore.EscEnd)
-	updateSQL.WriteByte('=')
-	updateSQL.WriteString(primaryVal)
-	return updateSQL.String(), nil
+	return dbCore.Dialect.Update(quoteName(dbCore.EscStart, dbCore.EscEnd, orm.tableName), util.JoinArr(upSet, ","),
+		quoteName(dbCore.EscStart, dbCore.EscEnd, orm.primaryKey)+"="+primaryVal), nil
+}
+
+func isRegexOperator(colOperator string) bool {
+	return colOperator == "regex" || colOperator == "iregex" || colOperator == "nregex"
+}
+
+// isCompareOperator 比较操作符，可带 bin ignore 前缀
+func isCompareOperator(colOperator string) bool {
+	if i := strings.IndexByte(colOperator, '_'); i > 0 {
+		switch colOperator[:i] {
+		case "bin", "b", "ignore", "i":
+			colOperator = colOperator[i+1:]
+		}
+	}
+
+	switch colOperator {
+	case "eq", "ne", "lt", "lte", "gt", "gte":
+		return true
+	}
+	return false
+}
+
+// datePartOperator 日期部分的操作符：year month_gt weekday_in 等，比较方式默认为 eq
+func datePartOperator(colOperator string) (part, op string, ok bool) {
+	part, op = colOperator, "eq"
+	if i := strings.IndexByte(colOperator, '_'); i > 0 {
+		part, op = colOperator[:i], colOperator[i+1:]
+	}
+
+	if !isDatePart(part) {
+		return "", "", false
+	}
+
+	switch op {
+	case "eq", "ne", "lt", "lte", "gt", "gte", "in", "nin":
+		return part, op, true
+	}
+	return "", "", false
+}
+
+func isDatePart(part string) bool {
+	switch part {
+	case "year", "quarter", "month", "week", "weekday", "day", "hour":
+		return true
+	}
+	return false
 }
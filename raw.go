@@ -0,0 +1,159 @@
+package orm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RawSQL 原始SQL片段，参数以 ? 占位，按数据库格式化为值或作为绑定参数传递，不会直接拼接；
+// ?? 为 ? 本身（如 postgres jsonb 的 ? 操作符），单引号内的 ? 不是参数
+type RawSQL struct {
+	sql   string
+	args  []interface{}
+	alias string
+	// name 表达式的默认别名，orm.Raw 为空
+	name string
+	// isCase Case 表达式，可以设置 Else
+	isCase bool
+	// window 窗口函数的定义，orm.Over 设置
+	window *windowSpec
+	// valuesOrder 按值列表排序，参数为字段与值，OrderByValues 设置
+	valuesOrder bool
+	// grouping GROUPING(字段)，orm.Grouping 设置，SQL 由数据库方言生成
+	grouping bool
+}
+
+// Raw 原始SQL片段，可用于：Where Having 的 $raw（值也可以是 []*RawSQL，以 and 连接）、SelectExpr、OrderExpr、GroupByExpr，
+// 参数与条件值的规则一致，另外 orm.Col 为字段（按 tag 解析），*RawSQL 为表达式，*Cond 为条件，如：orm.Where{"$raw": orm.Raw("a+b>?", 10)}
+func Raw(sql string, args ...interface{}) *RawSQL {
+	if sql == "" {
+		panic("raw sql cannot be empty")
+	}
+	return newExpr(sql, args)
+}
+
+// As 作为 Select 字段时的别名
+func (r *RawSQL) As(alias string) *RawSQL {
+	n := *r
+	n.alias = alias
+	return &n
+}
+
+// rawList $raw 的值
+func rawList(value interface{}) []*RawSQL {
+	switch v := value.(type) {
+	case *RawSQL:
+		return []*RawSQL{v}
+	case []*RawSQL:
+		return v
+	}
+	panic("the value of $raw must be *RawSQL or []*RawSQL")
+}
+
+// rawSQL 替换参数占位
+func (p *queryModel) rawSQL(r *RawSQL) string {
+	if r.window != nil {
+		return p.windowSQL(r)
+	}
+	if r.valuesOrder {
+		return p.valuesOrderSQL(r)
+	}
+	if r.grouping {
+		return p.groupingFuncSQL(r)
+	}
+
+	var sql strings.Builder
+	sql.Grow(len(r.sql) + len(r.args)*10)
+	quoted := false
+	n := 0
+	for i := 0; i < len(r.sql); i++ {
+		c := r.sql[i]
+		if c == '\'' {
+			quoted = !quoted
+		} else if c == '?' && !quoted {
+			if i+1 < len(r.sql) && r.sql[i+1] == '?' {
+				i++
+			} else {
+				if n >= len(r.args) {
+					panic(fmt.Sprintf("raw sql[%s] args count error", r.sql))
+				}
+				sql.WriteString(p.rawValue(r.args[n]))
+				n++
+				continue
+			}
+		}
+		sql.WriteByte(c)
+	}
+
+	if n != len(r.args) {
+		panic(fmt.Sprintf("raw sql[%s] args count error", r.sql))
+	}
+	return sql.String()
+}
+
+func (p *queryModel) rawValue(arg interface{}) string {
+	switch v := arg.(type) {
+	case nil:
+		return "null"
+	case BaseQuery:
+		arg = v.cond()
+	case *BaseQuery:
+		arg = v.cond()
+	case *RawSQL:
+		return p.rawSQL(v)
+	case *Cond:
+		return p.condSQL(v)
+	}
+
+	val, _, _ := p.formatSQLValue("eq", "raw", arg)
+	return val
+}
+
+// rawWhereSQL $raw 条件，多个以 and 连接
+func (p *queryModel) rawWhereSQL(list []*RawSQL) string {
+	sqlArr := make([]string, 0, len(list))
+	for _, r := range list {
+		sqlArr = append(sqlArr, "("+p.rawSQL(r)+")")
+	}
+	if len(sqlArr) == 1 {
+		return sqlArr[0]
+	}
+	return "(" + strings.Join(sqlArr, " and ") + ")"
+}
+
+// formatRaw 格式化参数中的字段（orm.Col）、表达式与条件，值在生成SQL时格式化
+func (q *BaseQuery) formatRaw(r *RawSQL) *RawSQL {
+	n := *r
+	n.args = make([]interface{}, len(r.args))
+	for i, arg := range r.args {
+		switch v := arg.(type) {
+		case Col:
+			n.args[i] = q.colValue(v)
+		case OuterCol:
+			n.args[i] = q.outerColValue(v)
+		case BaseQuery:
+			n.args[i] = q.subQuery(v)
+		case *BaseQuery:
+			n.args[i] = q.subQuery(*v)
+		case *RawSQL:
+			n.args[i] = q.formatRaw(v)
+		case *Cond:
+			n.args[i] = q.formatCondTree(v)
+		default:
+			n.args[i] = arg
+		}
+	}
+	return &n
+}
+
+func (q *BaseQuery) formatRawList(list []*RawSQL) []*RawSQL {
+	if len(list) <= 0 {
+		return nil
+	}
+
+	newList := make([]*RawSQL, 0, len(list))
+	for _, r := range list {
+		newList = append(newList, q.formatRaw(r))
+	}
+	return newList
+}
@@ -64,15 +64,30 @@ type formatColumnData struct {
 	FuncName   string
 	Alias      string
 	TagList    []string
+	// JSONPath json 字段的路径，如：meta->a.b 为 [a b]
+	JSONPath []string
+	// DatePart 日期函数，如：created_at|month 为 month，created_at|trunc_month 为 trunc_month
+	DatePart string
 }
 
-func NewReference(dbType int) *Reference {
-	obj := new(Reference)
-	obj.dbType = dbType
-	obj.dbConf = dbConfMap[obj.dbType]
-	if obj.dbConf == nil {
-		panic(fmt.Errorf("database type[%d] not implemented, please confirm", dbType))
+// NewReference db 为数据库类型（dbtype）或数据库方言（Dialect）
+func NewReference(db interface{}) *Reference {
+	var dialect Dialect
+	switch db := db.(type) {
+	case Dialect:
+		dialect = db
+	case int:
+		dialect = GetDialect(db)
+		if dialect == nil {
+			panic(fmt.Errorf("database type[%d] not implemented, please confirm", db))
+		}
+	default:
+		panic(fmt.Errorf("database type[%v] not implemented, please confirm", db))
 	}
+
+	obj := new(Reference)
+	obj.dbType = dialect.DBType()
+	obj.dbConf = newDBCoreData(dialect)
 	obj.joinConf = map[string]map[string]*referenceData{}
 	obj.tableDef = map[string][]string{}
 	obj.structToTable = map[string]string{}
@@ -81,10 +96,29 @@ func NewReference(dbType int) *Reference {
 	return obj
 }
 
+// NewReferenceWithVersion 指定数据库版本，方言根据版本选择分页、upsert 等语法，当前版本无法实现的功能返回 ErrDBFunc
+// 如：MySQL 5.7 8.0.20；Oracle 11g 12c；SQLServer 2008 2012（或 10.50 11.0）；其他数据库忽略版本
+func NewReferenceWithVersion(db interface{}, version string) *Reference {
+	obj := NewReference(db)
+	if d, ok := obj.dbConf.Dialect.(VersionDialect); ok {
+		dialect, err := d.WithVersion(version)
+		if err != nil {
+			panic(err)
+		}
+		obj.dbConf = newDBCoreData(dialect)
+	}
+	return obj
+}
+
 func (c *Reference) GetDBType() int {
 	return c.dbType
 }
 
+// GetDialect 数据库方言
+func (c *Reference) GetDialect() Dialect {
+	return c.dbConf.Dialect
+}
+
 func (c *Reference) getTableCacheByTp(tableTp reflect.Type) *tableStructData {
 	if tableTp.Kind() == reflect.Ptr {
 		tableTp = tableTp.Elem()
@@ -2,82 +2,20 @@ package orm
 
 import (
 	"fmt"
-	"reflect"
+	"regexp"
 	"strings"
+	"sync"
 
-	"github.com/assembly-hub/basics/set"
-	"github.com/assembly-hub/basics/util"
+	"github.com/assembly-hub/orm/dbtype"
 )
 
-func (p *queryModel) querySQLite() string {
-	var sql strings.Builder
-	sql.Grow(100)
-	sql.WriteString("select ")
-
-	sel := p.selectSQL()
-	if p.MainTable == "" {
-		panic("MainTable is nil")
-	}
-
-	sql.WriteString(sel)
-	sql.WriteString(" from ")
-	sql.WriteString(p.MainTable)
-	if p.MainAlias != "" {
-		sql.WriteString(" as ")
-		sql.WriteString(p.MainAlias)
-	}
-
-	join := p.joinSQL()
-	if join != "" {
-		sql.WriteString(join)
-	}
-
-	where := p.andSQL(p.Where)
-	if where != "" {
-		sql.WriteString(" where ")
-		sql.WriteString(where)
-	}
-
-	if len(p.GroupBy) > 0 {
-		sql.WriteString(" group by ")
-		sql.WriteString(util.JoinArr(p.GroupBy, ","))
-	}
-
-	if len(p.GroupBy) > 0 && len(p.Having) > 0 {
-		sql.WriteString(" having ")
-		sql.WriteString(p.andSQL(p.Having))
-	}
-
-	order := p.orderSQL()
-	if order != "" {
-		sql.WriteString(" order by ")
-		sql.WriteString(order)
-	}
-
-	if len(p.Limit) == 1 {
-		sql.WriteString(" limit ")
-		sql.WriteString(util.UintToStr(p.Limit[0]))
-	} else if len(p.Limit) == 2 {
-		sql.WriteString(" limit ")
-		sql.WriteString(util.UintToStr(p.Limit[1]))
-		sql.WriteString(" offset ")
-		sql.WriteString(util.UintToStr(p.Limit[0]))
-	}
-
-	//if p.SelectForUpdate {
-	//	sql += " for update"
-	//}
-
-	return sql.String()
-}
-
-func (p *queryModel) sqliteIgnoreFormatSubSQL(colOperator string, colName string, val, rawVal string, rawStrArr []string,
+func sqliteIgnoreFormatSubSQL(ignoreStr string, colOperator string, colName string, val, rawVal string, rawStrArr []string,
 	colData interface{}) string {
 	var subSQL strings.Builder
 	subSQL.Grow(20)
 	subSQL.WriteString(colName)
 	subSQL.WriteByte(' ')
-	subSQL.WriteString(p.DBCore.IgnoreStr)
+	subSQL.WriteString(ignoreStr)
 	switch colOperator {
 	case "eq":
 		subSQL.WriteString(" =")
@@ -96,13 +34,13 @@ func (p *queryModel) sqliteIgnoreFormatSubSQL(colOperator string, colName string
 	case "nin":
 		subSQL.WriteString(" not in ")
 	default:
-		return p.sqliteIgnoreLikeSQL(colOperator, colName, val, rawVal, rawStrArr)
+		return sqliteIgnoreLikeSQL(ignoreStr, colOperator, colName, val, rawVal, rawStrArr)
 	}
 	subSQL.WriteString(val)
 	return subSQL.String()
 }
 
-func (p *queryModel) sqliteIgnoreLikeSQL(colOperator string, colName string, val, rawVal string, rawStrArr []string) string {
+func sqliteIgnoreLikeSQL(ignoreStr string, colOperator string, colName string, val, rawVal string, rawStrArr []string) string {
 	var subSQL strings.Builder
 	subSQL.Grow(20)
 	switch colOperator {
@@ -110,7 +48,7 @@ func (p *queryModel) sqliteIgnoreLikeSQL(colOperator string, colName string, val
 		if rawVal != "" {
 			subSQL.WriteString(colName)
 			subSQL.WriteByte(' ')
-			subSQL.WriteString(p.DBCore.IgnoreStr)
+			subSQL.WriteString(ignoreStr)
 			subSQL.WriteString(" like '")
 			subSQL.WriteString(rawVal)
 			subSQL.WriteString("%'")
@@ -119,7 +57,7 @@ func (p *queryModel) sqliteIgnoreLikeSQL(colOperator string, colName string, val
 		if rawVal != "" {
 			subSQL.WriteString(colName)
 			subSQL.WriteByte(' ')
-			subSQL.WriteString(p.DBCore.IgnoreStr)
+			subSQL.WriteString(ignoreStr)
 			subSQL.WriteString(" like '%")
 			subSQL.WriteString(rawVal)
 			subSQL.WriteString("'")
@@ -128,7 +66,7 @@ func (p *queryModel) sqliteIgnoreLikeSQL(colOperator string, colName string, val
 		if rawVal != "" {
 			subSQL.WriteString(colName)
 			subSQL.WriteByte(' ')
-			subSQL.WriteString(p.DBCore.IgnoreStr)
+			subSQL.WriteString(ignoreStr)
 			subSQL.WriteString(" like '%")
 			subSQL.WriteString(rawVal)
 			subSQL.WriteString("%'")
@@ -141,7 +79,7 @@ func (p *queryModel) sqliteIgnoreLikeSQL(colOperator string, colName string, val
 				}
 				subSQL.WriteString(colName)
 				subSQL.WriteByte(' ')
-				subSQL.WriteString(p.DBCore.IgnoreStr)
+				subSQL.WriteString(ignoreStr)
 				subSQL.WriteString(" like '%")
 				subSQL.WriteString(v)
 				subSQL.WriteString("%'")
@@ -152,7 +90,7 @@ func (p *queryModel) sqliteIgnoreLikeSQL(colOperator string, colName string, val
 		if rawVal != "" {
 			subSQL.WriteString(colName)
 			subSQL.WriteByte(' ')
-			subSQL.WriteString(p.DBCore.IgnoreStr)
+			subSQL.WriteString(ignoreStr)
 			subSQL.WriteString(" like '")
 			subSQL.WriteString(rawVal)
 			subSQL.WriteString("'")
@@ -165,7 +103,7 @@ func (p *queryModel) sqliteIgnoreLikeSQL(colOperator string, colName string, val
 				}
 				subSQL.WriteString(colName)
 				subSQL.WriteByte(' ')
-				subSQL.WriteString(p.DBCore.IgnoreStr)
+				subSQL.WriteString(ignoreStr)
 				subSQL.WriteString(" like '")
 				subSQL.WriteString(v)
 				subSQL.WriteString("'")
@@ -173,12 +111,12 @@ func (p *queryModel) sqliteIgnoreLikeSQL(colOperator string, colName string, val
 			subSQL.WriteByte(')')
 		}
 	default:
-		return p.sqliteIgnoreOrLikeSQL(colOperator, colName, val, rawVal, rawStrArr)
+		return sqliteIgnoreOrLikeSQL(ignoreStr, colOperator, colName, val, rawVal, rawStrArr)
 	}
 	return subSQL.String()
 }
 
-func (p *queryModel) sqliteIgnoreOrLikeSQL(colOperator string, colName string, val, rawVal string, rawStrArr []string) string {
+func sqliteIgnoreOrLikeSQL(ignoreStr string, colOperator string, colName string, val, rawVal string, rawStrArr []string) string {
 	var subSQL strings.Builder
 	subSQL.Grow(20)
 	switch colOperator {
@@ -186,7 +124,7 @@ func (p *queryModel) sqliteIgnoreOrLikeSQL(colOperator string, colName string, v
 		if rawVal != "" {
 			subSQL.WriteString(colName)
 			subSQL.WriteByte(' ')
-			subSQL.WriteString(p.DBCore.IgnoreStr)
+			subSQL.WriteString(ignoreStr)
 			subSQL.WriteString(" like '")
 			subSQL.WriteString(rawVal)
 			subSQL.WriteString("%'")
@@ -199,7 +137,7 @@ func (p *queryModel) sqliteIgnoreOrLikeSQL(colOperator string, colName string, v
 				}
 				subSQL.WriteString(colName)
 				subSQL.WriteByte(' ')
-				subSQL.WriteString(p.DBCore.IgnoreStr)
+				subSQL.WriteString(ignoreStr)
 				subSQL.WriteString(" like '")
 				subSQL.WriteString(v)
 				subSQL.WriteString("%'")
@@ -210,7 +148,7 @@ func (p *queryModel) sqliteIgnoreOrLikeSQL(colOperator string, colName string, v
 		if rawVal != "" {
 			subSQL.WriteString(colName)
 			subSQL.WriteByte(' ')
-			subSQL.WriteString(p.DBCore.IgnoreStr)
+			subSQL.WriteString(ignoreStr)
 			subSQL.WriteString(" like '%")
 			subSQL.WriteString(rawVal)
 			subSQL.WriteString("'")
@@ -223,7 +161,7 @@ func (p *queryModel) sqliteIgnoreOrLikeSQL(colOperator string, colName string, v
 				}
 				subSQL.WriteString(colName)
 				subSQL.WriteByte(' ')
-				subSQL.WriteString(p.DBCore.IgnoreStr)
+				subSQL.WriteString(ignoreStr)
 				subSQL.WriteString(" like '%")
 				subSQL.WriteString(v)
 				subSQL.WriteString("'")
@@ -234,7 +172,7 @@ func (p *queryModel) sqliteIgnoreOrLikeSQL(colOperator string, colName string, v
 		if rawVal != "" {
 			subSQL.WriteString(colName)
 			subSQL.WriteByte(' ')
-			subSQL.WriteString(p.DBCore.IgnoreStr)
+			subSQL.WriteString(ignoreStr)
 			subSQL.WriteString(" like '%")
 			subSQL.WriteString(rawVal)
 			subSQL.WriteString("%'")
@@ -247,7 +185,7 @@ func (p *queryModel) sqliteIgnoreOrLikeSQL(colOperator string, colName string, v
 				}
 				subSQL.WriteString(colName)
 				subSQL.WriteByte(' ')
-				subSQL.WriteString(p.DBCore.IgnoreStr)
+				subSQL.WriteString(ignoreStr)
 				subSQL.WriteString(" like '%")
 				subSQL.WriteString(v)
 				subSQL.WriteString("%'")
@@ -258,7 +196,7 @@ func (p *queryModel) sqliteIgnoreOrLikeSQL(colOperator string, colName string, v
 		if rawVal != "" {
 			subSQL.WriteString(colName)
 			subSQL.WriteByte(' ')
-			subSQL.WriteString(p.DBCore.IgnoreStr)
+			subSQL.WriteString(ignoreStr)
 			subSQL.WriteString(" like '")
 			subSQL.WriteString(rawVal)
 			subSQL.WriteString("'")
@@ -271,7 +209,7 @@ func (p *queryModel) sqliteIgnoreOrLikeSQL(colOperator string, colName string, v
 				}
 				subSQL.WriteString(colName)
 				subSQL.WriteByte(' ')
-				subSQL.WriteString(p.DBCore.IgnoreStr)
+				subSQL.WriteString(ignoreStr)
 				subSQL.WriteString(" like '")
 				subSQL.WriteString(v)
 				subSQL.WriteString("'")
@@ -279,357 +217,207 @@ func (p *queryModel) sqliteIgnoreOrLikeSQL(colOperator string, colName string, v
 			subSQL.WriteByte(')')
 		}
 	default:
-		panic("no definition")
+		panic("is not defined")
 	}
 	return subSQL.String()
 }
 
-// orm
-func (orm *ORM) sqliteUpsertSQL(data interface{}) (string, error) {
-	dbCore := orm.ref.getDBConf()
-	escLen := len(dbCore.EscStart) + len(dbCore.EscEnd)
-
-	var upsertSQL strings.Builder
-	upsertSQL.Grow(100)
-	upsertSQL.WriteString("insert into ")
-	upsertSQL.WriteString(dbCore.EscStart)
-	upsertSQL.WriteString(orm.tableName)
-	upsertSQL.WriteString(dbCore.EscEnd)
-	upsertSQL.WriteByte('(')
+// sqliteDialect sqlite3 sqlite2，部分功能（窗口函数、with、nulls first/last 等）与版本相关
+type sqliteDialect struct {
+	BaseDialect
+	ignoreStr string
+	// version 数据库版本，nil 为未指定
+	version []int
+}
 
-	if data == nil {
-		return "", fmt.Errorf("insert data is nil")
+func (d sqliteDialect) WithVersion(version string) (Dialect, error) {
+	ver, err := parseVersion(version)
+	if err != nil {
+		return nil, err
 	}
+	d.version = ver
+	return d, nil
+}
 
-	typeErrStr := "type of upsert data is map[string]interface{} or *struct or struct"
-	var formatCols []string
-	var values []string
-	colSet := set.New[string]()
-
-	switch data := data.(type) {
-	case map[string]interface{}:
-		formatCols = make([]string, 0, len(data))
-		values = make([]string, 0, len(data))
-		for k, v := range data {
-			if k[0] == '#' {
-				k = k[1:]
-				err := globalVerifyObj.VerifyFieldName(k)
-				if err != nil {
-					return "", err
-				}
-				var formatKey strings.Builder
-				formatKey.Grow(escLen + len(k))
-				formatKey.WriteString(dbCore.EscStart)
-				formatKey.WriteString(k)
-				formatKey.WriteString(dbCore.EscEnd)
-
-				colSet.Add(k)
-				formatCols = append(formatCols, formatKey.String())
-				values = append(values, util.Any2String(v))
-				continue
-			}
-
-			err := globalVerifyObj.VerifyFieldName(k)
-			if err != nil {
-				return "", err
-			}
-
-			val, timeEmpty := orm.formatValue(v)
-			if k == orm.primaryKey && (val == "" || val == "0") {
-				continue
-			}
-			if timeEmpty {
-				continue
-			} else {
-				var formatKey strings.Builder
-				formatKey.Grow(escLen + len(k))
-				formatKey.WriteString(dbCore.EscStart)
-				formatKey.WriteString(k)
-				formatKey.WriteString(dbCore.EscEnd)
-
-				colSet.Add(k)
-				formatCols = append(formatCols, formatKey.String())
-				values = append(values, val)
-			}
-		}
-	default:
-		dataValue := reflect.ValueOf(data)
-		if dataValue.Type().Kind() != reflect.Struct && dataValue.Type().Kind() != reflect.Ptr {
-			return "", fmt.Errorf(typeErrStr)
-		}
-
-		if dataValue.Type().Kind() == reflect.Ptr {
-			dataValue = dataValue.Elem()
-		}
-
-		if dataValue.Type().Kind() != reflect.Struct {
-			return "", fmt.Errorf(typeErrStr)
-		}
-
-		for i := 0; i < dataValue.NumField(); i++ {
-			colName := dataValue.Type().Field(i).Tag.Get("json")
-			ref := dataValue.Type().Field(i).Tag.Get("ref")
-			if ref != "" || colName == "" || !dataValue.Type().Field(i).IsExported() {
-				continue
-			}
-
-			err := globalVerifyObj.VerifyFieldName(colName)
-			if err != nil {
-				return "", err
-			}
+func (d sqliteDialect) Select(q *SelectParts) string {
+	// 不支持 for update
+	parts := *q
+	parts.ForUpdate = false
+	return d.BaseDialect.Select(&parts)
+}
 
-			val, timeEmpty := orm.formatValue(dataValue.Field(i).Interface())
-			if colName == orm.primaryKey && (val == "" || val == "0") {
-				continue
-			}
-			if timeEmpty {
-				continue
-			} else {
-				var formatKey strings.Builder
-				formatKey.Grow(escLen + len(colName))
-				formatKey.WriteString(dbCore.EscStart)
-				formatKey.WriteString(colName)
-				formatKey.WriteString(dbCore.EscEnd)
+func (d sqliteDialect) IgnoreCondition(c *Condition) (string, bool) {
+	return sqliteIgnoreFormatSubSQL(d.ignoreStr, c.Operator, c.Column, c.Value, c.Raw, c.RawList, c.Data), true
+}
 
-				colSet.Add(colName)
-				formatCols = append(formatCols, formatKey.String())
-				values = append(values, val)
-			}
-		}
-	}
-	if len(formatCols) <= 0 || len(values) <= 0 {
-		return "", fmt.Errorf("sql data is empty, please check it")
+// Regex regexp 需要在驱动中注册函数，见 SQLiteRegexp，忽略大小写使用 (?i) 前缀
+func (d sqliteDialect) Regex(column, pattern string, flag RegexFlag) (string, error) {
+	if flag.Has(RegexIgnoreCase) {
+		pattern = "'(?i)'||" + pattern
 	}
 
-	hasPK := colSet.Has(orm.primaryKey)
-	hasUK := false
-	if !hasPK {
-		hasUK = orm.checkUK(colSet)
+	var subSQL strings.Builder
+	subSQL.Grow(len(column) + len(pattern) + 20)
+	subSQL.WriteString(column)
+	if flag.Has(RegexNot) {
+		subSQL.WriteString(" not")
 	}
+	subSQL.WriteString(" regexp ")
+	subSQL.WriteString(pattern)
+	return subSQL.String(), nil
+}
 
-	upsertSQL.WriteString(util.JoinArr(formatCols, ","))
-	upsertSQL.WriteString(") values(")
-	upsertSQL.WriteString(util.JoinArr(values, ","))
-	upsertSQL.WriteByte(')')
-	if hasPK || hasUK {
-		upsertSQL.WriteString(" ON conflict(")
-
-		excludeSet := set.New[string]()
-		if hasPK {
-			upsertSQL.WriteString(dbCore.EscStart)
-			upsertSQL.WriteString(orm.primaryKey)
-			upsertSQL.WriteString(dbCore.EscEnd)
-			excludeSet.Add(orm.primaryKey)
-		} else {
-			hasData := false
-			orm.uniqueKeys.Range(func(item string) bool {
-				if hasData {
-					upsertSQL.WriteByte(',')
-				}
-				hasData = true
-
-				upsertSQL.WriteString(dbCore.EscStart)
-				upsertSQL.WriteString(item)
-				upsertSQL.WriteString(dbCore.EscEnd)
-				return true
-			})
-			excludeSet = orm.uniqueKeys
-		}
-
-		upsertSQL.WriteString(") DO update set ")
-
-		hasData := false
-		for k := range colSet {
-			if excludeSet.Has(k) {
-				continue
-			}
-
-			if hasData {
-				upsertSQL.WriteByte(',')
-			}
-			hasData = true
-
-			upsertSQL.WriteString(dbCore.EscStart)
-			upsertSQL.WriteString(k)
-			upsertSQL.WriteString(dbCore.EscEnd)
-			upsertSQL.WriteString("=EXCLUDED.")
-			upsertSQL.WriteString(dbCore.EscStart)
-			upsertSQL.WriteString(k)
-			upsertSQL.WriteString(dbCore.EscEnd)
-		}
+// Search FTS5 虚拟表的字段
+func (d sqliteDialect) Search(column, query string) (string, error) {
+	return column + " MATCH " + query, nil
+}
 
-		if !hasData {
-			return "", fmt.Errorf("conflict excluded field is empty")
-		}
+// DatePart week 为 ISO 周：所在周的周四是当年的第几周
+func (d sqliteDialect) DatePart(column, part string) (string, error) {
+	switch part {
+	case "year":
+		return "CAST(strftime('%Y'," + column + ") AS INTEGER)", nil
+	case "quarter":
+		return "((CAST(strftime('%m'," + column + ") AS INTEGER)+2)/3)", nil
+	case "month":
+		return "CAST(strftime('%m'," + column + ") AS INTEGER)", nil
+	case "week":
+		return "((CAST(strftime('%j',date(" + column + ",'-3 days','weekday 4')) AS INTEGER)-1)/7+1)", nil
+	case "weekday":
+		return "((CAST(strftime('%w'," + column + ") AS INTEGER)+6)%7+1)", nil
+	case "day":
+		return "CAST(strftime('%d'," + column + ") AS INTEGER)", nil
+	case "hour":
+		return "CAST(strftime('%H'," + column + ") AS INTEGER)", nil
 	}
-
-	return upsertSQL.String(), nil
+	return "", ErrDBFunc
 }
 
-func (orm *ORM) sqliteUpsertManySQL(dataList []interface{}, cols []string) (string, error) {
-	if len(dataList) <= 0 {
-		return "", fmt.Errorf("insert data is nil")
+func (d sqliteDialect) DateTrunc(column, unit string) (string, error) {
+	switch unit {
+	case "year":
+		return "strftime('%Y-01-01'," + column + ")", nil
+	case "quarter":
+		return "printf('%s-%02d-01',strftime('%Y'," + column + "),(CAST(strftime('%m'," + column + ") AS INTEGER)-1)/3*3+1)", nil
+	case "month":
+		return "strftime('%Y-%m-01'," + column + ")", nil
+	case "week":
+		return "date(" + column + ",'-6 days','weekday 1')", nil
+	case "day":
+		return "date(" + column + ")", nil
+	case "hour":
+		return "strftime('%Y-%m-%d %H:00:00'," + column + ")", nil
 	}
+	return "", ErrDBFunc
+}
 
-	dbCore := orm.ref.getDBConf()
-
-	escLen := len(dbCore.EscStart) + len(dbCore.EscEnd)
-
-	var upsertSQL strings.Builder
-	upsertSQL.Grow(len(dataList)*len(cols)*5 + 100)
-
-	upsertSQL.WriteString("insert into ")
-	upsertSQL.WriteString(dbCore.EscStart)
-	upsertSQL.WriteString(orm.tableName)
-	upsertSQL.WriteString(dbCore.EscEnd)
-
-	typeErrStr := "type of upsert data is []map[string]interface{} or []*struct or []struct"
-
-	valArr := make([]string, 0, len(dataList))
-	for _, data := range dataList {
-		var valMap map[string]interface{}
-		switch data := data.(type) {
-		case map[string]interface{}:
-			valMap = data
-		default:
-			dataValue := reflect.ValueOf(data)
-			if dataValue.Type().Kind() != reflect.Struct && dataValue.Type().Kind() != reflect.Ptr {
-				return "", fmt.Errorf(typeErrStr)
-			}
-
-			if dataValue.Type().Kind() == reflect.Ptr {
-				dataValue = dataValue.Elem()
-			}
-
-			if dataValue.Type().Kind() != reflect.Struct {
-				return "", fmt.Errorf(typeErrStr)
-			}
-
-			valMap = map[string]interface{}{}
-
-			for i := 0; i < dataValue.NumField(); i++ {
-				colName := dataValue.Type().Field(i).Tag.Get("json")
-				ref := dataValue.Type().Field(i).Tag.Get("ref")
-				if ref != "" || colName == "" || !dataValue.Type().Field(i).IsExported() {
-					continue
-				}
+// With SQLite 3.8.3 及以上支持
+func (d sqliteDialect) With(list []*WithParts) (string, error) {
+	if d.Type == dbtype.SQLite2 || versionBelow(d.version, 3, 8, 3) {
+		return "", ErrDBFunc
+	}
+	return d.BaseDialect.With(list)
+}
 
-				valMap[colName] = dataValue.Field(i).Interface()
-			}
-		}
-		if len(valMap) <= 0 {
-			return "", fmt.Errorf("sql data is empty, please check it")
-		}
+// Grouping 不支持 rollup cube grouping sets
+func (d sqliteDialect) Grouping(groupBy, kind string, sets [][]string) (string, error) {
+	return "", ErrDBFunc
+}
 
-		var subVal strings.Builder
-		subVal.Grow(len(cols) * 10)
-		subVal.WriteByte('(')
-		for i := range cols {
-			if i > 0 {
-				subVal.WriteByte(',')
-			}
-			if v, ok := valMap[cols[i]]; ok {
-				val, timeEmpty := orm.formatValue(v)
-				if (cols[i] == orm.primaryKey && (val == "" || val == "0")) || timeEmpty {
-					subVal.WriteString("null")
-					continue
-				}
+// GroupingFunc 不支持
+func (d sqliteDialect) GroupingFunc(column string) (string, error) {
+	return "", ErrDBFunc
+}
 
-				subVal.WriteString(val)
-			} else if v, ok = valMap["#"+cols[i]]; ok {
-				subVal.WriteString(util.Any2String(v))
-			} else {
-				subVal.WriteString("null")
-			}
-		}
-		subVal.WriteByte(')')
-		valArr = append(valArr, subVal.String())
+// Window SQLite 3.25 及以上支持
+func (d sqliteDialect) Window(fn, over string) (string, error) {
+	if d.Type == dbtype.SQLite2 || versionBelow(d.version, 3, 25) {
+		return "", ErrDBFunc
 	}
+	return d.BaseDialect.Window(fn, over)
+}
 
-	if len(valArr) <= 0 {
-		return "", fmt.Errorf("insert data is empty")
+// NullsOrder SQLite 3.30 及以上支持 nulls first/last，以下使用 case when 排序
+func (d sqliteDialect) NullsOrder(column string, desc, nullsFirst bool) string {
+	if d.Type == dbtype.SQLite2 || versionBelow(d.version, 3, 30) {
+		return nullsCaseOrder(column, desc, nullsFirst)
 	}
+	return d.BaseDialect.NullsOrder(column, desc, nullsFirst)
+}
 
-	colSet := set.New[string]()
-	formatCols := make([]string, 0, len(cols))
-	for _, k := range cols {
-		err := globalVerifyObj.VerifyFieldName(k)
-		if err != nil {
-			return "", err
-		}
-
-		var buf strings.Builder
-		buf.Grow(escLen + len(k))
-		buf.WriteString(dbCore.EscStart)
-		buf.WriteString(k)
-		buf.WriteString(dbCore.EscEnd)
-
-		formatCols = append(formatCols, buf.String())
-		colSet.Add(k)
+// RowCompare SQLite 3.15 及以上支持行值比较
+func (d sqliteDialect) RowCompare(left, right []string, op string) (string, error) {
+	if d.Type == dbtype.SQLite2 || versionBelow(d.version, 3, 15) {
+		return "", ErrDBFunc
 	}
+	return rowCompareSQL(left, right, op), nil
+}
 
-	upsertSQL.WriteByte('(')
-	upsertSQL.WriteString(util.JoinArr(formatCols, ","))
-	upsertSQL.WriteString(") values")
-	upsertSQL.WriteString(util.JoinArr(valArr, ","))
-
-	hasPK := colSet.Has(orm.primaryKey)
-	hasUK := false
-	if !hasPK {
-		hasUK = orm.checkUK(colSet)
-	}
+func (d sqliteDialect) JSONPath(column string, path []string) string {
+	return "json_extract(" + column + ",'" + jsonPathLiteral(path) + "')"
+}
 
-	if hasPK || hasUK {
-		upsertSQL.WriteString(" ON conflict(")
+func (d sqliteDialect) JSONHasKey(column, key string) (string, error) {
+	return "json_type(" + column + ",'" + jsonPathLiteral([]string{key}) + "') is not null", nil
+}
 
-		excludeSet := set.New[string]()
-		if hasPK {
-			upsertSQL.WriteString(dbCore.EscStart)
-			upsertSQL.WriteString(orm.primaryKey)
-			upsertSQL.WriteString(dbCore.EscEnd)
-			excludeSet.Add(orm.primaryKey)
-		} else {
-			hasData := false
-			orm.uniqueKeys.Range(func(item string) bool {
-				if hasData {
-					upsertSQL.WriteByte(',')
-				}
-				hasData = true
+func (d sqliteDialect) Replace(w *WriteParts) (string, error) {
+	return insertSQL("replace", d.EscStart, d.EscEnd, w), nil
+}
 
-				upsertSQL.WriteString(dbCore.EscStart)
-				upsertSQL.WriteString(item)
-				upsertSQL.WriteString(dbCore.EscEnd)
-				return true
-			})
-			excludeSet = orm.uniqueKeys
-		}
+func (d sqliteDialect) Upsert(w *WriteParts) (string, error) {
+	return conflictUpsertSQL(d.EscStart, d.EscEnd, w)
+}
 
-		upsertSQL.WriteString(") DO update set ")
+// conflictUpsertSQL insert ... ON conflict(...) DO update set，sqlite postgres 使用
+func conflictUpsertSQL(escStart, escEnd string, w *WriteParts) (string, error) {
+	var upsertSQL strings.Builder
+	upsertSQL.Grow(len(w.Rows)*len(w.Cols)*5 + len(w.Cols)*20 + 100)
+	upsertSQL.WriteString(insertSQL("insert", escStart, escEnd, w))
 
-		hasData := false
-		for k := range colSet {
-			if excludeSet.Has(k) {
-				continue
-			}
+	keys := w.ConflictKeys()
+	if keys == nil {
+		return upsertSQL.String(), nil
+	}
 
-			if hasData {
-				upsertSQL.WriteByte(',')
-			}
-			hasData = true
+	upsertSQL.WriteString(" ON conflict(")
+	upsertSQL.WriteString(connectStrArr(keys, ",", escStart, escEnd))
+	upsertSQL.WriteString(") DO update set ")
 
-			upsertSQL.WriteString(dbCore.EscStart)
-			upsertSQL.WriteString(k)
-			upsertSQL.WriteString(dbCore.EscEnd)
-			upsertSQL.WriteString("=EXCLUDED.")
-			upsertSQL.WriteString(dbCore.EscStart)
-			upsertSQL.WriteString(k)
-			upsertSQL.WriteString(dbCore.EscEnd)
+	cols := sortedStrs(w.updateCols(keys))
+	if len(cols) <= 0 {
+		return "", fmt.Errorf("conflict excluded field is empty")
+	}
+	for i, k := range cols {
+		if i > 0 {
+			upsertSQL.WriteByte(',')
 		}
+		col := quoteName(escStart, escEnd, k)
+		upsertSQL.WriteString(col)
+		upsertSQL.WriteString("=EXCLUDED.")
+		upsertSQL.WriteString(col)
+	}
+	return upsertSQL.String(), nil
+}
 
-		if !hasData {
-			return "", fmt.Errorf("conflict excluded field is empty")
-		}
+var sqliteRegexpCache sync.Map
+
+// SQLiteRegexp SQLite 的 regexp(pattern, value) 函数实现，regex 系列操作符需要在驱动中注册，如 go-sqlite3：
+//
+//	sql.Register("sqlite3_regexp", &sqlite3.SQLiteDriver{
+//		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+//			return conn.RegisterFunc("regexp", orm.SQLiteRegexp, true)
+//		},
+//	})
+func SQLiteRegexp(pattern, value string) (bool, error) {
+	if re, ok := sqliteRegexpCache.Load(pattern); ok {
+		return re.(*regexp.Regexp).MatchString(value), nil
 	}
 
-	return upsertSQL.String(), nil
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return false, err
+	}
+	sqliteRegexpCache.Store(pattern, re)
+	return re.MatchString(value), nil
 }
@@ -1,91 +1,19 @@
 package orm
 
 import (
-	"fmt"
-	"reflect"
+	"strconv"
 	"strings"
 
-	"github.com/assembly-hub/basics/set"
 	"github.com/assembly-hub/basics/util"
 )
 
-func (p *queryModel) querySQLServer() string {
-	var sql strings.Builder
-	sql.Grow(100)
-	sql.WriteString("select ")
-
-	if len(p.Limit) == 1 {
-		sql.WriteString(" top(")
-		sql.WriteString(util.UintToStr(p.Limit[0]))
-		sql.WriteString(") ")
-	}
-	sel := p.selectSQL()
-	if p.MainTable == "" {
-		panic("MainTable is nil")
-	}
-
-	sql.WriteString(sel)
-	sql.WriteString(" from ")
-	sql.WriteString(p.MainTable)
-	if p.MainAlias != "" {
-		sql.WriteString(" as ")
-		sql.WriteString(p.MainAlias)
-	}
-
-	join := p.joinSQL()
-	if join != "" {
-		sql.WriteString(join)
-	}
-
-	where := p.andSQL(p.Where)
-	if where != "" {
-		sql.WriteString(" where ")
-		sql.WriteString(where)
-	}
-
-	if len(p.GroupBy) > 0 {
-		sql.WriteString(" group by ")
-		sql.WriteString(util.JoinArr(p.GroupBy, ","))
-	}
-
-	if len(p.GroupBy) > 0 && len(p.Having) > 0 {
-		sql.WriteString(" having ")
-		sql.WriteString(p.andSQL(p.Having))
-	}
-
-	order := p.orderSQL()
-	orderSQL := ""
-	if order != "" {
-		sql.WriteString(" order by ")
-		sql.WriteString(order)
-	} else {
-		orderSQL = " order by " + p.DBCore.EscStart + p.PrivateKey + p.DBCore.EscEnd
-	}
-
-	if len(p.Limit) == 2 {
-		sql.WriteString(orderSQL)
-		sql.WriteString(" offset ")
-		sql.WriteString(util.UintToStr(p.Limit[0]))
-		sql.WriteString(" rows fetch next ")
-		sql.WriteString(util.UintToStr(p.Limit[1]))
-		sql.WriteString(" rows only")
-	}
-
-	// SelectMethod=cursor
-	if p.SelectForUpdate {
-		sql.WriteString(" for update")
-	}
-
-	return sql.String()
-}
-
-func (p *queryModel) sqlserverBinFormatSubSQL(colOperator string, colName string, val, rawVal string, rawStrArr []string,
+func sqlserverBinFormatSubSQL(binStr string, colOperator string, colName string, val, rawVal string, rawStrArr []string,
 	colData interface{}) string {
 	var subSQL strings.Builder
 	subSQL.Grow(20)
 	subSQL.WriteString(colName)
 	subSQL.WriteByte(' ')
-	subSQL.WriteString(p.DBCore.BinStr)
+	subSQL.WriteString(binStr)
 
 	switch colOperator {
 	case "eq":
@@ -105,13 +33,13 @@ func (p *queryModel) sqlserverBinFormatSubSQL(colOperator string, colName string
 	case "nin":
 		subSQL.WriteString(" not in ")
 	default:
-		return p.sqlserverBinLikeSQL(colOperator, colName, val, rawVal, rawStrArr)
+		return sqlserverBinLikeSQL(binStr, colOperator, colName, val, rawVal, rawStrArr)
 	}
 	subSQL.WriteString(val)
 	return subSQL.String()
 }
 
-func (p *queryModel) sqlserverBinLikeSQL(colOperator string, colName string, val, rawVal string, rawStrArr []string) string {
+func sqlserverBinLikeSQL(binStr string, colOperator string, colName string, val, rawVal string, rawStrArr []string) string {
 	var subSQL strings.Builder
 	subSQL.Grow(20)
 	switch colOperator {
@@ -119,7 +47,7 @@ func (p *queryModel) sqlserverBinLikeSQL(colOperator string, colName string, val
 		if rawVal != "" {
 			subSQL.WriteString(colName)
 			subSQL.WriteByte(' ')
-			subSQL.WriteString(p.DBCore.BinStr)
+			subSQL.WriteString(binStr)
 			subSQL.WriteString(" like '")
 			subSQL.WriteString(rawVal)
 			subSQL.WriteString("%'")
@@ -128,7 +56,7 @@ func (p *queryModel) sqlserverBinLikeSQL(colOperator string, colName string, val
 		if rawVal != "" {
 			subSQL.WriteString(colName)
 			subSQL.WriteByte(' ')
-			subSQL.WriteString(p.DBCore.BinStr)
+			subSQL.WriteString(binStr)
 			subSQL.WriteString(" like '%")
 			subSQL.WriteString(rawVal)
 			subSQL.WriteString("'")
@@ -137,7 +65,7 @@ func (p *queryModel) sqlserverBinLikeSQL(colOperator string, colName string, val
 		if rawVal != "" {
 			subSQL.WriteString(colName)
 			subSQL.WriteByte(' ')
-			subSQL.WriteString(p.DBCore.BinStr)
+			subSQL.WriteString(binStr)
 			subSQL.WriteString(" like '%")
 			subSQL.WriteString(rawVal)
 			subSQL.WriteString("%'")
@@ -151,7 +79,7 @@ func (p *queryModel) sqlserverBinLikeSQL(colOperator string, colName string, val
 
 				subSQL.WriteString(colName)
 				subSQL.WriteByte(' ')
-				subSQL.WriteString(p.DBCore.BinStr)
+				subSQL.WriteString(binStr)
 				subSQL.WriteString(" like '%")
 				subSQL.WriteString(v)
 				subSQL.WriteString("%'")
@@ -162,7 +90,7 @@ func (p *queryModel) sqlserverBinLikeSQL(colOperator string, colName string, val
 		if rawVal != "" {
 			subSQL.WriteString(colName)
 			subSQL.WriteByte(' ')
-			subSQL.WriteString(p.DBCore.BinStr)
+			subSQL.WriteString(binStr)
 			subSQL.WriteString(" like '")
 			subSQL.WriteString(rawVal)
 			subSQL.WriteString("'")
@@ -176,7 +104,7 @@ func (p *queryModel) sqlserverBinLikeSQL(colOperator string, colName string, val
 
 				subSQL.WriteString(colName)
 				subSQL.WriteByte(' ')
-				subSQL.WriteString(p.DBCore.BinStr)
+				subSQL.WriteString(binStr)
 				subSQL.WriteString(" like '")
 				subSQL.WriteString(v)
 				subSQL.WriteString("'")
@@ -184,12 +112,12 @@ func (p *queryModel) sqlserverBinLikeSQL(colOperator string, colName string, val
 			subSQL.WriteByte(')')
 		}
 	default:
-		return p.sqlserverBinOrLikeSQL(colOperator, colName, val, rawVal, rawStrArr)
+		return sqlserverBinOrLikeSQL(binStr, colOperator, colName, val, rawVal, rawStrArr)
 	}
 	return subSQL.String()
 }
 
-func (p *queryModel) sqlserverBinOrLikeSQL(colOperator string, colName string, val, rawVal string, rawStrArr []string) string {
+func sqlserverBinOrLikeSQL(binStr string, colOperator string, colName string, val, rawVal string, rawStrArr []string) string {
 	var subSQL strings.Builder
 	subSQL.Grow(20)
 	switch colOperator {
@@ -197,7 +125,7 @@ func (p *queryModel) sqlserverBinOrLikeSQL(colOperator string, colName string, v
 		if rawVal != "" {
 			subSQL.WriteString(colName)
 			subSQL.WriteByte(' ')
-			subSQL.WriteString(p.DBCore.BinStr)
+			subSQL.WriteString(binStr)
 			subSQL.WriteString(" like '")
 			subSQL.WriteString(rawVal)
 			subSQL.WriteString("%'")
@@ -211,7 +139,7 @@ func (p *queryModel) sqlserverBinOrLikeSQL(colOperator string, colName string, v
 
 				subSQL.WriteString(colName)
 				subSQL.WriteByte(' ')
-				subSQL.WriteString(p.DBCore.BinStr)
+				subSQL.WriteString(binStr)
 				subSQL.WriteString(" like '")
 				subSQL.WriteString(v)
 				subSQL.WriteString("%'")
@@ -222,7 +150,7 @@ func (p *queryModel) sqlserverBinOrLikeSQL(colOperator string, colName string, v
 		if rawVal != "" {
 			subSQL.WriteString(colName)
 			subSQL.WriteByte(' ')
-			subSQL.WriteString(p.DBCore.BinStr)
+			subSQL.WriteString(binStr)
 			subSQL.WriteString(" like '%")
 			subSQL.WriteString(rawVal)
 			subSQL.WriteString("'")
@@ -236,7 +164,7 @@ func (p *queryModel) sqlserverBinOrLikeSQL(colOperator string, colName string, v
 
 				subSQL.WriteString(colName)
 				subSQL.WriteByte(' ')
-				subSQL.WriteString(p.DBCore.BinStr)
+				subSQL.WriteString(binStr)
 				subSQL.WriteString(" like '%")
 				subSQL.WriteString(v)
 				subSQL.WriteString("'")
@@ -247,7 +175,7 @@ func (p *queryModel) sqlserverBinOrLikeSQL(colOperator string, colName string, v
 		if rawVal != "" {
 			subSQL.WriteString(colName)
 			subSQL.WriteByte(' ')
-			subSQL.WriteString(p.DBCore.BinStr)
+			subSQL.WriteString(binStr)
 			subSQL.WriteString(" like '%")
 			subSQL.WriteString(rawVal)
 			subSQL.WriteString("%'")
@@ -261,7 +189,7 @@ func (p *queryModel) sqlserverBinOrLikeSQL(colOperator string, colName string, v
 
 				subSQL.WriteString(colName)
 				subSQL.WriteByte(' ')
-				subSQL.WriteString(p.DBCore.BinStr)
+				subSQL.WriteString(binStr)
 				subSQL.WriteString(" like '%")
 				subSQL.WriteString(v)
 				subSQL.WriteString("%'")
@@ -272,7 +200,7 @@ func (p *queryModel) sqlserverBinOrLikeSQL(colOperator string, colName string, v
 		if rawVal != "" {
 			subSQL.WriteString(colName)
 			subSQL.WriteByte(' ')
-			subSQL.WriteString(p.DBCore.BinStr)
+			subSQL.WriteString(binStr)
 			subSQL.WriteString(" like '")
 			subSQL.WriteString(rawVal)
 			subSQL.WriteString("'")
@@ -286,7 +214,7 @@ func (p *queryModel) sqlserverBinOrLikeSQL(colOperator string, colName string, v
 
 				subSQL.WriteString(colName)
 				subSQL.WriteByte(' ')
-				subSQL.WriteString(p.DBCore.BinStr)
+				subSQL.WriteString(binStr)
 				subSQL.WriteString(" like '")
 				subSQL.WriteString(v)
 				subSQL.WriteString("'")
@@ -294,446 +222,164 @@ func (p *queryModel) sqlserverBinOrLikeSQL(colOperator string, colName string, v
 			subSQL.WriteByte(')')
 		}
 	default:
-		panic("no definition")
+		panic("is not defined")
 	}
 	return subSQL.String()
 }
 
-// orm
-func (orm *ORM) sqlserverUpsertSQL(data interface{}) (string, error) {
-	if data == nil {
-		return "", fmt.Errorf("upsert data is nil")
-	}
-
-	dbCore := orm.ref.getDBConf()
-	escLen := len(dbCore.EscStart) + len(dbCore.EscEnd)
-
-	typeErrStr := "type of upsert data is map[string]interface{} or *struct or struct"
-	var formatCols []string
-	rawColSet := set.New[string]()
-	var rawVal []string
-
-	switch data := data.(type) {
-	case map[string]interface{}:
-		for k, v := range data {
-			if k[0] == '#' {
-				k = k[1:]
-				err := globalVerifyObj.VerifyFieldName(k)
-				if err != nil {
-					return "", err
-				}
-				var formatKey strings.Builder
-				formatKey.Grow(escLen + len(k))
-				formatKey.WriteString(dbCore.EscStart)
-				formatKey.WriteString(k)
-				formatKey.WriteString(dbCore.EscEnd)
-
-				formatCols = append(formatCols, formatKey.String())
-				rawColSet.Add(k)
-
-				val := util.Any2String(v)
-				rawVal = append(rawVal, val)
-				continue
-			}
-
-			err := globalVerifyObj.VerifyFieldName(k)
-			if err != nil {
-				return "", err
-			}
-
-			val, timeEmpty := orm.formatValue(v)
-			if k == orm.primaryKey && (val == "" || val == "0") {
-				continue
-			}
-			if timeEmpty {
-				continue
-			}
-			var formatKey strings.Builder
-			formatKey.Grow(escLen + len(k))
-			formatKey.WriteString(dbCore.EscStart)
-			formatKey.WriteString(k)
-			formatKey.WriteString(dbCore.EscEnd)
-
-			formatCols = append(formatCols, formatKey.String())
-			rawColSet.Add(k)
-			rawVal = append(rawVal, val)
-		}
-	default:
-		dataValue := reflect.ValueOf(data)
-		if dataValue.Type().Kind() != reflect.Struct && dataValue.Type().Kind() != reflect.Ptr {
-			return "", fmt.Errorf(typeErrStr)
-		}
-
-		if dataValue.Type().Kind() == reflect.Ptr {
-			dataValue = dataValue.Elem()
-		}
-
-		if dataValue.Type().Kind() != reflect.Struct {
-			return "", fmt.Errorf(typeErrStr)
-		}
-
-		for i := 0; i < dataValue.NumField(); i++ {
-			colName := dataValue.Type().Field(i).Tag.Get("json")
-			ref := dataValue.Type().Field(i).Tag.Get("ref")
-			if ref != "" || colName == "" || !dataValue.Type().Field(i).IsExported() {
-				continue
-			}
-
-			val, timeEmpty := orm.formatValue(dataValue.Field(i).Interface())
-			if colName == orm.primaryKey && (val == "" || val == "0") {
-				continue
-			}
-			if timeEmpty {
-				continue
-			}
-
-			var formatKey strings.Builder
-			formatKey.Grow(escLen + len(colName))
-			formatKey.WriteString(dbCore.EscStart)
-			formatKey.WriteString(colName)
-			formatKey.WriteString(dbCore.EscEnd)
+// sqlserverDialect sqlserver，@pn 占位符，2012 以下使用 ROW_NUMBER() 分页，upsert 使用 merge into
+type sqlserverDialect struct {
+	BaseDialect
+	binStr string
+	// version 主版本号：10 为 2008，11 为 2012，nil 为未指定
+	version []int
+}
 
-			formatCols = append(formatCols, formatKey.String())
-			rawColSet.Add(colName)
-			rawVal = append(rawVal, val)
-		}
-	}
-	if len(formatCols) <= 0 {
-		return "", fmt.Errorf("sql data is empty, please check it")
-	}
+// sqlserverYears 发行年份对应的主版本号
+var sqlserverYears = [][2]int{
+	{2000, 8}, {2005, 9}, {2008, 10}, {2012, 11}, {2014, 12}, {2016, 13}, {2017, 14}, {2019, 15}, {2022, 16},
+}
 
-	hasPK := rawColSet.Has(orm.primaryKey)
-	hasUK := false
-	if !hasPK {
-		hasUK = orm.checkUK(rawColSet)
+// WithVersion version 可以是发行年份（2008 2012）或主版本号（10.50 11.0）
+func (d sqlserverDialect) WithVersion(version string) (Dialect, error) {
+	ver, err := parseVersion(version)
+	if err != nil {
+		return nil, err
 	}
 
-	if hasPK || hasUK {
-		var upsertSQL strings.Builder
-		upsertSQL.Grow(len(formatCols)*10 + 100)
-
-		upsertSQL.WriteString("MERGE INTO ")
-		upsertSQL.WriteString(dbCore.EscStart)
-		upsertSQL.WriteString(orm.tableName)
-		upsertSQL.WriteString(dbCore.EscEnd)
-		upsertSQL.WriteString(" as [T] USING (values(")
-		for i := range rawVal {
-			if i > 0 {
-				upsertSQL.WriteByte(',')
+	if ver[0] >= sqlserverYears[0][0] {
+		year := ver[0]
+		ver = []int{sqlserverYears[0][1]}
+		for _, v := range sqlserverYears {
+			if year >= v[0] {
+				ver[0] = v[1]
 			}
-			upsertSQL.WriteString(rawVal[i])
 		}
-		upsertSQL.WriteString(")) as [S] ON (")
-
-		if hasPK {
-			upsertSQL.WriteString("[T].")
-			upsertSQL.WriteString(dbCore.EscStart)
-			upsertSQL.WriteString(orm.primaryKey)
-			upsertSQL.WriteString(dbCore.EscEnd)
-			upsertSQL.WriteString("=[S].")
-			upsertSQL.WriteString(dbCore.EscStart)
-			upsertSQL.WriteString(orm.primaryKey)
-			upsertSQL.WriteString(dbCore.EscEnd)
-		} else {
-			var onStrBuff strings.Builder
-			onStrBuff.Grow(orm.uniqueKeys.Size() * (escLen + 6 + 10))
-			orm.uniqueKeys.Range(func(item string) bool {
-				if onStrBuff.Len() > 0 {
-					onStrBuff.WriteString(" and ")
-				}
-
-				onStrBuff.WriteString("[T].")
-				onStrBuff.WriteString(dbCore.EscStart)
-				onStrBuff.WriteString(item)
-				onStrBuff.WriteString(dbCore.EscEnd)
-				onStrBuff.WriteString("=[S].")
-				onStrBuff.WriteString(dbCore.EscStart)
-				onStrBuff.WriteString(item)
-				onStrBuff.WriteString(dbCore.EscEnd)
-				return true
-			})
-			upsertSQL.WriteString(onStrBuff.String())
-		}
-		upsertSQL.WriteString(") WHEN MATCHED THEN UPDATE SET ")
-		if hasPK {
-			has := false
-			formatPK := dbCore.EscStart + orm.primaryKey + dbCore.EscEnd
-			for i := range formatCols {
-				if formatPK == formatCols[i] {
-					continue
-				}
-
-				if has {
-					upsertSQL.WriteByte(',')
-				}
-				has = true
+	}
+	d.version = ver
+	return d, nil
+}
 
-				upsertSQL.WriteString("[T].")
-				upsertSQL.WriteString(formatCols[i])
-				upsertSQL.WriteString("=[S].")
-				upsertSQL.WriteString(formatCols[i])
-			}
-		} else {
-			has := false
-			for i := range formatCols {
-				k := formatCols[i]
-				if orm.uniqueKeys.Has(k[len(dbCore.EscStart) : len(k)-len(dbCore.EscEnd)]) {
-					continue
-				}
+func (d sqlserverDialect) Placeholder(n int) string {
+	return "@p" + strconv.Itoa(n)
+}
 
-				if has {
-					upsertSQL.WriteByte(',')
-				}
-				has = true
+// With 递归不需要 recursive 关键字
+func (d sqlserverDialect) With(list []*WithParts) (string, error) {
+	return withSQL(list, "", false), nil
+}
 
-				upsertSQL.WriteString("[T].")
-				upsertSQL.WriteString(formatCols[i])
-				upsertSQL.WriteString("=[S].")
-				upsertSQL.WriteString(formatCols[i])
-			}
+func (d sqlserverDialect) Select(q *SelectParts) string {
+	// offset fetch 需要 2012 及以上版本
+	if len(q.Limit) == 2 && versionBelow(d.version, 11) {
+		sql := rowNumberSQL(d.EscStart, d.EscEnd, d.TableAlias, q, "(SELECT NULL)")
+		if q.ForUpdate {
+			sql += " for update"
 		}
-		upsertSQL.WriteString(" WHEN NOT MATCHED THEN INSERT(")
-		upsertSQL.WriteString(util.JoinArr(formatCols, ","))
-		upsertSQL.WriteString(" VALUES(")
-		upsertSQL.WriteString(connectStrArr(formatCols, ",", "[S].", ""))
-		upsertSQL.WriteString(");")
-		return upsertSQL.String(), nil
+		return sql
 	}
 
-	var upsertSQL strings.Builder
-	upsertSQL.WriteString("insert into ")
-	upsertSQL.WriteString(dbCore.EscStart)
-	upsertSQL.WriteString(orm.tableName)
-	upsertSQL.WriteString(dbCore.EscEnd)
-	upsertSQL.WriteByte('(')
-	upsertSQL.WriteString(util.JoinArr(formatCols, ","))
-	upsertSQL.WriteString(") VALUES(")
-	upsertSQL.WriteString(util.JoinArr(rawVal, ","))
-	upsertSQL.WriteByte(')')
-	return upsertSQL.String(), nil
-}
+	var sql strings.Builder
+	sql.Grow(100)
+	sql.WriteString("select ")
 
-func (orm *ORM) sqlserverUpsertManySQL(dataList []interface{}, cols []string) (string, error) {
-	if len(dataList) <= 0 {
-		return "", fmt.Errorf("upsert data is nil")
+	if len(q.Limit) == 1 {
+		sql.WriteString(" top(")
+		sql.WriteString(util.UintToStr(q.Limit[0]))
+		sql.WriteString(") ")
 	}
-
-	dbCore := orm.ref.getDBConf()
-	escLen := len(dbCore.EscStart) + len(dbCore.EscEnd)
-
-	typeErrStr := "type of upsert data is []map[string]interface{} or []*struct or []struct"
-
-	formatCols := make([]string, 0, len(cols))
-	for _, k := range cols {
-		err := globalVerifyObj.VerifyFieldName(k)
-		if err != nil {
-			return "", err
+	q.writeFrom(&sql, d.TableAlias)
+	q.writeWhere(&sql, "")
+	q.writeGroupBy(&sql)
+	q.writeOrderBy(&sql)
+
+	if len(q.Limit) == 2 {
+		// offset fetch 必须排序，默认按主键
+		if q.OrderBy == "" {
+			sql.WriteString(" order by ")
+			sql.WriteString(quoteName(d.EscStart, d.EscEnd, q.PrimaryKey))
 		}
-		var formatKey strings.Builder
-		formatKey.Grow(escLen + len(k))
-		formatKey.WriteString(dbCore.EscStart)
-		formatKey.WriteString(k)
-		formatKey.WriteString(dbCore.EscEnd)
-
-		formatCols = append(formatCols, formatKey.String())
+		sql.WriteString(" offset ")
+		sql.WriteString(util.UintToStr(q.Limit[0]))
+		sql.WriteString(" rows fetch next ")
+		sql.WriteString(util.UintToStr(q.Limit[1]))
+		sql.WriteString(" rows only")
 	}
 
-	rawColSet := set.New[string]()
-	rawColSet.Add(cols...)
-
-	rawVal := make([][]string, 0, len(dataList))
-	for _, data := range dataList {
-		var valMap map[string]interface{}
-		switch data := data.(type) {
-		case map[string]interface{}:
-			valMap = data
-		default:
-			dataValue := reflect.ValueOf(data)
-			if dataValue.Type().Kind() != reflect.Struct && dataValue.Type().Kind() != reflect.Ptr {
-				return "", fmt.Errorf(typeErrStr)
-			}
-
-			if dataValue.Type().Kind() == reflect.Ptr {
-				dataValue = dataValue.Elem()
-			}
-
-			if dataValue.Type().Kind() != reflect.Struct {
-				return "", fmt.Errorf(typeErrStr)
-			}
-
-			valMap = map[string]interface{}{}
+	// SelectMethod=cursor
+	if q.ForUpdate {
+		sql.WriteString(" for update")
+	}
+	return sql.String()
+}
 
-			for i := 0; i < dataValue.NumField(); i++ {
-				colName := dataValue.Type().Field(i).Tag.Get("json")
-				ref := dataValue.Type().Field(i).Tag.Get("ref")
-				if ref != "" || colName == "" || !dataValue.Type().Field(i).IsExported() {
-					continue
-				}
+// NullsOrder 不支持 nulls first/last，使用 case when 排序
+func (d sqlserverDialect) NullsOrder(column string, desc, nullsFirst bool) string {
+	return nullsCaseOrder(column, desc, nullsFirst)
+}
 
-				valMap[colName] = dataValue.Field(i).Interface()
-			}
-		}
-		if len(valMap) <= 0 {
-			return "", fmt.Errorf("sql data is empty, please check it")
-		}
+func (d sqlserverDialect) BinCondition(c *Condition) (string, bool) {
+	return sqlserverBinFormatSubSQL(d.binStr, c.Operator, c.Column, c.Value, c.Raw, c.RawList, c.Data), true
+}
 
-		subVal := make([]string, 0, len(cols))
-		for _, colName := range cols {
-			if v, ok := valMap[colName]; ok {
-				val, timeEmpty := orm.formatValue(v)
-				if (colName == orm.primaryKey && (val == "" || val == "0")) || timeEmpty {
-					subVal = append(subVal, "null")
-					continue
-				}
+// Search 需要全文索引，query 为 CONTAINS 的检索条件，如：'"手机*"'
+func (d sqlserverDialect) Search(column, query string) (string, error) {
+	return "CONTAINS(" + column + "," + query + ")", nil
+}
 
-				subVal = append(subVal, val)
-			} else if v, ok = valMap["#"+colName]; ok {
-				subVal = append(subVal, util.Any2String(v))
-			} else {
-				subVal = append(subVal, "null")
-			}
-		}
-		rawVal = append(rawVal, subVal)
+// DatePart weekday 与 @@DATEFIRST 无关，周一为 1
+func (d sqlserverDialect) DatePart(column, part string) (string, error) {
+	switch part {
+	case "week":
+		return "DATEPART(iso_week," + column + ")", nil
+	case "weekday":
+		return "((DATEPART(weekday," + column + ")+@@DATEFIRST+5)%7+1)", nil
 	}
+	return "DATEPART(" + part + "," + column + ")", nil
+}
 
-	if len(rawVal) <= 0 {
-		return "", fmt.Errorf("upsert data is empty")
+// DateTrunc DATEADD(unit,DATEDIFF(unit,0,字段),0)，0 为 1900-01-01（周一），week 按天计算
+func (d sqlserverDialect) DateTrunc(column, unit string) (string, error) {
+	if unit == "week" {
+		return "DATEADD(day,DATEDIFF(day,0," + column + ")/7*7,0)", nil
 	}
+	return "DATEADD(" + unit + ",DATEDIFF(" + unit + ",0," + column + "),0)", nil
+}
 
-	hasPK := rawColSet.Has(orm.primaryKey)
-	hasUK := false
-	if !hasPK {
-		hasUK = orm.checkUK(rawColSet)
+// JSONHasKey JSON_PATH_EXISTS 需要 2022 及以上版本
+func (d sqlserverDialect) JSONHasKey(column, key string) (string, error) {
+	if versionBelow(d.version, 16) {
+		return "", ErrDBFunc
 	}
+	return "JSON_PATH_EXISTS(" + column + ",'" + jsonPathLiteral([]string{key}) + "')=1", nil
+}
 
-	if hasPK || hasUK {
-		var upsertSQL strings.Builder
-		upsertSQL.Grow(len(formatCols)*10 + 100)
-
-		upsertSQL.WriteString("MERGE INTO ")
-		upsertSQL.WriteString(dbCore.EscStart)
-		upsertSQL.WriteString(orm.tableName)
-		upsertSQL.WriteString(dbCore.EscEnd)
-		upsertSQL.WriteString(" as [T] USING (values")
-		for index := range rawVal {
-			if index > 0 {
-				upsertSQL.WriteByte(',')
-			}
-			upsertSQL.WriteString("(")
-			for i := range formatCols {
-				if i > 0 {
-					upsertSQL.WriteByte(',')
-				}
-				upsertSQL.WriteString(rawVal[index][i])
-			}
-			upsertSQL.WriteString(")")
-		}
-
-		upsertSQL.WriteString(") as [S] ON (")
-
-		if hasPK {
-			upsertSQL.WriteString("[T].")
-			upsertSQL.WriteString(dbCore.EscStart)
-			upsertSQL.WriteString(orm.primaryKey)
-			upsertSQL.WriteString(dbCore.EscEnd)
-			upsertSQL.WriteString("=[S].")
-			upsertSQL.WriteString(dbCore.EscStart)
-			upsertSQL.WriteString(orm.primaryKey)
-			upsertSQL.WriteString(dbCore.EscEnd)
-		} else {
-			var onStrBuff strings.Builder
-			onStrBuff.Grow(orm.uniqueKeys.Size() * (escLen + 6 + 10))
-			orm.uniqueKeys.Range(func(item string) bool {
-				if onStrBuff.Len() > 0 {
-					onStrBuff.WriteString(" and ")
-				}
-
-				onStrBuff.WriteString("[T].")
-				onStrBuff.WriteString(dbCore.EscStart)
-				onStrBuff.WriteString(item)
-				onStrBuff.WriteString(dbCore.EscEnd)
-				onStrBuff.WriteString("=[S].")
-				onStrBuff.WriteString(dbCore.EscStart)
-				onStrBuff.WriteString(item)
-				onStrBuff.WriteString(dbCore.EscEnd)
-				return true
-			})
-			upsertSQL.WriteString(onStrBuff.String())
-		}
-		upsertSQL.WriteString(") WHEN MATCHED THEN UPDATE SET ")
-		if hasPK {
-			has := false
-			formatPK := dbCore.EscStart + orm.primaryKey + dbCore.EscEnd
-			for i := range formatCols {
-				if formatPK == formatCols[i] {
-					continue
-				}
-
-				if has {
-					upsertSQL.WriteByte(',')
-				}
-				has = true
-
-				upsertSQL.WriteString("[T].")
-				upsertSQL.WriteString(formatCols[i])
-				upsertSQL.WriteString("=[S].")
-				upsertSQL.WriteString(formatCols[i])
-			}
-		} else {
-			has := false
-			for i := range formatCols {
-				k := formatCols[i]
-				if orm.uniqueKeys.Has(k[len(dbCore.EscStart) : len(k)-len(dbCore.EscEnd)]) {
-					continue
-				}
-
-				if has {
-					upsertSQL.WriteByte(',')
-				}
-				has = true
-
-				upsertSQL.WriteString("[T].")
-				upsertSQL.WriteString(formatCols[i])
-				upsertSQL.WriteString("=[S].")
-				upsertSQL.WriteString(formatCols[i])
-			}
-		}
-		upsertSQL.WriteString(" WHEN NOT MATCHED THEN INSERT(")
-		upsertSQL.WriteString(util.JoinArr(formatCols, ","))
-		upsertSQL.WriteString(" VALUES(")
-		upsertSQL.WriteString(connectStrArr(formatCols, ",", "[S].", ""))
-		upsertSQL.WriteString(");")
-		return upsertSQL.String(), nil
+func (d sqlserverDialect) Upsert(w *WriteParts) (string, error) {
+	// merge 需要 2008 及以上版本
+	if versionBelow(d.version, 10) {
+		return "", ErrDBFunc
 	}
 
-	var insertSQL strings.Builder
-	insertSQL.Grow(len(rawVal)*len(cols)*5 + 200)
-
-	insertSQL.WriteString("insert into ")
-	insertSQL.WriteString(dbCore.EscStart)
-	insertSQL.WriteString(orm.tableName)
-	insertSQL.WriteString(dbCore.EscEnd)
-	insertSQL.WriteByte('(')
-	for i := range formatCols {
-		if i > 0 {
-			insertSQL.WriteByte(',')
-		}
-		insertSQL.WriteString(formatCols[i])
+	keys := w.ConflictKeys()
+	if keys == nil {
+		return d.Insert(w)
 	}
-	insertSQL.WriteString(") values")
 
-	for i := 0; i < len(rawVal); i++ {
+	var source strings.Builder
+	source.Grow(len(w.Rows)*len(w.Cols)*5 + 20)
+	source.WriteString("(values")
+	for i, row := range w.Rows {
 		if i > 0 {
-			insertSQL.WriteByte(',')
+			source.WriteByte(',')
 		}
-		insertSQL.WriteByte('(')
-		for j := 0; j < len(rawVal[i]); j++ {
-			if j > 0 {
-				insertSQL.WriteByte(',')
-			}
-			insertSQL.WriteString(rawVal[i][j])
-		}
-		insertSQL.WriteByte(')')
+		source.WriteByte('(')
+		source.WriteString(util.JoinArr(row, ","))
+		source.WriteByte(')')
 	}
-	return insertSQL.String(), nil
+	source.WriteByte(')')
+	return mergeSQL(d.EscStart, d.EscEnd, d.TableAlias, source.String(), w, keys) + ";", nil
+}
+
+func (d sqlserverDialect) LastInsertID() bool {
+	return false
 }
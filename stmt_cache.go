@@ -0,0 +1,224 @@
+// Package orm
+package orm
+
+import (
+	"container/list"
+	"context"
+	"database/sql"
+	"strings"
+	"sync"
+
+	"github.com/assembly-hub/db"
+)
+
+const (
+	defStmtCacheSize = 200
+)
+
+// stmtCacheMap 预编译语句缓存，key：db.Executor；value：*stmtCache
+var stmtCacheMap sync.Map
+
+// EnableStmtCache 为 executor 开启预编译语句缓存，size 为最多缓存的语句数，<=0 时使用默认值 200
+// 仅缓存带绑定参数的SQL（BindParams 模式），同一个 executor 上的所有 ORM 共享，事务（Tx）中不使用缓存
+func EnableStmtCache(executor db.Executor, size int) {
+	if executor == nil {
+		panic("executor is nil")
+	}
+
+	if size <= 0 {
+		size = defStmtCacheSize
+	}
+
+	cache := &stmtCache{
+		executor: executor,
+		size:     size,
+		stmts:    map[string]*list.Element{},
+		lru:      list.New(),
+	}
+	old, ok := stmtCacheMap.Load(executor)
+	stmtCacheMap.Store(executor, cache)
+	if ok {
+		old.(*stmtCache).close()
+	}
+}
+
+// DisableStmtCache 关闭 executor 的预编译语句缓存，并关闭已缓存的语句
+func DisableStmtCache(executor db.Executor) {
+	if executor == nil {
+		return
+	}
+
+	if old, ok := stmtCacheMap.LoadAndDelete(executor); ok {
+		old.(*stmtCache).close()
+	}
+}
+
+func getStmtCache(sqlDB db.BaseExecutor) *stmtCache {
+	executor, ok := sqlDB.(db.Executor)
+	if !ok || executor == nil {
+		return nil
+	}
+
+	cache, ok := stmtCacheMap.Load(executor)
+	if !ok {
+		return nil
+	}
+	return cache.(*stmtCache)
+}
+
+type stmtEntry struct {
+	key  string
+	stmt db.Stmt
+	// 正在使用的次数，淘汰后使用结束再关闭
+	refs    int
+	evicted bool
+}
+
+// stmtCache LRU 预编译语句缓存
+type stmtCache struct {
+	executor db.Executor
+	size     int
+
+	mu    sync.Mutex
+	stmts map[string]*list.Element
+	lru   *list.List
+}
+
+// acquire 获取预编译语句，不存在则预编译并缓存，使用结束需要调用 release
+func (c *stmtCache) acquire(ctx context.Context, sqlStr string) (*stmtEntry, error) {
+	key := normalizeSQL(sqlStr)
+
+	c.mu.Lock()
+	if elem, ok := c.stmts[key]; ok {
+		c.lru.MoveToFront(elem)
+		entry := elem.Value.(*stmtEntry)
+		entry.refs++
+		c.mu.Unlock()
+		return entry, nil
+	}
+	c.mu.Unlock()
+
+	stmt, err := c.executor.PrepareContext(ctx, sqlStr)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// 并发预编译了同样的语句，使用已缓存的
+	if elem, ok := c.stmts[key]; ok {
+		_ = stmt.Close()
+		c.lru.MoveToFront(elem)
+		entry := elem.Value.(*stmtEntry)
+		entry.refs++
+		return entry, nil
+	}
+
+	entry := &stmtEntry{
+		key:  key,
+		stmt: stmt,
+		refs: 1,
+	}
+	c.stmts[key] = c.lru.PushFront(entry)
+	for c.lru.Len() > c.size {
+		c.evict(c.lru.Back())
+	}
+	return entry, nil
+}
+
+// release 使用结束
+func (c *stmtCache) release(entry *stmtEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry.refs--
+	if entry.evicted && entry.refs <= 0 {
+		_ = entry.stmt.Close()
+	}
+}
+
+func (c *stmtCache) evict(elem *list.Element) {
+	entry := elem.Value.(*stmtEntry)
+	c.lru.Remove(elem)
+	delete(c.stmts, entry.key)
+	entry.evicted = true
+	if entry.refs <= 0 {
+		_ = entry.stmt.Close()
+	}
+}
+
+func (c *stmtCache) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for c.lru.Len() > 0 {
+		c.evict(c.lru.Back())
+	}
+}
+
+// normalizeSQL 缓存使用的SQL，去除首尾空白并合并引号外连续的空白
+func normalizeSQL(s string) string {
+	s = strings.TrimSpace(s)
+
+	var strBuf strings.Builder
+	strBuf.Grow(len(s))
+	var quote byte
+	space := false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if quote != 0 {
+			strBuf.WriteByte(c)
+			if c == quote {
+				quote = 0
+			}
+			continue
+		}
+
+		switch c {
+		case ' ', '\t', '\n', '\r':
+			space = true
+			continue
+		case '\'', '"', '`':
+			quote = c
+		}
+
+		if space {
+			strBuf.WriteByte(' ')
+			space = false
+		}
+		strBuf.WriteByte(c)
+	}
+	return strBuf.String()
+}
+
+// execContext 执行SQL，executor 开启了预编译语句缓存且存在绑定参数时使用缓存的语句
+func execContext(ctx context.Context, sqlDB db.BaseExecutor, sqlStr string, args []interface{}) (sql.Result, error) {
+	if cache := getStmtCache(sqlDB); cache != nil && len(args) > 0 {
+		entry, err := cache.acquire(ctx, sqlStr)
+		if err != nil {
+			return nil, err
+		}
+		defer cache.release(entry)
+		return entry.stmt.ExecContext(ctx, args...)
+	}
+	return sqlDB.ExecContext(ctx, sqlStr, args...)
+}
+
+// queryContext 查询SQL，executor 开启了预编译语句缓存且存在绑定参数时使用缓存的语句；
+// ROW_NUMBER() 分页的序号字段不包括在结果中
+func queryContext(ctx context.Context, sqlDB db.BaseExecutor, sqlStr string, args []interface{}) (rows db.Rows, err error) {
+	if cache := getStmtCache(sqlDB); cache != nil && len(args) > 0 {
+		var entry *stmtEntry
+		entry, err = cache.acquire(ctx, sqlStr)
+		if err != nil {
+			return nil, err
+		}
+		defer cache.release(entry)
+		rows, err = entry.stmt.QueryContext(ctx, args...)
+	} else {
+		rows, err = sqlDB.QueryContext(ctx, sqlStr, args...)
+	}
+
+	if err == nil && rows != nil && strings.Contains(sqlStr, rowNumberAlias) {
+		rows = hideRowNumber(rows)
+	}
+	return rows, err
+}
@@ -14,3 +14,11 @@ type Order = []string
 type Limit = []uint
 type GroupBy = []string
 type Having = map[string]interface{}
+
+// Col 字段引用，作为 Where Having 的值时与字段比较，规则与条件的字段一致，支持 tag 与 #，
+// 仅支持比较操作符（eq ne lt lte gt gte），如：orm.Where{"updated_at__gt": orm.Col("created_at")}
+type Col string
+
+// OuterCol 外层查询的字段，用于关联子查询（Where 的值为子查询、orm.Sub）的条件与表达式，按外层查询的 tag 解析，
+// 如：orm.Where{"ref_id": orm.OuterCol("id")}
+type OuterCol string
@@ -0,0 +1,169 @@
+package orm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// setAlias 集合运算结果（派生表）的别名
+const setAlias = defaultAliasPrefix + "set"
+
+// SetQuery 集合运算的查询，Op 为：union、union all、intersect、except
+type SetQuery struct {
+	Op    string
+	Query *BaseQuery
+}
+
+type setORM struct {
+	op    string
+	query *ORM
+}
+
+type setModel struct {
+	op    string
+	query *queryModel
+}
+
+func (orm *ORM) setOperation(op string, others []*ORM) *ORM {
+	for _, other := range others {
+		if other == nil {
+			panic(op + " query is nil")
+		}
+		orm.Q.SetQuery = append(orm.Q.SetQuery, &setORM{op: op, query: other})
+	}
+	return orm
+}
+
+// setQuery 集合运算的查询，字段别名与主查询一致
+func (orm *ORM) setQuery(flat bool) []*SetQuery {
+	if len(orm.Q.SetQuery) <= 0 {
+		return nil
+	}
+
+	list := make([]*SetQuery, 0, len(orm.Q.SetQuery))
+	for _, s := range orm.Q.SetQuery {
+		list = append(list, &SetQuery{Op: s.op, Query: s.query.cond(flat)})
+	}
+	return list
+}
+
+// setCond 集合运算：select * from (q union q1 ...) as orm_set，当前查询的排序与分页作用于合并后的结果
+func (q *BaseQuery) setCond() *queryModel {
+	if len(q.SearchOrder) > 0 || len(q.ValuesOrder) > 0 || len(q.OrderExpr) > 0 {
+		panic("set operation query only supports Order")
+	}
+
+	first := *q
+	first.SetQuery = nil
+	first.With = nil
+	first.Order = nil
+	first.Limit = nil
+	first.SelectForUpdate = false
+	first.Settings = nil
+
+	list := make([]*setModel, 0, len(q.SetQuery)+1)
+	list = append(list, &setModel{query: first.cond()})
+	count := list[0].query.columnCount()
+	for _, s := range q.SetQuery {
+		if s.Query == nil {
+			panic(s.Op + " query is nil")
+		}
+		if len(s.Query.Order) > 0 || len(s.Query.SearchOrder) > 0 || len(s.Query.ValuesOrder) > 0 || len(s.Query.OrderExpr) > 0 || len(s.Query.Limit) > 0 {
+			panic(s.Op + " query does not support order and limit, please set them on the main query")
+		}
+
+		sub := s.Query.cond()
+		if n := sub.columnCount(); count >= 0 && n >= 0 && n != count {
+			panic(fmt.Sprintf("%s query column count[%d] does not match[%d]", s.Op, n, count))
+		}
+		list = append(list, &setModel{op: s.Op, query: sub})
+	}
+
+	dbCore := q.RefConf.getDBConf()
+	alias := dbCore.EscStart + setAlias + dbCore.EscEnd
+	query := &queryModel{
+		PrivateKey: q.PrivateKey,
+		DBCore:     dbCore,
+		MainTable:  alias,
+		MainAlias:  alias,
+		Limit:      q.Limit,
+		Order:      q.setOrderData(alias),
+		SetQuery:   list,
+		With:       q.withData(),
+	}
+	if len(q.Settings) > 0 {
+		query.Settings = clickhouseSettingsSQL(q.Settings)
+	}
+	return query
+}
+
+// setOrderData 合并结果的排序，字段为结果的字段名，跨表字段为 tag_字段（与查询字段的别名一致），# 为原始字段
+func (q *BaseQuery) setOrderData(alias string) []*orderModel {
+	if len(q.Order) <= 0 {
+		return nil
+	}
+
+	dbCore := q.RefConf.getDBConf()
+	linkStr := "_"
+	if q.SelectColLinkStr != "" {
+		linkStr = q.SelectColLinkStr
+	}
+
+	orderObj := &orderModel{}
+	for _, sel := range q.Order {
+		sel, nulls := splitNullsOrder(sel)
+		orderObj.Nulls = append(orderObj.Nulls, nulls)
+
+		prefix := ""
+		if sel[0] == '-' || sel[0] == '+' {
+			prefix = sel[:1]
+			sel = sel[1:]
+		}
+
+		if sel[0] == '#' {
+			orderObj.Cols = append(orderObj.Cols, prefix+sel[1:])
+		} else {
+			col := strings.ReplaceAll(sel, ".", linkStr)
+			err := globalVerifyObj.VerifyFieldName(col)
+			if err != nil {
+				panic(err)
+			}
+			orderObj.Cols = append(orderObj.Cols, prefix+alias+"."+dbCore.EscStart+col+dbCore.EscEnd)
+		}
+	}
+	return []*orderModel{orderObj}
+}
+
+// columnCount 查询字段的数量，包含 * 时无法确定，返回 -1
+func (p *queryModel) columnCount() int {
+	n := len(p.SelectExpr)
+	for _, sel := range p.Select {
+		for _, col := range sel.Cols {
+			if strings.HasSuffix(col, "*") {
+				return -1
+			}
+			n++
+		}
+	}
+	return n
+}
+
+// setSQL 集合运算的SQL：q union q1 ...
+func (p *queryModel) setSQL() string {
+	var sql strings.Builder
+	sql.Grow(len(p.SetQuery) * 100)
+	for _, s := range p.SetQuery {
+		if s.op != "" {
+			op, err := p.DBCore.Dialect.SetOperation(s.op)
+			if err != nil {
+				panic(err)
+			}
+			sql.WriteByte(' ')
+			sql.WriteString(op)
+			sql.WriteByte(' ')
+		}
+		s.query.BindParams = p.BindParams
+		sql.WriteString(s.query.SQL())
+	}
+	return sql.String()
+}
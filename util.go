@@ -8,9 +8,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"reflect"
+	"sort"
 	"strings"
 	"time"
 
+	"github.com/assembly-hub/basics/set"
 	"github.com/assembly-hub/basics/util"
 	"github.com/assembly-hub/db"
 )
@@ -220,7 +222,8 @@ func count(ctx context.Context, sqlDB db.BaseExecutor, q *BaseQuery) (int64, err
 	var rows db.Rows
 	var err error
 	if sqlDB != nil {
-		rows, err = sqlDB.QueryContext(ctx, q.Count())
+		sqlStr, args := q.buildCount()
+		rows, err = queryContext(ctx, sqlDB, sqlStr, args)
 	} else {
 		return 0, ErrClient
 	}
@@ -255,3 +258,29 @@ func connectStrArr(arr []string, linkStr string, start, end string) string {
 
 	return s.String()
 }
+
+// sortedKeys 排序后的键，保证同样的数据每次生成的SQL完全一致
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// sortedSet 排序后的集合元素
+func sortedSet(s set.Set[string]) []string {
+	list := s.ToList()
+	sort.Strings(list)
+	return list
+}
+
+// jsonValue 转换为json字符串，字符串同样视为值（"a"），json.RawMessage 原样使用
+func jsonValue(v interface{}) string {
+	bytes, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return string(bytes)
+}
@@ -0,0 +1,163 @@
+package orm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 窗口函数：orm.Over(函数) 或 RowNumber Rank DenseRank Lag Lead，再用 PartitionBy OrderBy Frame 设置窗口，
+// 用于 SelectExpr（别名使用 As 设置）与 OrderExpr，字段规则与 orm.Col 一致（按 tag 解析）；
+// 未设置别名时默认别名为函数的默认别名，如：row_number、amount_sum、price_lag；
+// 数据库不支持时返回 ErrDBFunc：MySQL 8.0 以下、MariaDB 10.2 以下、SQLite 3.25 以下（版本通过 NewReferenceWithVersion 指定）
+
+// windowSpec 窗口定义，分区与排序字段依次保存在 RawSQL 的参数中（函数之后）
+type windowSpec struct {
+	partition int
+	order     int
+	frame     string
+}
+
+// Over 窗口函数，如：orm.Over(orm.Func("SUM", orm.Col("amount"))).PartitionBy("user_id").OrderBy("created_at").As("total")
+func Over(fn *RawSQL) *RawSQL {
+	if fn == nil {
+		panic("window function cannot be nil")
+	}
+
+	r := newExpr("?", []interface{}{fn})
+	r.name = exprName(fn)
+	r.window = &windowSpec{}
+	return r
+}
+
+// RowNumber ROW_NUMBER() OVER (...)
+func RowNumber() *RawSQL {
+	r := Over(Raw("ROW_NUMBER()"))
+	r.name = "row_number"
+	return r
+}
+
+// Rank RANK() OVER (...)
+func Rank() *RawSQL {
+	r := Over(Raw("RANK()"))
+	r.name = "rank"
+	return r
+}
+
+// DenseRank DENSE_RANK() OVER (...)
+func DenseRank() *RawSQL {
+	r := Over(Raw("DENSE_RANK()"))
+	r.name = "dense_rank"
+	return r
+}
+
+// Lag LAG(col,offset) OVER (...)，前 offset 行的值
+func Lag(col string, offset int) *RawSQL {
+	return Over(Func("LAG", Col(col), offset))
+}
+
+// Lead LEAD(col,offset) OVER (...)，后 offset 行的值
+func Lead(col string, offset int) *RawSQL {
+	return Over(Func("LEAD", Col(col), offset))
+}
+
+// PartitionBy 窗口的分区字段
+func (r *RawSQL) PartitionBy(cols ...string) *RawSQL {
+	n := r.windowCopy()
+	args := make([]interface{}, 0, len(r.args)+len(cols))
+	args = append(args, r.args[:1+r.window.partition]...)
+	for _, col := range cols {
+		args = append(args, Col(col))
+	}
+	n.args = append(args, r.args[1+r.window.partition:]...)
+	n.window.partition += len(cols)
+	return n
+}
+
+// OrderBy 窗口的排序字段，规则与 Order 一致：-col 降序，+col 或 col 升序
+func (r *RawSQL) OrderBy(cols ...string) *RawSQL {
+	n := r.windowCopy()
+	n.args = make([]interface{}, 0, len(r.args)+len(cols))
+	n.args = append(n.args, r.args...)
+	for _, col := range cols {
+		if col == "" {
+			panic("window order column cannot be empty")
+		}
+
+		switch col[0] {
+		case '-':
+			n.args = append(n.args, newExpr("? desc", []interface{}{Col(col[1:])}))
+		case '+':
+			n.args = append(n.args, newExpr("? asc", []interface{}{Col(col[1:])}))
+		default:
+			n.args = append(n.args, newExpr("? asc", []interface{}{Col(col)}))
+		}
+	}
+	n.window.order += len(cols)
+	return n
+}
+
+// Frame 窗口的范围，如：ROWS BETWEEN UNBOUNDED PRECEDING AND CURRENT ROW，只能包含字母、数字与空格
+func (r *RawSQL) Frame(frame string) *RawSQL {
+	for _, c := range frame {
+		if c != ' ' && !(c >= 'a' && c <= 'z') && !(c >= 'A' && c <= 'Z') && !(c >= '0' && c <= '9') {
+			panic(fmt.Sprintf("window frame[%s] is invalid", frame))
+		}
+	}
+
+	n := r.windowCopy()
+	n.window.frame = strings.TrimSpace(frame)
+	return n
+}
+
+func (r *RawSQL) windowCopy() *RawSQL {
+	if r.window == nil {
+		panic("window definition can only be used after orm.Over")
+	}
+
+	n := *r
+	w := *r.window
+	n.window = &w
+	return &n
+}
+
+// windowSQL fn OVER (PARTITION BY ... ORDER BY ... frame)
+func (p *queryModel) windowSQL(r *RawSQL) string {
+	w := r.window
+	if len(r.args) != 1+w.partition+w.order {
+		panic(fmt.Sprintf("window sql[%s] args count error", r.sql))
+	}
+
+	var over strings.Builder
+	over.Grow(50)
+	for i := 0; i < w.partition; i++ {
+		if i == 0 {
+			over.WriteString("PARTITION BY ")
+		} else {
+			over.WriteByte(',')
+		}
+		over.WriteString(p.rawValue(r.args[1+i]))
+	}
+	for i := 0; i < w.order; i++ {
+		if i == 0 {
+			if over.Len() > 0 {
+				over.WriteByte(' ')
+			}
+			over.WriteString("ORDER BY ")
+		} else {
+			over.WriteByte(',')
+		}
+		over.WriteString(p.rawValue(r.args[1+w.partition+i]))
+	}
+	if w.frame != "" {
+		if over.Len() > 0 {
+			over.WriteByte(' ')
+		}
+		over.WriteString(w.frame)
+	}
+
+	sql, err := p.DBCore.Dialect.Window(p.rawValue(r.args[0]), over.String())
+	if err != nil {
+		panic(err)
+	}
+	return sql
+}
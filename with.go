@@ -0,0 +1,80 @@
+package orm
+
+import "fmt"
+
+// CTE 公共表表达式（WITH），Name 可以作为表名使用：新建 ORM 查询，或在 ref 中定义关联后通过 tag 关联；
+// 使用 ref.AddTableDef(Name, 结构体) 定义字段后，可以使用 * 展开字段与 tag
+type CTE struct {
+	Name  string
+	Query *BaseQuery
+	// Recursive 递归部分，不为 nil 时为递归 CTE：Query union all Recursive
+	Recursive *BaseQuery
+}
+
+type withModel struct {
+	// Name Columns 已转义
+	Name      string
+	Columns   []string
+	Query     *queryModel
+	Recursive *queryModel
+}
+
+func (q *BaseQuery) withData() []*withModel {
+	if len(q.With) <= 0 {
+		return nil
+	}
+
+	dbCore := q.RefConf.getDBConf()
+	list := make([]*withModel, 0, len(q.With))
+	for _, c := range q.With {
+		err := globalVerifyObj.VerifyTableName(c.Name)
+		if err != nil {
+			panic(err)
+		}
+		if c.Query == nil {
+			panic(fmt.Sprintf("with[%s] query is nil", c.Name))
+		}
+
+		w := &withModel{
+			Name:  dbCore.EscStart + c.Name + dbCore.EscEnd,
+			Query: c.Query.cond(),
+		}
+		for _, col := range q.RefConf.GetTableDef(c.Name) {
+			w.Columns = append(w.Columns, dbCore.EscStart+col+dbCore.EscEnd)
+		}
+		if c.Recursive != nil {
+			w.Recursive = c.Recursive.cond()
+		}
+		list = append(list, w)
+	}
+	return list
+}
+
+// withSQL WITH 子句，以空格结尾，没有 CTE 时为空
+func (p *queryModel) withSQL() string {
+	if len(p.With) <= 0 {
+		return ""
+	}
+
+	list := make([]*WithParts, 0, len(p.With))
+	for _, w := range p.With {
+		w.Query.BindParams = p.BindParams
+		sql := w.Query.SQL()
+		if w.Recursive != nil {
+			w.Recursive.BindParams = p.BindParams
+			sql += " union all " + w.Recursive.SQL()
+		}
+		list = append(list, &WithParts{
+			Name:      w.Name,
+			Columns:   w.Columns,
+			SQL:       sql,
+			Recursive: w.Recursive != nil,
+		})
+	}
+
+	sql, err := p.DBCore.Dialect.With(list)
+	if err != nil {
+		panic(err)
+	}
+	return sql
+}